@@ -0,0 +1,200 @@
+// Package align extracts aligned face chips: faces rotated so that the eyes are level,
+// scaled to a fixed interocular distance and cropped to a fixed size, as expected by most face recognition models.
+package align
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// Params contains the settings of the aligned face chips.
+// Width, Height: the size of the face chips in pixels.
+// HorizontalMargin: the distance between the chip edges and the pupils, as a fraction of the chip width.
+// The interocular distance of the chips is Width * (1 - 2*HorizontalMargin).
+// TopMargin: the distance between the top edge of the chip and the eye line, as a fraction of the chip height.
+// The margins should be smaller than half of the chip width and than the chip height respectively (see Validate).
+type Params struct {
+	Width            int
+	Height           int
+	HorizontalMargin float64
+	TopMargin        float64
+}
+
+// DefaultParams returns 112x112 face chips with the pupils placed at 30% from the sides and 35% from the top.
+func DefaultParams() Params {
+	return Params{
+		Width:            112,
+		Height:           112,
+		HorizontalMargin: 0.3,
+		TopMargin:        0.35,
+	}
+}
+
+// The errors returned by Validate.
+var (
+	// ErrInvalidSize is returned when the chip width or height is not positive.
+	ErrInvalidSize = errors.New("the size of the face chips should be positive")
+	// ErrInvalidMargin is returned when the margins leave no room for the eyes on the chip.
+	ErrInvalidMargin = errors.New("the horizontal margin should be within [0, 0.5) and the top margin within [0, 1)")
+)
+
+// Validate checks whether the settings produce a face chip with a positive interocular distance,
+// which guarantees that the transform of the chip is invertible.
+func (p Params) Validate() error {
+	var errs []error
+	if p.Width <= 0 || p.Height <= 0 {
+		errs = append(errs, ErrInvalidSize)
+	}
+	if p.HorizontalMargin < 0 || p.HorizontalMargin >= 0.5 || p.TopMargin < 0 || p.TopMargin >= 1 {
+		errs = append(errs, ErrInvalidMargin)
+	}
+	return errors.Join(errs...)
+}
+
+// Transform is a 2D affine transform mapping the (x, y) point to
+// (A*x + B*y + C, D*x + E*y + F).
+type Transform struct {
+	A, B, C float64
+	D, E, F float64
+}
+
+// Apply maps the point through the transform.
+func (t Transform) Apply(x, y float64) (float64, float64) {
+	return t.A*x + t.B*y + t.C, t.D*x + t.E*y + t.F
+}
+
+// Invert returns the inverse transform. The transforms returned by this package are always invertible,
+// since the chip settings are validated.
+func (t Transform) Invert() Transform {
+	det := t.A*t.E - t.B*t.D
+	a, b := t.E/det, -t.B/det
+	d, e := -t.D/det, t.A/det
+	return Transform{
+		A: a, B: b, C: -(a*t.C + b*t.F),
+		D: d, E: e, F: -(d*t.C + e*t.F),
+	}
+}
+
+// Chip is an aligned face chip.
+// Image: the aligned face. The areas falling outside of the source image are left transparent.
+// Transform: maps the source image coordinates to the chip coordinates. Use its inverse for mapping the points back.
+type Chip struct {
+	Image     *image.NRGBA
+	Transform Transform
+}
+
+// FromPupils extracts the face chip aligned on the left and right (from the viewer's perspective) pupils.
+// An error is returned if the settings are not valid.
+func FromPupils(img image.Image, left, right pigo.Puploc, p Params) (Chip, error) {
+	if err := p.Validate(); err != nil {
+		return Chip{}, err
+	}
+	lx, ly := float64(left.Col), float64(left.Row)
+	rx, ry := float64(right.Col), float64(right.Row)
+
+	// The positions of the pupils on the chip.
+	dlx := p.HorizontalMargin * float64(p.Width)
+	drx := float64(p.Width) - dlx
+	dy := p.TopMargin * float64(p.Height)
+
+	dist := math.Hypot(rx-lx, ry-ly)
+	if dist == 0 {
+		dist = 1
+	}
+	// Rotate the eye line to horizontal, scale it to the interocular distance and translate the left pupil in place.
+	scale := (drx - dlx) / dist
+	sin, cos := math.Sincos(-math.Atan2(ry-ly, rx-lx))
+	a, b := scale*cos, -scale*sin
+	d, e := scale*sin, scale*cos
+
+	t := Transform{
+		A: a, B: b, C: dlx - (a*lx + b*ly),
+		D: d, E: e, F: dy - (d*lx + e*ly),
+	}
+	return Chip{Image: warp(img, t, p.Width, p.Height), Transform: t}, nil
+}
+
+// FromDetection extracts the face chip of a detection without localized pupils. The pupils are placed
+// with the default eye heuristics of the face analyzer (see pigo.DefaultEyeParams), rotated by the detection angle
+// (0.0 is 0 radians and 1.0 is 2*pi radians).
+func FromDetection(img image.Image, det pigo.Detection, angle float64, p Params) (Chip, error) {
+	ep := pigo.DefaultEyeParams()
+	sin, cos := math.Sincos(2 * math.Pi * angle)
+	s := float64(det.Scale)
+
+	eye := func(dr, dc float64) pigo.Puploc {
+		return pigo.Puploc{
+			Row: det.Row + int(math.Round(cos*dr-sin*dc)),
+			Col: det.Col + int(math.Round(sin*dr+cos*dc)),
+		}
+	}
+	left := eye(-ep.RowOffset*s, -ep.LeftColOffset*s)
+	right := eye(-ep.RowOffset*s, ep.RightColOffset*s)
+
+	return FromPupils(img, left, right, p)
+}
+
+// Faces extracts the aligned chips of the analyzed faces. The faces with both pupils localized
+// are aligned on their pupils, the rest of them on their detection.
+func Faces(img image.Image, faces []pigo.Face, p Params) ([]Chip, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	// Convert the image only once, since the conversion is a no-op on NRGBA images.
+	src := pigo.ImgToNRGBA(img)
+
+	chips := make([]Chip, 0, len(faces))
+	for _, face := range faces {
+		var chip Chip
+		if face.LeftEye != nil && face.RightEye != nil {
+			chip, _ = FromPupils(src, *face.LeftEye, *face.RightEye, p)
+		} else {
+			chip, _ = FromDetection(src, face.Detection, face.Angle, p)
+		}
+		chips = append(chips, chip)
+	}
+	return chips, nil
+}
+
+// warp renders the chip by sampling the source image with bilinear interpolation through the inverse transform.
+func warp(img image.Image, t Transform, width, height int) *image.NRGBA {
+	src := pigo.ImgToNRGBA(img)
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	inv := t.Invert()
+	b := src.Bounds()
+
+	at := func(x, y int) color.NRGBA {
+		if x < b.Min.X || y < b.Min.Y || x >= b.Max.X || y >= b.Max.Y {
+			return color.NRGBA{}
+		}
+		return src.NRGBAAt(x, y)
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sx, sy := inv.Apply(float64(x), float64(y))
+			x0, y0 := int(math.Floor(sx)), int(math.Floor(sy))
+			fx, fy := sx-float64(x0), sy-float64(y0)
+
+			c00, c10 := at(x0, y0), at(x0+1, y0)
+			c01, c11 := at(x0, y0+1), at(x0+1, y0+1)
+
+			lerp := func(v00, v10, v01, v11 uint8) uint8 {
+				top := float64(v00)*(1-fx) + float64(v10)*fx
+				bottom := float64(v01)*(1-fx) + float64(v11)*fx
+				return uint8(math.Round(top*(1-fy) + bottom*fy))
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: lerp(c00.R, c10.R, c01.R, c11.R),
+				G: lerp(c00.G, c10.G, c01.G, c11.G),
+				B: lerp(c00.B, c10.B, c01.B, c11.B),
+				A: lerp(c00.A, c10.A, c01.A, c11.A),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,110 @@
+package align_test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/esimov/pigo/align"
+	pigo "github.com/esimov/pigo/core"
+)
+
+func TestAlign_FromPupilsShouldLevelTheEyes(t *testing.T) {
+	// Draw two marks on a tilted eye line.
+	img := image.NewNRGBA(image.Rect(0, 0, 300, 300))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	left := pigo.Puploc{Row: 120, Col: 100}
+	right := pigo.Puploc{Row: 160, Col: 190}
+	for _, p := range []pigo.Puploc{left, right} {
+		for y := -2; y <= 2; y++ {
+			for x := -2; x <= 2; x++ {
+				img.SetNRGBA(p.Col+x, p.Row+y, color.NRGBA{R: 255, A: 255})
+			}
+		}
+	}
+
+	params := align.DefaultParams()
+	chip, err := align.FromPupils(img, left, right, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chip.Image.Bounds().Dx() != params.Width || chip.Image.Bounds().Dy() != params.Height {
+		t.Fatalf("unexpected chip size: %v", chip.Image.Bounds())
+	}
+
+	wantY := params.TopMargin * float64(params.Height)
+	for i, want := range []float64{
+		params.HorizontalMargin * float64(params.Width),
+		(1 - params.HorizontalMargin) * float64(params.Width),
+	} {
+		p := []pigo.Puploc{left, right}[i]
+		x, y := chip.Transform.Apply(float64(p.Col), float64(p.Row))
+		if math.Abs(x-want) > 1e-6 || math.Abs(y-wantY) > 1e-6 {
+			t.Errorf("expected the pupil at (%.1f, %.1f), got: (%.1f, %.1f)", want, wantY, x, y)
+		}
+		if c := chip.Image.NRGBAAt(int(math.Round(x)), int(math.Round(y))); c.G > 64 {
+			t.Errorf("expected the pupil mark on the chip, got: %v", c)
+		}
+		// Map the point back to the source image.
+		sx, sy := chip.Transform.Invert().Apply(x, y)
+		if math.Abs(sx-float64(p.Col)) > 1e-6 || math.Abs(sy-float64(p.Row)) > 1e-6 {
+			t.Errorf("expected the inverse transform to map back to (%d, %d), got: (%.2f, %.2f)", p.Col, p.Row, sx, sy)
+		}
+	}
+}
+
+func TestAlign_FacesShouldFallBackOnTheDetection(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 200))
+	face := pigo.Face{Detection: pigo.Detection{Row: 100, Col: 100, Scale: 100}}
+
+	params := align.DefaultParams()
+	params.Width, params.Height = 64, 80
+	chips, err := align.Faces(img, []pigo.Face{face}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(chips) != 1 || chips[0].Image.Bounds().Dx() != 64 || chips[0].Image.Bounds().Dy() != 80 {
+		t.Fatalf("expected a 64x80 chip, got: %v", chips)
+	}
+
+	// The heuristic eye line of an upright detection is level, so the transform has no rotation.
+	tr := chips[0].Transform
+	if math.Abs(tr.B) > 1e-2 || math.Abs(tr.D) > 1e-2 {
+		t.Errorf("expected no rotation, got: %+v", tr)
+	}
+	// The face center lies below the eye line, horizontally centered.
+	x, y := tr.Apply(100, 100)
+	if math.Abs(x-32) > 2 || y <= params.TopMargin*80 {
+		t.Errorf("unexpected face center position on the chip: (%.1f, %.1f)", x, y)
+	}
+}
+
+func TestAlign_ShouldRejectDegenerateParams(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	left, right := pigo.Puploc{Row: 40, Col: 30}, pigo.Puploc{Row: 40, Col: 70}
+
+	for name, c := range map[string]struct {
+		set func(*align.Params)
+		err error
+	}{
+		"half margin":     {func(p *align.Params) { p.HorizontalMargin = 0.5 }, align.ErrInvalidMargin},
+		"mirrored":        {func(p *align.Params) { p.HorizontalMargin = 0.7 }, align.ErrInvalidMargin},
+		"negative margin": {func(p *align.Params) { p.HorizontalMargin = -0.1 }, align.ErrInvalidMargin},
+		"top margin":      {func(p *align.Params) { p.TopMargin = 1 }, align.ErrInvalidMargin},
+		"zero width":      {func(p *align.Params) { p.Width = 0 }, align.ErrInvalidSize},
+		"negative height": {func(p *align.Params) { p.Height = -10 }, align.ErrInvalidSize},
+	} {
+		p := align.DefaultParams()
+		c.set(&p)
+		if _, err := align.FromPupils(img, left, right, p); !errors.Is(err, c.err) {
+			t.Errorf("%s: expected the %v error, got: %v", name, c.err, err)
+		}
+		if _, err := align.Faces(img, []pigo.Face{{Detection: pigo.Detection{Row: 50, Col: 50, Scale: 60}}}, p); !errors.Is(err, c.err) {
+			t.Errorf("%s: expected the %v error, got: %v", name, c.err, err)
+		}
+	}
+}
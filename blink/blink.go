@@ -0,0 +1,220 @@
+// Package blink implements an eye blink detector working over the successive face analyses of a video stream.
+//
+// The openness of each eye is measured as the contrast between the dark iris found around the localized pupil
+// and the rest of the eye region, delimited by the eye corner landmark points. When the eyelid closes,
+// the iris gets covered and the contrast drops. The thresholds are relative to a baseline which adapts
+// to the individual while the eye is open, so they don't need to be tuned for each person.
+package blink
+
+import (
+	"math"
+	"time"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// Eye identifies the left or the right eye (from the viewer's perspective).
+type Eye string
+
+// The eyes tracked by the detector.
+const (
+	LeftEye  Eye = "left"
+	RightEye Eye = "right"
+)
+
+// Params contains the settings of the blink detector.
+// CloseRatio: the eye is considered closed when its openness drops below this fraction of the baseline.
+// OpenRatio: the closed eye is considered open again when its openness rises above this fraction of the baseline.
+// Adaptation: the weight of the new open eye measurements when updating the baseline.
+// Warmup: the number of open eye measurements needed for establishing the baseline.
+// MinDuration, MaxDuration: the eye closures outside of this interval are not reported as blinks.
+type Params struct {
+	CloseRatio  float64
+	OpenRatio   float64
+	Adaptation  float64
+	Warmup      int
+	MinDuration time.Duration
+	MaxDuration time.Duration
+}
+
+// DefaultParams returns the default blink detector settings.
+func DefaultParams() Params {
+	return Params{
+		CloseRatio:  0.55,
+		OpenRatio:   0.75,
+		Adaptation:  0.05,
+		Warmup:      10,
+		MinDuration: 0,
+		MaxDuration: time.Second,
+	}
+}
+
+// Event describes a detected blink.
+type Event struct {
+	Eye      Eye
+	Start    time.Time
+	Duration time.Duration
+}
+
+// eyeState holds the adaptive state of an eye.
+type eyeState struct {
+	baseline float64
+	samples  int
+	openness float64
+	closed   bool
+	closedAt time.Time
+}
+
+// Detector detects the blinks of a single tracked face. A separate detector should be used for each face.
+// OnBlink is called for each detected blink, if it's defined.
+type Detector struct {
+	Params
+	OnBlink func(Event)
+
+	left  eyeState
+	right eyeState
+}
+
+// NewDetector initializes the blink Detector constructor method.
+func NewDetector(p Params) *Detector {
+	return &Detector{Params: p}
+}
+
+// Update feeds the detector with the analysis of the face detected at the time t over the grayscale frame
+// and returns the blinks which ended on this frame. The eyes without a localized pupil are skipped.
+func (d *Detector) Update(t time.Time, face pigo.Face, img pigo.ImageParams) []Event {
+	var events []Event
+
+	for _, eye := range []Eye{LeftEye, RightEye} {
+		openness, ok := Openness(face, eye, img)
+		if !ok {
+			continue
+		}
+		if ev := d.update(d.state(eye), eye, t, openness); ev != nil {
+			events = append(events, *ev)
+			if d.OnBlink != nil {
+				d.OnBlink(*ev)
+			}
+		}
+	}
+	return events
+}
+
+// update runs the hysteresis thresholding over the openness of an eye.
+func (d *Detector) update(s *eyeState, eye Eye, t time.Time, openness float64) *Event {
+	s.openness = openness
+
+	// Establish the baseline of the open eye before detecting anything.
+	if s.samples < d.Warmup {
+		s.baseline = (s.baseline*float64(s.samples) + openness) / float64(s.samples+1)
+		s.samples++
+		return nil
+	}
+
+	if !s.closed {
+		if openness < d.CloseRatio*s.baseline {
+			s.closed = true
+			s.closedAt = t
+			return nil
+		}
+		s.baseline += d.Adaptation * (openness - s.baseline)
+		return nil
+	}
+
+	if openness > d.OpenRatio*s.baseline {
+		s.closed = false
+		dur := t.Sub(s.closedAt)
+		if dur >= d.MinDuration && dur <= d.MaxDuration {
+			return &Event{Eye: eye, Start: s.closedAt, Duration: dur}
+		}
+	}
+	return nil
+}
+
+// Openness returns the last openness of the eye relative to its baseline.
+// A value around 1 means the eye is open as usual, while values close to 0 mean the eye is closed.
+// It returns 1 while the baseline is not established yet.
+func (d *Detector) Openness(eye Eye) float64 {
+	s := d.state(eye)
+	if s.samples < d.Warmup || s.baseline == 0 {
+		return 1
+	}
+	return s.openness / s.baseline
+}
+
+// Closed reports whether the eye is currently closed.
+func (d *Detector) Closed(eye Eye) bool {
+	return d.state(eye).closed
+}
+
+// Reset clears the state of the detector, for example when the tracked face is lost.
+func (d *Detector) Reset() {
+	d.left = eyeState{}
+	d.right = eyeState{}
+}
+
+// state returns the state of the provided eye.
+func (d *Detector) state(eye Eye) *eyeState {
+	if eye == LeftEye {
+		return &d.left
+	}
+	return &d.right
+}
+
+// Openness measures the absolute openness of an eye as the contrast between the iris and the rest of the eye region.
+// The iris radius is derived from the pupil scale, while the size of the eye region is given by the distance
+// between the eye corners or, if the eye corner landmark points are missing, by the size of the face.
+// It returns false if the pupil of the eye was not localized.
+func Openness(face pigo.Face, eye Eye, img pigo.ImageParams) (float64, bool) {
+	var (
+		pupil        *pigo.Puploc
+		outer, inner string
+	)
+	if eye == LeftEye {
+		pupil, outer, inner = face.LeftEye, pigo.LeftEyeOuterCorner, pigo.LeftEyeInnerCorner
+	} else {
+		pupil, outer, inner = face.RightEye, pigo.RightEyeOuterCorner, pigo.RightEyeInnerCorner
+	}
+	if pupil == nil {
+		return 0, false
+	}
+
+	halfWidth := 0.075 * float64(face.Detection.Scale)
+	c1, ok1 := face.Landmark(outer)
+	c2, ok2 := face.Landmark(inner)
+	if ok1 && ok2 {
+		halfWidth = math.Hypot(float64(c1.Col-c2.Col), float64(c1.Row-c2.Row)) / 2
+	}
+	radius := math.Max(1, 0.25*float64(pupil.Scale))
+	halfHeight := math.Max(radius, 0.5*halfWidth)
+	halfWidth = math.Max(halfWidth, radius)
+
+	var (
+		irisSum, eyeSum float64
+		irisNum, eyeNum int
+	)
+	for y := -int(halfHeight); y <= int(halfHeight); y++ {
+		row := pupil.Row + y
+		if row < 0 || row >= img.Rows {
+			continue
+		}
+		for x := -int(halfWidth); x <= int(halfWidth); x++ {
+			col := pupil.Col + x
+			if col < 0 || col >= img.Cols {
+				continue
+			}
+			px := float64(img.Pixels[row*img.Dim+col])
+			if math.Hypot(float64(x), float64(y)) <= radius {
+				irisSum += px
+				irisNum++
+			} else {
+				eyeSum += px
+				eyeNum++
+			}
+		}
+	}
+	if irisNum == 0 || eyeNum == 0 {
+		return 0, false
+	}
+	return math.Max(0, (eyeSum/float64(eyeNum)-irisSum/float64(irisNum))/255), true
+}
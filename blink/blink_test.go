@@ -0,0 +1,118 @@
+package blink_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/esimov/pigo/blink"
+	pigo "github.com/esimov/pigo/core"
+)
+
+// syntheticFrame renders a uniform face region with the irises drawn as dark disks when the eyes are open.
+func syntheticFrame(open bool, irisColor uint8) (pigo.Face, pigo.ImageParams) {
+	const size = 300
+
+	pixels := make([]uint8, size*size)
+	for i := range pixels {
+		pixels[i] = 200
+	}
+	face := pigo.Face{
+		Detection: pigo.Detection{Row: 150, Col: 150, Scale: 200},
+		LeftEye:   &pigo.Puploc{Row: 120, Col: 110, Scale: 24},
+		RightEye:  &pigo.Puploc{Row: 120, Col: 190, Scale: 24},
+	}
+	if open {
+		for _, eye := range []*pigo.Puploc{face.LeftEye, face.RightEye} {
+			for y := -6; y <= 6; y++ {
+				for x := -6; x <= 6; x++ {
+					if x*x+y*y <= 36 {
+						pixels[(eye.Row+y)*size+eye.Col+x] = irisColor
+					}
+				}
+			}
+		}
+	}
+	return face, pigo.ImageParams{Pixels: pixels, Rows: size, Cols: size, Dim: size}
+}
+
+func TestBlink_OpennessShouldDropWhenTheEyeCloses(t *testing.T) {
+	face, img := syntheticFrame(true, 40)
+	open, ok := blink.Openness(face, blink.LeftEye, img)
+	if !ok {
+		t.Fatalf("expected the openness of the left eye to be measured")
+	}
+	face, img = syntheticFrame(false, 40)
+	closed, _ := blink.Openness(face, blink.LeftEye, img)
+
+	if open <= 0 || closed >= open/2 {
+		t.Errorf("expected the openness to drop on the closed eye, got open: %.3f, closed: %.3f", open, closed)
+	}
+
+	face.RightEye = nil
+	if _, ok := blink.Openness(face, blink.RightEye, img); ok {
+		t.Errorf("expected the openness of an eye without pupil not to be measured")
+	}
+}
+
+func TestBlink_DetectorShouldReportBlinks(t *testing.T) {
+	var (
+		det      = blink.NewDetector(blink.DefaultParams())
+		received []blink.Event
+		returned []blink.Event
+		start    = time.Unix(0, 0)
+		frame    = 40 * time.Millisecond
+	)
+	det.OnBlink = func(ev blink.Event) {
+		received = append(received, ev)
+	}
+
+	// 20 open frames, a blink lasting 3 frames, 10 open frames and an eye closure exceeding MaxDuration.
+	sequence := make([]bool, 0, 100)
+	for _, run := range []struct {
+		open bool
+		n    int
+	}{{true, 20}, {false, 3}, {true, 10}, {false, 40}, {true, 5}} {
+		for i := 0; i < run.n; i++ {
+			sequence = append(sequence, run.open)
+		}
+	}
+	for i, open := range sequence {
+		face, img := syntheticFrame(open, 40)
+		returned = append(returned, det.Update(start.Add(time.Duration(i)*frame), face, img)...)
+	}
+
+	if len(returned) != 2 {
+		t.Fatalf("expected a single blink reported for both eyes, got: %v", returned)
+	}
+	if len(received) != len(returned) {
+		t.Errorf("expected the callback to receive the returned events, got: %v", received)
+	}
+	for _, ev := range returned {
+		if !ev.Start.Equal(start.Add(20*frame)) || ev.Duration != 3*frame {
+			t.Errorf("unexpected blink event: %+v", ev)
+		}
+	}
+	if det.Closed(blink.LeftEye) || det.Openness(blink.LeftEye) < 0.9 {
+		t.Errorf("expected the eye to be open at the end of the sequence")
+	}
+}
+
+func TestBlink_DetectorShouldAdaptToTheIndividual(t *testing.T) {
+	// A low contrast iris should still produce blinks, since the thresholds are relative to the baseline.
+	det := blink.NewDetector(blink.DefaultParams())
+	start := time.Unix(0, 0)
+
+	var events []blink.Event
+	for i := 0; i < 30; i++ {
+		face, img := syntheticFrame(i < 20 || i > 22, 150)
+		events = append(events, det.Update(start.Add(time.Duration(i)*33*time.Millisecond), face, img)...)
+	}
+	if len(events) != 2 {
+		t.Errorf("expected the blink of a low contrast iris to be detected, got: %v", events)
+	}
+
+	det.Reset()
+	if det.Openness(blink.LeftEye) != 1 || det.Closed(blink.LeftEye) {
+		t.Errorf("expected the detector state to be cleared")
+	}
+}
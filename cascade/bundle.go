@@ -0,0 +1,194 @@
+package cascade
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// The file layout of a model bundle. Only the face detection cascade is mandatory,
+// the pupil localization cascade and the facial landmark points directory are optional.
+const (
+	FaceFinderFile = "facefinder"
+	PuplocFile     = "puploc"
+	LandmarksDir   = "lps"
+)
+
+// Bundle holds the unpacked cascades of a model bundle.
+// FaceFinder: the face detection cascade.
+// Puploc: the pupil localization cascade, nil if missing from the bundle.
+// Landmarks: the facial landmark points cascades keyed by the landmark point names, nil if missing from the bundle.
+type Bundle struct {
+	FaceFinder *pigo.Pigo
+	Puploc     *pigo.PuplocCascade
+	Landmarks  map[string][]*pigo.FlpCascade
+}
+
+// Analyzer returns a face analyzer running the cascades of the bundle.
+func (b *Bundle) Analyzer() *pigo.FaceAnalyzer {
+	return pigo.NewFaceAnalyzer(b.FaceFinder, b.Puploc, b.Landmarks)
+}
+
+// Load loads a model bundle from a directory, a .zip or a .tar.gz (.tgz) archive.
+func Load(name string) (*Bundle, error) {
+	lname := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lname, ".zip"):
+		return LoadZip(name)
+	case strings.HasSuffix(lname, ".tar.gz"), strings.HasSuffix(lname, ".tgz"):
+		return LoadTarGz(name)
+	}
+	return LoadFS(os.DirFS(name))
+}
+
+// LoadZip loads a model bundle from a zip archive.
+func LoadZip(name string) (*Bundle, error) {
+	r, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return LoadFS(r)
+}
+
+// LoadTarGz loads a model bundle from a gzip compressed tar archive.
+func LoadTarGz(name string) (*Bundle, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fsys, err := tarGzFS(f)
+	if err != nil {
+		return nil, err
+	}
+	return LoadFS(fsys)
+}
+
+// LoadFS loads a model bundle from the provided file system. The cascade files can be placed
+// either in the root of the file system or in a single top level directory.
+// All the errors encountered while unpacking the cascade files are reported together.
+func LoadFS(fsys fs.FS) (*Bundle, error) {
+	fsys, err := bundleRoot(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		bundle Bundle
+		errs   []error
+	)
+
+	bundle.FaceFinder, err = unpackFaceFinder(fsys, FaceFinderFile)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", FaceFinderFile, err))
+	}
+
+	if _, err := fs.Stat(fsys, PuplocFile); err == nil {
+		bundle.Puploc, err = unpackPuploc(fsys, PuplocFile)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", PuplocFile, err))
+		}
+	}
+
+	if _, err := fs.Stat(fsys, LandmarksDir); err == nil {
+		bundle.Landmarks, err = pigo.NewPuplocCascade().ReadCascadeFS(fsys, LandmarksDir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", LandmarksDir, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+// bundleRoot returns the directory of the file system containing the face detection cascade.
+func bundleRoot(fsys fs.FS) (fs.FS, error) {
+	if _, err := fs.Stat(fsys, FaceFinderFile); err == nil {
+		return fsys, nil
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return fs.Sub(fsys, entries[0].Name())
+	}
+	return nil, fmt.Errorf("the %s cascade file is missing from the model bundle", FaceFinderFile)
+}
+
+// unpackFaceFinder unpacks the face detection cascade found in the provided file system.
+func unpackFaceFinder(fsys fs.FS, name string) (*pigo.Pigo, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return pigo.NewPigo().Unpack(data)
+}
+
+// unpackPuploc unpacks the pupil localization cascade found in the provided file system.
+func unpackPuploc(fsys fs.FS, name string) (*pigo.PuplocCascade, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return pigo.NewPuplocCascade().UnpackCascade(data)
+}
+
+// tarGzFS reads the regular files of a gzip compressed tar archive into memory
+// and exposes them as a file system. Since the standard library does not provide
+// an in-memory file system outside of the testing packages, the files are
+// repacked into an uncompressed zip archive, which implements fs.FS.
+func tarGzFS(r io.Reader) (fs.FS, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gzr.Close()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean(hdr.Name), "./")
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("invalid file name in archive: %s", hdr.Name)
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(w, tr); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
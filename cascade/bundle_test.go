@@ -0,0 +1,136 @@
+package cascade_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"image"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/esimov/pigo/cascade"
+	pigo "github.com/esimov/pigo/core"
+)
+
+// bundleFiles returns the cascade files of the package directory, using the model bundle layout.
+func bundleFiles(t *testing.T) map[string][]byte {
+	files := make(map[string][]byte)
+	names := []string{cascade.FaceFinderFile, cascade.PuplocFile}
+	for _, spec := range pigo.LandmarkManifest {
+		names = append(names, path.Join(cascade.LandmarksDir, spec.Cascade))
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.FromSlash(name))
+		if err != nil {
+			t.Fatalf("error reading the cascade file: %v", err)
+		}
+		files[name] = data
+	}
+	return files
+}
+
+func assertBundle(t *testing.T, bundle *cascade.Bundle) {
+	if bundle.FaceFinder == nil || bundle.Puploc == nil {
+		t.Fatal("the face detection and pupil localization cascades should have been loaded")
+	}
+	if len(bundle.Landmarks) != len(pigo.LandmarkManifest) {
+		t.Fatalf("expected %d landmark points cascades, got: %d", len(pigo.LandmarkManifest), len(bundle.Landmarks))
+	}
+}
+
+func TestBundle_LoadZip(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "models.zip")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for fn, data := range bundleFiles(t) {
+		// Place the cascades into a top level directory, as it usually happens when zipping a folder.
+		w, err := zw.Create(path.Join("models", fn))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	// Stray files should not break the loading.
+	w, _ := zw.Create("models/lps/README.md")
+	io.WriteString(w, "custom landmark models")
+	zw.Close()
+	f.Close()
+
+	bundle, err := cascade.Load(name)
+	if err != nil {
+		t.Fatalf("error loading the zip bundle: %v", err)
+	}
+	assertBundle(t, bundle)
+}
+
+func TestBundle_LoadTarGz(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "models.tar.gz")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	for fn, data := range bundleFiles(t) {
+		tw.WriteHeader(&tar.Header{Name: "./" + fn, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg})
+		tw.Write(data)
+	}
+	tw.Close()
+	gzw.Close()
+	f.Close()
+
+	bundle, err := cascade.Load(name)
+	if err != nil {
+		t.Fatalf("error loading the tar.gz bundle: %v", err)
+	}
+	assertBundle(t, bundle)
+
+	faces := bundle.Analyzer().Analyze(mustImage(t))
+	if len(faces) == 0 || len(faces[0].Landmarks) != len(pigo.LandmarkManifest) {
+		t.Fatal("the bundle cascades should have detected the face and its landmark points")
+	}
+}
+
+func TestBundle_ShouldReportAllTheInvalidFiles(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for fn, data := range bundleFiles(t) {
+		fsys[fn] = &fstest.MapFile{Data: data}
+	}
+	fsys[cascade.PuplocFile] = &fstest.MapFile{Data: []byte("invalid")}
+	fsys["lps/lp93"] = &fstest.MapFile{Data: []byte("invalid")}
+	delete(fsys, "lps/lp84")
+
+	_, err := cascade.LoadFS(fsys)
+	if err == nil {
+		t.Fatal("loading an invalid bundle should return an error")
+	}
+	for _, name := range []string{"puploc", "lp93", "lp84"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Fatalf("the error should report the %s cascade file: %v", name, err)
+		}
+	}
+}
+
+func TestBundle_DefaultShouldLoadTheEmbeddedCascades(t *testing.T) {
+	bundle, err := cascade.Default()
+	if err != nil {
+		t.Fatalf("error loading the embedded cascades: %v", err)
+	}
+	assertBundle(t, bundle)
+}
+
+func mustImage(t *testing.T) image.Image {
+	src, err := pigo.GetImage("../testdata/sample.jpg")
+	if err != nil {
+		t.Fatalf("error reading the source file: %v", err)
+	}
+	return src
+}
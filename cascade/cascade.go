@@ -0,0 +1,36 @@
+// Package cascade embeds the face detection, pupil localization and facial landmark points
+// cascade files bundled with the library, so they can be used without shipping them on disk.
+// It also provides helpers for loading a whole model bundle from a directory or an archive.
+package cascade
+
+import (
+	"embed"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// models holds the bundled cascade files using the same layout as a model bundle.
+//
+//go:embed facefinder puploc lps
+var models embed.FS
+
+// Default unpacks all the embedded cascades.
+func Default() (*Bundle, error) {
+	return LoadFS(models)
+}
+
+// DefaultFaceFinder unpacks the embedded face detection cascade.
+func DefaultFaceFinder() (*pigo.Pigo, error) {
+	return unpackFaceFinder(models, FaceFinderFile)
+}
+
+// DefaultPuploc unpacks the embedded pupil localization cascade.
+func DefaultPuploc() (*pigo.PuplocCascade, error) {
+	return unpackPuploc(models, PuplocFile)
+}
+
+// DefaultLandmarks unpacks the embedded facial landmark points cascades
+// and returns them keyed by the landmark point names defined in pigo.LandmarkManifest.
+func DefaultLandmarks() (map[string][]*pigo.FlpCascade, error) {
+	return pigo.NewPuplocCascade().ReadCascadeFS(models, LandmarksDir)
+}
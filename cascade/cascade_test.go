@@ -0,0 +1,41 @@
+package cascade_test
+
+import (
+	"testing"
+
+	"github.com/esimov/pigo/cascade"
+	pigo "github.com/esimov/pigo/core"
+)
+
+func TestCascade_DefaultCascadesShouldDetectFacialFeatures(t *testing.T) {
+	p, err := cascade.DefaultFaceFinder()
+	if err != nil {
+		t.Fatalf("failed unpacking the embedded facefinder cascade: %v", err)
+	}
+	plc, err := cascade.DefaultPuploc()
+	if err != nil {
+		t.Fatalf("failed unpacking the embedded puploc cascade: %v", err)
+	}
+	flpcs, err := cascade.DefaultLandmarks()
+	if err != nil {
+		t.Fatalf("failed unpacking the embedded landmark points cascades: %v", err)
+	}
+	if len(flpcs) != len(pigo.LandmarkManifest) {
+		t.Fatalf("expected %d landmark points cascades, got: %d", len(pigo.LandmarkManifest), len(flpcs))
+	}
+
+	src, err := pigo.GetImage("../testdata/sample.jpg")
+	if err != nil {
+		t.Fatalf("error reading the source file: %v", err)
+	}
+
+	faces := pigo.NewFaceAnalyzer(p, plc, flpcs).Analyze(src)
+	if len(faces) == 0 {
+		t.Fatal("should have detected at least one face")
+	}
+	for _, face := range faces {
+		if face.LeftEye == nil || face.RightEye == nil || len(face.Landmarks) != len(pigo.LandmarkManifest) {
+			t.Fatalf("the pupils and the landmark points should have been detected: %+v", face)
+		}
+	}
+}
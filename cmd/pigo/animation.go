@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/esimov/pigo/blink"
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/render"
+	"github.com/esimov/pigo/utils"
+)
+
+// sequenceVerb matches the printf style frame number of the image sequence patterns, like %d or %04d.
+var sequenceVerb = regexp.MustCompile(`%0?[0-9]*d`)
+
+// animationOptions holds the settings of an animation run.
+type animationOptions struct {
+	source string
+	jsonf  string
+	fps    float64
+	track  bool
+}
+
+// animationFrame is a frame of an animated GIF or of an image sequence.
+// Img: the full size frame image, set only while the frame is processed.
+// Number: the number of the frame, used for naming the frames of the output sequence.
+// File: the source file of the sequence frames, empty for the GIF frames.
+// Delay: the display duration of the frame in 100ths of a second, as in the GIF format.
+// Disposal: the GIF disposal method of the frame.
+// Palette: the palette the annotated frame is quantized to in the output GIF.
+type animationFrame struct {
+	img      *image.NRGBA
+	number   int
+	file     string
+	delay    int
+	disposal byte
+	palette  color.Palette
+}
+
+// animation holds the frames of an animated GIF or of an image sequence. The frame images are decoded
+// one at a time while the animation is processed, so only the frame being processed is held in memory.
+// For the GIFs the decoded source GIF is kept, since its frames are composited over each other.
+type animation struct {
+	frames    []animationFrame
+	loopCount int
+	gif       *gif.GIF
+}
+
+// each decodes the frames of the animation in order and calls fn with each of them.
+// The frame image is released once fn returns. The first error stops the processing and it's returned.
+func (a *animation) each(fn func(i int, frame animationFrame) error) error {
+	if a.gif != nil {
+		return pigo.WalkGIFFrames(a.gif, func(i int, img *image.NRGBA) error {
+			frame := a.frames[i]
+			frame.img = img
+			return fn(i, frame)
+		})
+	}
+	for i, frame := range a.frames {
+		img, err := pigo.GetImage(frame.file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", frame.file, err)
+		}
+		frame.img = img
+		if err := fn(i, frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// frameResult is the record of an animation frame in the json stream of the detection results.
+// Frame: the index of the frame. File: the source file of the sequence frames.
+// Time: the time the frame is displayed at, since the start of the animation, in milliseconds.
+// Delay: the display duration of the frame in milliseconds.
+// Faces: the faces detected on the frame in the v2 json schema, with the track identifiers if tracking is enabled.
+type frameResult struct {
+	SchemaVersion int          `json:"schema_version"`
+	Frame         int          `json:"frame"`
+	File          string       `json:"file,omitempty"`
+	Time          int          `json:"time"`
+	Delay         int          `json:"delay"`
+	Width         int          `json:"width"`
+	Height        int          `json:"height"`
+	Faces         []faceResult `json:"faces"`
+}
+
+// pruneBlinkDetectors removes the blink detectors of the tracks which are not active anymore.
+func pruneBlinkDetectors(blinks map[int]*blink.Detector, active []int) {
+	alive := make(map[int]bool, len(active))
+	for _, id := range active {
+		alive[id] = true
+	}
+	for id := range blinks {
+		if !alive[id] {
+			delete(blinks, id)
+		}
+	}
+}
+
+// isSequence checks whether the path is an image sequence pattern, like frames/%04d.png.
+func isSequence(path string) bool {
+	return !utils.IsValidUrl(path) && sequenceVerb.MatchString(filepath.Base(path))
+}
+
+// checkSequence checks that the image sequence pattern holds a single frame number in its file name.
+func checkSequence(path string) error {
+	if n := len(sequenceVerb.FindAllStringIndex(filepath.Base(path), -1)); isSequence(path) && n > 1 {
+		return fmt.Errorf("the image sequence pattern %s should contain a single frame number, found %d", path, n)
+	}
+	return nil
+}
+
+// sequenceFile returns the file name of the numbered frame of the image sequence pattern. Only the frame number
+// of the file name is formatted, the rest of the path is kept as it is, even if it contains other % characters.
+func sequenceFile(pattern string, number int) string {
+	dir, base := filepath.Split(pattern)
+	loc := sequenceVerb.FindStringIndex(base)
+	return dir + base[:loc[0]] + fmt.Sprintf(base[loc[0]:loc[1]], number) + base[loc[1]:]
+}
+
+// isAnimation checks whether the source should be processed frame by frame: it's either an image sequence pattern
+// or an animated GIF, unless the destination is a single still image, in which case only the first GIF frame is processed.
+func isAnimation(source, destination string) bool {
+	if source == pipeName || utils.IsValidUrl(source) {
+		return false
+	}
+	if isSequence(source) {
+		return true
+	}
+	return strings.ToLower(filepath.Ext(source)) == ".gif" &&
+		(isSequence(destination) || !inSlice(strings.ToLower(filepath.Ext(destination)), imageExts))
+}
+
+// readAnimation lists the frames of the image sequence or of the animated GIF. The frames of an image
+// sequence are displayed at the provided frame rate, while the GIF frames keep their own delays.
+func readAnimation(source string, fps float64) (*animation, error) {
+	if isSequence(source) {
+		return readSequence(source, int(100/fps+0.5))
+	}
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	g, err := gif.DecodeAll(f)
+	if err != nil {
+		return nil, err
+	}
+	anim := &animation{loopCount: g.LoopCount, gif: g}
+	for i := range g.Image {
+		frame := animationFrame{number: i + 1, palette: pigo.GIFPalette(g, i)}
+		if i < len(g.Delay) {
+			frame.delay = g.Delay[i]
+		}
+		if i < len(g.Disposal) {
+			frame.disposal = g.Disposal[i]
+		}
+		anim.frames = append(anim.frames, frame)
+	}
+	return anim, nil
+}
+
+// readSequence lists the frames of the image sequence matching the pattern, ordered by their number.
+func readSequence(pattern string, delay int) (*animation, error) {
+	dir, base := filepath.Split(pattern)
+	if dir == "" {
+		dir = "."
+	}
+	loc := sequenceVerb.FindStringIndex(base)
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(base[:loc[0]]) + `([0-9]+)` + regexp.QuoteMeta(base[loc[1]:]) + "$")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	anim := &animation{}
+	for _, entry := range entries {
+		m := re.FindStringSubmatch(entry.Name())
+		if m == nil || entry.IsDir() {
+			continue
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		anim.frames = append(anim.frames, animationFrame{
+			number:   n,
+			file:     filepath.Join(dir, entry.Name()),
+			delay:    delay,
+			disposal: gif.DisposalNone,
+			palette:  palette.Plan9,
+		})
+	}
+	if len(anim.frames) == 0 {
+		return nil, fmt.Errorf("no frames matching %s", pattern)
+	}
+	sort.SliceStable(anim.frames, func(i, j int) bool {
+		return anim.frames[i].number < anim.frames[j].number
+	})
+	return anim, nil
+}
+
+// runAnimation detects the faces of each frame of an animated GIF or of an image sequence. The annotated frames
+// are written into an animated GIF or an image sequence, depending on the destination, keeping the frame delays
+// and disposal methods of the source. The detection results are written as a json stream, one record per frame.
+// The frames are processed one at a time: the frames of an output sequence are written as soon as they're annotated,
+// while the output GIF holds only the quantized frames until it's encoded.
+func runAnimation(fd *faceDetector, opts animationOptions) {
+	anim, err := readAnimation(opts.source, opts.fps)
+	if err != nil {
+		log.Fatalf("%sError reading the animation: %v%s", errorColor, err, defaultColor)
+	}
+	analyzer, err := fd.newAnalyzer()
+	if err != nil {
+		log.Fatalf("%sDetection error: %v%s", errorColor, err, defaultColor)
+	}
+
+	var enc *json.Encoder
+	if opts.jsonf != "" {
+		var w io.Writer = os.Stdout
+		if opts.jsonf != pipeName {
+			f, err := os.Create(opts.jsonf)
+			if err != nil {
+				log.Fatalf("%sCould not create the json file: %v%s", errorColor, err, defaultColor)
+			}
+			defer f.Close()
+			w = f
+		}
+		enc = json.NewEncoder(w)
+	}
+
+	// The track identifiers are shown in the face labels.
+	if opts.track && !fd.render.Has(render.Labels) {
+		fd.render.Layers = append(fd.render.Layers, render.Labels)
+	}
+
+	var (
+		tracker = pigo.NewTracker()
+		// The blinks are detected separately for each tracked face.
+		blinks  = make(map[int]*blink.Detector)
+		start   = time.Unix(0, 0)
+		out     = &gif.GIF{LoopCount: anim.loopCount}
+		elapsed int
+		total   int
+	)
+	err = anim.each(func(i int, frame animationFrame) error {
+		faces, imgParams := fd.analyze(analyzer, frame.img)
+		res := fd.newResult(frame.file, faces, imgParams)
+		dc := newCanvas(frame.img)
+		if opts.track {
+			tracks := tracker.Update(faces)
+			now := start.Add(time.Duration(elapsed*10) * time.Millisecond)
+			for j, tr := range tracks {
+				res.Faces[j].Track = tr.ID
+
+				det, ok := blinks[tr.ID]
+				if !ok {
+					det = blink.NewDetector(blink.DefaultParams())
+					blinks[tr.ID] = det
+				}
+				for _, ev := range det.Update(now, tr.Face, imgParams) {
+					res.Faces[j].Blinks = append(res.Faces[j].Blinks, blinkEvent{
+						Eye:      ev.Eye,
+						Start:    ev.Start.Sub(start).Milliseconds(),
+						Duration: ev.Duration.Milliseconds(),
+					})
+				}
+			}
+			pruneBlinkDetectors(blinks, tracker.Active())
+			render.DrawTracks(dc, tracks, fd.render)
+		} else {
+			render.Draw(dc, faces, fd.render)
+		}
+		total += len(faces)
+
+		if enc != nil {
+			err := enc.Encode(frameResult{
+				SchemaVersion: schemaVersion,
+				Frame:         i,
+				File:          frame.file,
+				Time:          elapsed * 10,
+				Delay:         frame.delay * 10,
+				Width:         imgParams.Cols,
+				Height:        imgParams.Rows,
+				Faces:         res.Faces,
+			})
+			if err != nil {
+				return fmt.Errorf("error encoding the json stream: %w", err)
+			}
+		}
+		elapsed += frame.delay
+
+		switch {
+		case fd.destination == "empty":
+		case isSequence(fd.destination):
+			if err := writeFrame(sequenceFile(fd.destination, frame.number), dc.Image()); err != nil {
+				return fmt.Errorf("error writing the frame: %w", err)
+			}
+		default:
+			img := dc.Image()
+			paletted := image.NewPaletted(img.Bounds(), annotationPalette(frame.palette, fd.render.Colors()))
+			draw.FloydSteinberg.Draw(paletted, img.Bounds(), img, img.Bounds().Min)
+
+			out.Image = append(out.Image, paletted)
+			out.Delay = append(out.Delay, frame.delay)
+			out.Disposal = append(out.Disposal, frame.disposal)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("%sError processing the animation: %v%s", errorColor, err, defaultColor)
+	}
+
+	if len(out.Image) > 0 {
+		if err := writeGIF(fd.destination, out); err != nil {
+			log.Fatalf("%sError encoding the output GIF: %v%s", errorColor, err, defaultColor)
+		}
+	}
+	log.Printf("\n%s%d%s face(s) detected on %d frame(s)", successColor, total, defaultColor, len(anim.frames))
+}
+
+// writeFrame encodes the annotated frame into the image file found at path, creating its directory if needed.
+func writeFrame(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := encodeImage(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeGIF encodes the animated GIF into the destination file, or to stdout.
+func writeGIF(destination string, g *gif.GIF) error {
+	// The frames can have different sizes in an image sequence, so the canvas should cover all of them.
+	for _, img := range g.Image {
+		b := img.Bounds().Union(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+		g.Config.Width, g.Config.Height = b.Max.X, b.Max.Y
+	}
+	if destination == pipeName {
+		return gif.EncodeAll(os.Stdout, g)
+	}
+	f, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, g); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// annotationPalette extends the palette with the annotation colors, so the detection markers keep their colors
+// after the quantization. The Plan9 palette is used if the extended palette doesn't fit into 256 colors.
+func annotationPalette(pal color.Palette, colors []color.Color) color.Palette {
+	ext := append(color.Palette(nil), pal...)
+	for _, c := range colors {
+		r, g, b, a := c.RGBA()
+		if r1, g1, b1, a1 := pal.Convert(c).RGBA(); r1 != r || g1 != g || b1 != b || a1 != a {
+			ext = append(ext, c)
+		}
+	}
+	if len(ext) > 256 {
+		return palette.Plan9
+	}
+	return ext
+}
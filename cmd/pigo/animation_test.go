@@ -0,0 +1,25 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAnimation_SequenceFile(t *testing.T) {
+	cases := map[string]string{
+		"frames/%04d.png":         "frames/0007.png",
+		"out 100%/%d.jpg":         "out 100%/7.jpg",
+		"frames/%d_50%_scale.png": "frames/7_50%_scale.png",
+	}
+	for pattern, expected := range cases {
+		if f := sequenceFile(filepath.FromSlash(pattern), 7); f != filepath.FromSlash(expected) {
+			t.Errorf("%s: expected %s, got %s", pattern, expected, f)
+		}
+	}
+	if err := checkSequence("frames/%d_%d.png"); err == nil {
+		t.Errorf("expected an error for a pattern with two frame numbers")
+	}
+	if err := checkSequence("frames/%04d.png"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
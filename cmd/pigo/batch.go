@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/export"
+	"github.com/esimov/pigo/utils"
+)
+
+// batchOptions holds the settings of a batch run.
+type batchOptions struct {
+	source  string
+	list    string
+	jsonf   string
+	ndjson  bool
+	workers int
+	format  string
+	export  export.Options
+}
+
+// batchJob is a source image of a batch run. The annotated image is written
+// under the destination directory, using the relative path of the source image.
+type batchJob struct {
+	src string
+	rel string
+}
+
+// batchResult holds the detection results of a batch job in the v1 json schema.
+// The faces are null if the file failed to be processed.
+type batchResult struct {
+	File  string      `json:"file"`
+	Faces []detection `json:"faces"`
+	Error string      `json:"error,omitempty"`
+}
+
+// isBatch checks whether the source is a directory or a glob pattern, rather than a single image.
+func isBatch(source string) bool {
+	if source == pipeName || utils.IsValidUrl(source) {
+		return false
+	}
+	if strings.ContainsAny(source, "*?[") {
+		return true
+	}
+	info, err := os.Stat(source)
+	return err == nil && info.IsDir()
+}
+
+// batchJobs lists the images of a batch run. The images are read from the list file if it's provided,
+// otherwise the source is either a directory, which is walked recursively, or a glob pattern.
+// The images of the directories and the glob patterns are listed in lexical order. The skipped directory,
+// the destination of the annotated images, is left out if it's nested into the source directory, so a rerun
+// doesn't process the outputs of the previous run.
+func batchJobs(source, list, skip string) ([]batchJob, error) {
+	var jobs []batchJob
+	root := source
+	if strings.ContainsAny(source, "*?[") {
+		root = globRoot(source)
+	}
+	if !inDir(skip, root) || inDir(root, skip) {
+		skip = ""
+	}
+	supported := func(path string) bool {
+		return inSlice(strings.ToLower(filepath.Ext(path)), imageExts)
+	}
+
+	switch {
+	case list != "":
+		f, err := os.Open(list)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			path := strings.TrimSpace(scanner.Text())
+			if path == "" || strings.HasPrefix(path, "#") {
+				continue
+			}
+			// The paths pointing outside of the working directory are mirrored by their file name.
+			rel := filepath.Clean(path)
+			if filepath.IsAbs(rel) || outsideDir(rel) {
+				rel = filepath.Base(rel)
+			}
+			jobs = append(jobs, batchJob{src: path, rel: rel})
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	case strings.ContainsAny(source, "*?["):
+		matches, err := filepath.Glob(source)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range matches {
+			if info, err := os.Stat(path); err != nil || info.IsDir() || !supported(path) || inDir(path, skip) {
+				continue
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				rel = filepath.Base(path)
+			}
+			jobs = append(jobs, batchJob{src: path, rel: rel})
+		}
+	default:
+		err := filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if inDir(path, skip) {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if !supported(path) {
+				return nil
+			}
+			rel, err := filepath.Rel(source, path)
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, batchJob{src: path, rel: rel})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jobs, nil
+}
+
+// outsideDir checks whether the cleaned relative path points outside of its base directory.
+func outsideDir(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// inDir checks whether the path is the directory itself or is located under it.
+// No path is located under an empty directory name.
+func inDir(path, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	a, err1 := filepath.Abs(dir)
+	b, err2 := filepath.Abs(path)
+	if errors.Join(err1, err2) != nil {
+		return false
+	}
+	rel, err := filepath.Rel(a, b)
+	return err == nil && !outsideDir(rel)
+}
+
+// globRoot returns the directory preceding the first path element of the pattern containing a wildcard.
+func globRoot(pattern string) string {
+	dir := filepath.Dir(pattern)
+	for strings.ContainsAny(dir, "*?[") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// runBatch detects the faces of all the images of a batch run, using a pool of workers sharing the same cascades.
+// The annotated images are mirrored into the destination directory and the results are aggregated into a single
+// json array, or written as newline delimited json in their completion order. The files failing to be processed
+// are reported in the results without aborting the run, but the process exits with a non-zero status.
+func runBatch(fd *faceDetector, opts batchOptions) {
+	if fd.destination == pipeName {
+		log.Fatalf("%sThe batch mode requires the -out flag to be a directory or empty%s", errorColor, defaultColor)
+	}
+	skip := fd.destination
+	if skip == "empty" {
+		skip = ""
+	}
+	jobs, err := batchJobs(opts.source, opts.list, skip)
+	if err != nil {
+		log.Fatalf("%sError listing the source images: %v%s", errorColor, err, defaultColor)
+	}
+	if len(jobs) == 0 {
+		log.Fatalf("%sNo source images found%s", errorColor, defaultColor)
+	}
+	if fd.destination != "empty" && opts.list == "" && sameDir(fd.destination, opts.source) {
+		log.Fatalf("%sThe destination directory should differ from the source directory%s", errorColor, defaultColor)
+	}
+	format := export.Format(opts.format)
+	if fd.destination != "empty" || (format.PerImage() && opts.jsonf != "") {
+		if err := checkDestinations(jobs, fd.destination); err != nil {
+			log.Fatalf("%s%v%s", errorColor, err, defaultColor)
+		}
+	}
+	if format.PerImage() && opts.jsonf == pipeName {
+		log.Fatalf("%sThe %s annotations of a batch run should be written into a directory%s", errorColor, format, defaultColor)
+	}
+
+	analyzer, err := fd.newAnalyzer()
+	if err != nil {
+		log.Fatalf("%sDetection error: %v%s", errorColor, err, defaultColor)
+	}
+
+	var out io.Writer
+	var jsonFile *os.File
+	switch {
+	case opts.jsonf == "" || opts.format != jsonFormat:
+	case opts.jsonf == pipeName:
+		out = os.Stdout
+	default:
+		jsonFile, err = os.Create(opts.jsonf)
+		if err != nil {
+			log.Fatalf("%sCould not create the json file: %v%s", errorColor, err, defaultColor)
+		}
+		out = jsonFile
+	}
+
+	var (
+		records = make([]any, len(jobs))
+		images  = make([]export.Image, len(jobs))
+		errs    = make([]error, len(jobs))
+		queue   = make(chan int)
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		encErr  error
+	)
+	workers := opts.workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				img, imgParams, err := fd.processFile(analyzer, jobs[i])
+				images[i], errs[i] = img, err
+				records[i] = fd.newRecord(jobs[i].src, img.Faces, imgParams, err)
+
+				if opts.ndjson && out != nil {
+					mu.Lock()
+					if err := json.NewEncoder(out).Encode(records[i]); err != nil && encErr == nil {
+						encErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range jobs {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	if !opts.ndjson && out != nil {
+		encErr = json.NewEncoder(out).Encode(records)
+	}
+	if jsonFile != nil {
+		if err := jsonFile.Close(); err != nil && encErr == nil {
+			encErr = err
+		}
+	}
+	if encErr != nil {
+		log.Fatalf("%sError encoding the json file: %v%s", errorColor, encErr, defaultColor)
+	}
+	if opts.jsonf != "" && opts.format != jsonFormat {
+		// The annotations describe only the successfully processed images.
+		var (
+			processed []export.Image
+			rels      []string
+		)
+		for i, img := range images {
+			if errs[i] == nil {
+				processed = append(processed, img)
+				rels = append(rels, jobs[i].rel)
+			}
+		}
+		if format.PerImage() {
+			err = writeImageAnnotations(opts.jsonf, format, processed, rels, opts.export)
+		} else {
+			err = writeAnnotations(opts.jsonf, format, processed, opts.export)
+		}
+		if err != nil {
+			log.Fatalf("%sError writing the %s annotations: %v%s", errorColor, format, err, defaultColor)
+		}
+	}
+
+	var faces, failed int
+	for i, img := range images {
+		if errs[i] != nil {
+			failed++
+			log.Printf("%s%s: %s%s", errorColor, jobs[i].src, errs[i], defaultColor)
+		}
+		faces += len(img.Faces)
+	}
+	log.Printf("\n%s%d%s face(s) detected in %d file(s)", successColor, faces, defaultColor, len(jobs)-failed)
+	if failed > 0 {
+		log.Printf("%s%d of %d file(s) failed to be processed%s", errorColor, failed, len(jobs), defaultColor)
+		os.Exit(1)
+	}
+}
+
+// processFile detects the faces of the batch job image and writes the annotated image into the destination
+// directory, unless the image output is disabled. The grayscale image the faces were detected on is returned too.
+func (fd *faceDetector) processFile(analyzer *pigo.FaceAnalyzer, job batchJob) (export.Image, pigo.ImageParams, error) {
+	dc, faces, imgParams, err := fd.detectFaces(analyzer, job.src)
+	if err != nil {
+		return export.Image{}, pigo.ImageParams{}, err
+	}
+	img := export.Image{File: job.src, Width: dc.Width(), Height: dc.Height(), Faces: faces}
+	if fd.destination == "empty" {
+		return img, imgParams, nil
+	}
+
+	path := filepath.Join(fd.destination, job.rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return export.Image{}, pigo.ImageParams{}, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return export.Image{}, pigo.ImageParams{}, err
+	}
+	if err := encodeImage(f, dc.Image()); err != nil {
+		f.Close()
+		return export.Image{}, pigo.ImageParams{}, fmt.Errorf("error encoding the output image: %w", err)
+	}
+	return img, imgParams, f.Close()
+}
+
+// checkDestinations checks that the batch jobs are mirrored to distinct paths, so the concurrent workers
+// never overwrite each other's output, and that no source image is overwritten by its annotated copy.
+// The destination is the output directory, or "empty" if only the annotations are written.
+func checkDestinations(jobs []batchJob, destination string) error {
+	seen := make(map[string]string, len(jobs))
+	for _, job := range jobs {
+		if prev, ok := seen[job.rel]; ok {
+			return fmt.Errorf("%s and %s would be written to the same destination: %s", prev, job.src, job.rel)
+		}
+		seen[job.rel] = job.src
+
+		if destination == "empty" {
+			continue
+		}
+		src, err1 := filepath.Abs(job.src)
+		dst, err2 := filepath.Abs(filepath.Join(destination, job.rel))
+		if err := errors.Join(err1, err2); err != nil {
+			return err
+		}
+		if src == dst {
+			return fmt.Errorf("%s would be overwritten by its annotated copy", job.src)
+		}
+	}
+	return nil
+}
+
+// sameDir checks whether the source, a directory or a glob pattern, resolves to the destination directory.
+func sameDir(dst, source string) bool {
+	if strings.ContainsAny(source, "*?[") {
+		source = globRoot(source)
+	}
+	a, err1 := filepath.Abs(dst)
+	b, err2 := filepath.Abs(source)
+	return errors.Join(err1, err2) == nil && a == b
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBatch_ShouldSkipNestedDestination(t *testing.T) {
+	src := t.TempDir()
+	for _, name := range []string{"a.jpg", "sub/b.png", "out/a.jpg", "out/sub/b.png", "notes.txt"} {
+		path := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{"a.jpg", filepath.Join("sub", "b.png")}
+
+	for _, source := range []string{src, filepath.Join(src, "*"), filepath.Join(src, "*", "*")} {
+		jobs, err := batchJobs(source, "", filepath.Join(src, "out"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var rels []string
+		for _, job := range jobs {
+			rels = append(rels, job.rel)
+		}
+		want := expected
+		switch source {
+		case filepath.Join(src, "*"):
+			want = expected[:1]
+		case filepath.Join(src, "*", "*"):
+			want = expected[1:]
+		}
+		if !reflect.DeepEqual(rels, want) {
+			t.Errorf("%s: expected the %v images, got: %v", source, want, rels)
+		}
+	}
+
+	// The destination containing the source directory doesn't exclude the source images.
+	jobs, err := batchJobs(filepath.Join(src, "sub"), "", src)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(jobs) != 1 {
+		t.Errorf("expected a single image, got: %v", jobs)
+	}
+}
+
+func TestBatch_ShouldMirrorListedPathsOutsideWorkingDir(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "list.txt")
+	data := "..foo.jpg\ndir/../..bar.jpg\n../up.jpg\n/abs/c.jpg\n"
+	if err := os.WriteFile(list, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := batchJobs("", list, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var rels []string
+	for _, job := range jobs {
+		rels = append(rels, job.rel)
+	}
+	want := []string{"..foo.jpg", "..bar.jpg", "up.jpg", "c.jpg"}
+	if !reflect.DeepEqual(rels, want) {
+		t.Errorf("expected the %v relative paths, got: %v", want, rels)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// benchStats summarizes the durations measured over the benchmark runs, in milliseconds.
+type benchStats struct {
+	Min    float64 `json:"min_ms"`
+	Mean   float64 `json:"mean_ms"`
+	Median float64 `json:"median_ms"`
+	P95    float64 `json:"p95_ms"`
+	Max    float64 `json:"max_ms"`
+}
+
+// benchReport holds the benchmark results. The detection stage runs the face detection cascade,
+// while the analysis stage localizes the pupils and the facial landmark points of the detected faces.
+type benchReport struct {
+	Image     imageInfo  `json:"image"`
+	Runs      int        `json:"runs"`
+	Faces     int        `json:"faces"`
+	Detection benchStats `json:"detection"`
+	Analysis  benchStats `json:"analysis"`
+	Total     benchStats `json:"total"`
+	FPS       float64    `json:"fps"`
+}
+
+// benchDetector runs the bench subcommand, which measures the time spent by the face detection
+// and by the face analysis stages over repeated runs on the same image.
+func benchDetector(args []string) {
+	fs := newFlagSet("bench", "pigo bench -in input.jpg [-runs 20] [-json]",
+		"Measure the time spent by the face detection and the face analysis over repeated runs on the same image.")
+	var (
+		source      = fs.String("in", "", "Source image")
+		runs        = fs.Int("runs", 20, "Number of measured runs")
+		warmup      = fs.Int("warmup", 2, "Number of runs executed before the measurement")
+		asJSON      = fs.Bool("json", false, "Print the results as json")
+		newDetector = detectorFlags(fs)
+	)
+	fs.Parse(args)
+
+	if len(*source) == 0 {
+		usageError(fs, "The source image is missing")
+	}
+	if *runs <= 0 || *warmup < 0 {
+		usageError(fs, "The number of runs should be positive")
+	}
+
+	src, err := decodeSource(*source)
+	if err != nil {
+		log.Fatalf("%sError decoding the source image: %v%s", errorColor, err, defaultColor)
+	}
+	analyzer, err := newDetector().newAnalyzer()
+	if err != nil {
+		log.Fatalf("%sDetection error: %v%s", errorColor, err, defaultColor)
+	}
+	imgParams := grayscaleParams(src)
+
+	var detection, analysis, total []time.Duration
+	var faces int
+	for i := 0; i < *warmup+*runs; i++ {
+		start := time.Now()
+		dets := analyzer.DetectFaces(imgParams)
+		detected := time.Now()
+		for _, det := range dets {
+			analyzer.AnalyzeFace(det, imgParams)
+		}
+		end := time.Now()
+
+		if i < *warmup {
+			continue
+		}
+		detection = append(detection, detected.Sub(start))
+		analysis = append(analysis, end.Sub(detected))
+		total = append(total, end.Sub(start))
+		faces = len(dets)
+	}
+
+	report := benchReport{
+		Image:     imageInfo{File: *source, Width: imgParams.Cols, Height: imgParams.Rows},
+		Runs:      *runs,
+		Faces:     faces,
+		Detection: newBenchStats(detection),
+		Analysis:  newBenchStats(analysis),
+		Total:     newBenchStats(total),
+	}
+	if report.Total.Mean > 0 {
+		report.FPS = 1000 / report.Total.Mean
+	}
+
+	if *asJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			log.Fatalf("%sError encoding the json results: %v%s", errorColor, err, defaultColor)
+		}
+		return
+	}
+	fmt.Printf("%s (%dx%d), %d face(s), %d runs\n\n", *source, imgParams.Cols, imgParams.Rows, faces, *runs)
+	fmt.Printf("%-10s %9s %9s %9s %9s %9s\n", "stage", "min", "mean", "median", "p95", "max")
+	for _, stage := range []struct {
+		name  string
+		stats benchStats
+	}{
+		{"detection", report.Detection},
+		{"analysis", report.Analysis},
+		{"total", report.Total},
+	} {
+		st := stage.stats
+		fmt.Printf("%-10s %7.2fms %7.2fms %7.2fms %7.2fms %7.2fms\n", stage.name, st.Min, st.Mean, st.Median, st.P95, st.Max)
+	}
+	fmt.Printf("\n%s%.1f%s frames per second\n", successColor, report.FPS, defaultColor)
+}
+
+// newBenchStats summarizes the measured durations.
+func newBenchStats(durations []time.Duration) benchStats {
+	ms := make([]float64, len(durations))
+	var sum float64
+	for i, d := range durations {
+		ms[i] = float64(d) / float64(time.Millisecond)
+		sum += ms[i]
+	}
+	sort.Float64s(ms)
+
+	n := len(ms)
+	return benchStats{
+		Min:    ms[0],
+		Mean:   sum / float64(n),
+		Median: ms[n/2],
+		P95:    ms[min(n-1, n*95/100)],
+		Max:    ms[n-1],
+	}
+}
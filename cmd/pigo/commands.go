@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// The exit codes of the command line utility.
+const (
+	// exitFailure - the processing failed, e.g. the source image could not be decoded.
+	exitFailure = 1
+	// exitUsage - the command line arguments are invalid.
+	exitUsage = 2
+)
+
+// command is a subcommand of the command line utility.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string)
+}
+
+// commands lists the subcommands of the command line utility. The detect subcommand is run when none is named.
+var commands = []command{
+	{"detect", "Detect the faces and mark them on the source image (default)", runDetect},
+	{"crop", "Write the detected faces into separate image files", cropFaces},
+	{"redact", "Anonymize the detected faces", redactFaces},
+	{"overlay", "Composite an image asset over the detected faces", overlayFaces},
+	{"redeye", "Remove the red-eye effect from the detected faces", correctRedEyes},
+	{"inspect", "Print the detection settings and the loaded cascades", inspectDetector},
+	{"bench", "Measure the face detection and analysis time", benchDetector},
+	{"serve", "Run the face detection as an HTTP service", serveDetector},
+}
+
+// lookupCommand returns the subcommand with the provided name.
+func lookupCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// printUsage prints the general help text, listing the subcommands.
+func printUsage() {
+	fmt.Fprintf(os.Stderr, banner, Version)
+	fmt.Fprintln(os.Stderr, "Usage: pigo [command] [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s%s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'pigo <command> -h' for the flags of a command.")
+	fmt.Fprintf(os.Stderr, "\nExit codes: 0 on success, %d if the processing failed, %d for invalid arguments.\n", exitFailure, exitUsage)
+}
+
+// newFlagSet returns the flag set of a subcommand. Its help text shows the usage line and the description of the subcommand.
+func newFlagSet(name, usage, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, banner, Version)
+		fmt.Fprintf(os.Stderr, "Usage: %s\n\n%s\n\n", usage, description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// usageError reports the invalid arguments of the subcommand and exits with the usage error code.
+func usageError(fs *flag.FlagSet, format string, args ...any) {
+	log.Printf("%s%s%s", errorColor, fmt.Sprintf(format, args...), defaultColor)
+	log.Printf("Run 'pigo %s -h' for the supported flags.", fs.Name())
+	os.Exit(exitUsage)
+}
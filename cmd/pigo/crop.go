@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/esimov/pigo/align"
+)
+
+// cropFaces runs the crop subcommand, which writes the detected faces into separate image files, one file per face.
+func cropFaces(args []string) {
+	fs := newFlagSet("crop", "pigo crop -in input.jpg -out faces/ [-aligned] [-quality]",
+		"Write the detected faces into the destination directory, one png file per face.")
+	var (
+		source      = fs.String("in", "", "Source image")
+		destination = fs.String("out", ".", "Destination directory")
+		aligned     = fs.Bool("aligned", false, "Rotate, scale and crop the faces so that the eyes are level")
+		width       = fs.Int("width", 112, "Width of the aligned face chips")
+		height      = fs.Int("height", 112, "Height of the aligned face chips")
+		hMargin     = fs.Float64("hmargin", 0.3, "Distance between the aligned chip edges and the pupils, as a fraction of the chip width")
+		topMargin   = fs.Float64("tmargin", 0.35, "Distance between the aligned chip top edge and the eye line, as a fraction of the chip height")
+		newDetector = detectorFlags(fs)
+		newFilter   = qualityFlags(fs)
+	)
+	fs.Parse(args)
+
+	if len(*source) == 0 {
+		usageError(fs, "The source image is missing")
+	}
+	params := align.Params{
+		Width:            *width,
+		Height:           *height,
+		HorizontalMargin: *hMargin,
+		TopMargin:        *topMargin,
+	}
+	if err := params.Validate(); *aligned && err != nil {
+		usageError(fs, "Invalid aligned face chip settings: %v", err)
+	}
+
+	src, err := decodeSource(*source)
+	if err != nil {
+		log.Fatalf("%sError decoding the source image: %v%s", errorColor, err, defaultColor)
+	}
+	analyzer, err := newDetector().newAnalyzer()
+	if err != nil {
+		log.Fatalf("%sDetection error: %v%s", errorColor, err, defaultColor)
+	}
+	imgParams := grayscaleParams(src)
+	faces := newFilter().apply(analyzer.AnalyzeParams(imgParams), imgParams)
+
+	var crops []image.Image
+	if *aligned {
+		chips, err := align.Faces(src, faces, params)
+		if err != nil {
+			log.Fatalf("%sError aligning the faces: %v%s", errorColor, err, defaultColor)
+		}
+		for _, chip := range chips {
+			crops = append(crops, chip.Image)
+		}
+	} else {
+		for _, face := range faces {
+			crops = append(crops, imaging.Crop(src, face.Box))
+		}
+	}
+
+	if err := os.MkdirAll(*destination, 0755); err != nil {
+		log.Fatalf("%sUnable to create the destination directory: %v%s", errorColor, err, defaultColor)
+	}
+	name := strings.TrimSuffix(filepath.Base(*source), filepath.Ext(*source))
+	if *source == pipeName {
+		name = "face"
+	}
+	for i, crop := range crops {
+		path := filepath.Join(*destination, fmt.Sprintf("%s_%d.png", name, i))
+		if err := writePNG(path, crop); err != nil {
+			log.Fatalf("%sError writing the face crop: %v%s", errorColor, err, defaultColor)
+		}
+	}
+	log.Printf("%s%d%s face(s) written to %s", successColor, len(crops), defaultColor, *destination)
+}
+
+// writePNG encodes the image into the png file found at path.
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/esimov/pigo/export"
+)
+
+// jsonFormat is the default results format: the detection points in the pigo json schema.
+const jsonFormat = "json"
+
+// resultFormats are the supported values of the -format flag.
+var resultFormats = []string{jsonFormat, string(export.COCO), string(export.VOC), string(export.YOLO), string(export.CSV)}
+
+// writeAnnotations writes the annotations of the images into the file found at path, or to stdout.
+func writeAnnotations(path string, f export.Format, images []export.Image, opts export.Options) error {
+	if path == pipeName {
+		return export.Encode(os.Stdout, f, images, opts)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := export.Encode(file, f, images, opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeImageAnnotations writes the annotations of each image into a separate file of the directory,
+// using the relative path of the image with the extension of the format. The YOLO class names are
+// written into the classes.txt file of the directory.
+func writeImageAnnotations(dir string, f export.Format, images []export.Image, rels []string, opts export.Options) error {
+	for i, img := range images {
+		rel := strings.TrimSuffix(rels[i], filepath.Ext(rels[i])) + f.Ext()
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := writeAnnotations(path, f, []export.Image{img}, opts); err != nil {
+			return err
+		}
+	}
+	if f == export.YOLO {
+		return os.WriteFile(filepath.Join(dir, "classes.txt"), []byte(opts.Label+"\n"), 0644)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/utils"
+	"github.com/fogleman/gg"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	"golang.org/x/term"
+)
+
+// grayscaleParams converts the image into the grayscale pixel array used by the detector.
+func grayscaleParams(img *image.NRGBA) pigo.ImageParams {
+	cols, rows := img.Bounds().Dx(), img.Bounds().Dy()
+	return pigo.ImageParams{
+		Pixels: pigo.RgbToGrayscale(img),
+		Rows:   rows,
+		Cols:   cols,
+		Dim:    cols,
+	}
+}
+
+// newCanvas returns a drawing context holding a copy of the image.
+func newCanvas(src image.Image) *gg.Context {
+	dc := gg.NewContext(src.Bounds().Dx(), src.Bounds().Dy())
+	dc.DrawImage(src, 0, 0)
+	return dc
+}
+
+// decodeSource decodes the source image, which can be a local file, an URL or the stdin pipe.
+func decodeSource(source string) (*image.NRGBA, error) {
+	data, err := readSource(source)
+	if err != nil {
+		return nil, err
+	}
+	return pigo.DecodeImage(bytes.NewReader(data))
+}
+
+// readSource reads the encoded source image, which can be a local file, an URL or the stdin pipe.
+func readSource(source string) ([]byte, error) {
+	// Check if source path is a local image or URL.
+	if utils.IsValidUrl(source) {
+		src, err := utils.DownloadImage(source)
+		if err != nil {
+			return nil, err
+		}
+		// Close and remove the generated temporary file.
+		defer src.Close()
+		defer os.Remove(src.Name())
+
+		return os.ReadFile(src.Name())
+	}
+	if source == pipeName {
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			log.Fatalln("`-` should be used with a pipe for stdin")
+		}
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(source)
+}
+
+// encodeImage encodes the image in the format given by the destination file extension,
+// or as jpeg if the destination is not a file.
+func encodeImage(dst io.Writer, img image.Image) error {
+	if f, ok := dst.(*os.File); ok {
+		return encodeFormat(dst, img, strings.ToLower(filepath.Ext(f.Name())))
+	}
+	return encodeFormat(dst, img, ".jpg")
+}
+
+// encodeFormat encodes the image in the format given by the file extension.
+// The images without an extension are encoded as jpeg.
+func encodeFormat(dst io.Writer, img image.Image, ext string) error {
+	switch ext {
+	case "", ".jpg", ".jpeg":
+		return jpeg.Encode(dst, img, &jpeg.Options{Quality: 100})
+	case ".png":
+		return png.Encode(dst, img)
+	case ".bmp":
+		return bmp.Encode(dst, img)
+	case ".tif", ".tiff":
+		return tiff.Encode(dst, img, &tiff.Options{Compression: tiff.Deflate})
+	}
+	return errors.New("unsupported image format")
+}
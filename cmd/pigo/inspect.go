@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// inspectedCascade is an unpacked cascade listed by the inspect subcommand.
+type inspectedCascade struct {
+	name string
+	info pigo.CascadeInfo
+}
+
+// inspectDetector runs the inspect subcommand, which prints the effective detection settings,
+// resolved from the preset, the configuration file and the flags, the cascades used by the detector and their statistics.
+func inspectDetector(args []string) {
+	fs := newFlagSet("inspect", "pigo inspect [-preset name] [-config settings.json] [-heatmap out.png -cascade name] [detection flags]",
+		"Print the effective detection settings, the cascades used by the detector and their statistics.\n"+
+			"The settings are printed in the configuration file format, so they can be saved and reused with -config.")
+	var (
+		heatmap     = fs.String("heatmap", "", "Render the density of the pixels compared by the cascade trees into a png file")
+		heatmapSize = fs.Int("heatmap-size", 256, "Size of the rendered heatmap")
+		cascadeName = fs.String("cascade", "facefinder", "Cascade of the heatmap: facefinder, puploc or a landmark cascade name (like lp42)")
+		newDetector = detectorFlags(fs)
+	)
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		usageError(fs, "Unexpected arguments: %v", fs.Args())
+	}
+	if *heatmapSize <= 0 {
+		usageError(fs, "The heatmap size should be positive")
+	}
+	if *heatmap != "" && strings.ToLower(filepath.Ext(*heatmap)) != ".png" {
+		usageError(fs, "The heatmap should be a png file")
+	}
+
+	fd := newDetector()
+	classifier, plc, flpcs, err := fd.loadCascades()
+	if err != nil {
+		log.Fatalf("%sError loading the cascades: %v%s", errorColor, err, defaultColor)
+	}
+
+	source := func(path string) string {
+		switch path {
+		case "":
+			return "embedded"
+		case noCascade:
+			return "disabled"
+		}
+		return path
+	}
+
+	fmt.Println("Detection settings:")
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(fd.config); err != nil {
+		log.Fatalf("%sError encoding the settings: %v%s", errorColor, err, defaultColor)
+	}
+
+	cascades := inspectCascades(classifier, plc, flpcs)
+	if *heatmap != "" {
+		idx := -1
+		for i, c := range cascades {
+			if c.name == *cascadeName {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			usageError(fs, "Unknown or disabled cascade: %s", *cascadeName)
+		}
+		if err := writePNG(*heatmap, cascades[idx].info.Heatmap(*heatmapSize)); err != nil {
+			log.Fatalf("%sError writing the heatmap: %v%s", errorColor, err, defaultColor)
+		}
+	}
+
+	fmt.Println("\nCascades:")
+	fmt.Printf("  %-12s%s\n", "facefinder", source(fd.cascadeFile))
+	if plc == nil {
+		fmt.Printf("  %-12s%s\n", "puploc", "disabled")
+		fmt.Printf("  %-12s%s\n", "landmarks", "disabled")
+		printCascadeStats(cascades)
+		return
+	}
+	fmt.Printf("  %-12s%s\n", "puploc", source(fd.puploc))
+	if flpcs == nil {
+		fmt.Printf("  %-12s%s\n", "landmarks", "disabled")
+		printCascadeStats(cascades)
+		return
+	}
+
+	var points []string
+	for name, cascades := range flpcs {
+		if len(cascades) > 0 {
+			points = append(points, name)
+		}
+	}
+	sort.Strings(points)
+	fmt.Printf("  %-12s%s, %d points\n", "landmarks", source(fd.flploc), len(points))
+	for _, name := range points {
+		fmt.Printf("    %s\n", name)
+	}
+	printCascadeStats(cascades)
+}
+
+// inspectCascades returns the statistics of the loaded cascades. The landmark cascades
+// shared by the points of both face sides are listed once, in the manifest order.
+func inspectCascades(classifier *pigo.Pigo, plc *pigo.PuplocCascade, flpcs map[string][]*pigo.FlpCascade) []inspectedCascade {
+	cascades := []inspectedCascade{{"facefinder", classifier.Info()}}
+	if plc == nil {
+		return cascades
+	}
+	cascades = append(cascades, inspectedCascade{"puploc", plc.Info()})
+
+	seen := make(map[string]bool)
+	for _, spec := range pigo.LandmarkManifest {
+		if seen[spec.Cascade] {
+			continue
+		}
+		for _, flpc := range flpcs[spec.Name] {
+			if flpc.PuplocCascade != nil {
+				cascades = append(cascades, inspectedCascade{spec.Cascade, flpc.Info()})
+				seen[spec.Cascade] = true
+				break
+			}
+		}
+	}
+	return cascades
+}
+
+// printCascadeStats prints the structure, the leaf predictions and the compared pixel offsets of the cascades.
+// The offsets are expressed in 1/256 units of the inspected region size, relative to its center.
+func printCascadeStats(cascades []inspectedCascade) {
+	fmt.Println("\nCascade statistics:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  cascade\tstages\ttrees/stage\tdepth\tscale\tthresholds\tpredictions\trow offsets\tcol offsets")
+	for _, c := range cascades {
+		var (
+			info       = c.info
+			thresholds = "-"
+			preds      []string
+		)
+		if len(info.Thresholds) > 0 {
+			thr := pigo.Range{Min: info.Thresholds[0], Max: info.Thresholds[0]}
+			for _, t := range info.Thresholds {
+				thr.Min, thr.Max = min(thr.Min, t), max(thr.Max, t)
+			}
+			thresholds = formatRange(thr)
+		}
+		for _, r := range info.Predictions {
+			preds = append(preds, formatRange(r))
+		}
+		rows, cols := info.Offsets()
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%d\t%.2f\t%s\t%s\t%s\t%s\n",
+			c.name, info.Stages, info.TreesPerStage, info.Depth, info.ScaleFactor,
+			thresholds, strings.Join(preds, " "), formatRange(rows), formatRange(cols))
+	}
+	w.Flush()
+}
+
+// formatRange formats the range as a closed interval.
+func formatRange(r pigo.Range) string {
+	return fmt.Sprintf("[%.3g, %.3g]", r.Min, r.Max)
+}
@@ -1,14 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
 	"image"
-	"image/color"
-	"image/jpeg"
-	"image/png"
 	"io"
 	"io/ioutil"
 	"log"
@@ -18,8 +15,13 @@ import (
 	"strings"
 	"time"
 
-	"github.com/disintegration/imaging"
+	"github.com/esimov/pigo/blink"
+	"github.com/esimov/pigo/cascade"
+	"github.com/esimov/pigo/config"
 	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/export"
+	"github.com/esimov/pigo/quality"
+	"github.com/esimov/pigo/render"
 	"github.com/esimov/pigo/utils"
 	"github.com/fogleman/gg"
 	"golang.org/x/term"
@@ -38,133 +40,260 @@ Go (Golang) Face detection library.
 // pipeName is the file name that indicates stdin/stdout is being used.
 const pipeName = "-"
 
+// noCascade is the cascade flag value that disables the related detection.
+const noCascade = "none"
+
+// imageExts are the still image file types accepted as source and destination.
+var imageExts = []string{".jpg", ".jpeg", ".png", ".bmp", ".tif", ".tiff"}
+
+// message colors
 const (
-	// markerRectangle - use rectangle as face detection marker
-	markerRectangle string = "rect"
-	// markerCircle - use circle as face detection marker
-	markerCircle string = "circle"
-	// markerEllipse - use ellipse as face detection marker
-	markerEllipse string = "ellipse"
-
-	// message colors
 	successColor = "\x1b[92m"
 	errorColor   = "\x1b[31m"
 	defaultColor = "\x1b[0m"
-
-	perturb = 63
 )
 
 // Version indicates the current build version.
 var Version string
 
-var (
-	dc        *gg.Context
-	det       *faceDetector
-	plc       *pigo.PuplocCascade
-	flpcs     map[string][]*pigo.FlpCascade
-	imgParams *pigo.ImageParams
-)
-
-var (
-	eyeCascades   = [5]string{"lp46", "lp44", "lp42", "lp38", "lp312"}
-	mouthCascades = [4]string{"lp93", "lp84", "lp82", "lp81"}
-)
-
 // faceDetector struct contains Pigo face detector general settings.
 type faceDetector struct {
-	cascadeFile  string
-	destination  string
-	puploc       string
-	flploc       string
-	angle        float64
-	minSize      int
-	maxSize      int
-	shiftFactor  float64
-	scaleFactor  float64
-	iouThreshold float64
-	markDetEyes  bool
+	cascadeFile string
+	destination string
+	puploc      string
+	flploc      string
+	config      config.Config
+	render      render.Params
+	schema      string
+	filter      *qualityFilter
 }
 
-// coord holds the detection coordinates
+// coord holds the detection coordinates in the v1 json schema. Despite their json names, x holds
+// the column and y the row of the point, while the zero values are omitted. The fields are kept
+// unchanged for backward compatibility; the v2 schema describes the points unambiguously.
 type coord struct {
 	Row   int `json:"x,omitempty"`
 	Col   int `json:"y,omitempty"`
 	Scale int `json:"size,omitempty"`
 }
 
-// detection holds the detection points of the various detection types
+// eyeGaze holds the normalized pupil offset within the eye box
+type eyeGaze struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// gaze holds the gaze estimation of the detected face
+type gaze struct {
+	Left      *eyeGaze `json:"left,omitempty"`
+	Right     *eyeGaze `json:"right,omitempty"`
+	X         float64  `json:"x"`
+	Y         float64  `json:"y"`
+	Direction string   `json:"direction"`
+}
+
+// headPose holds the head orientation of the detected face in degrees
+type headPose struct {
+	Yaw   float64 `json:"yaw"`
+	Pitch float64 `json:"pitch"`
+	Roll  float64 `json:"roll"`
+	Fit   float64 `json:"fit"`
+}
+
+// openness holds the absolute eye openness measured by the blink detector
+type openness struct {
+	Left  *float64 `json:"left,omitempty"`
+	Right *float64 `json:"right,omitempty"`
+}
+
+// faceQuality holds the quality assessment of the detected face
+type faceQuality struct {
+	Sharpness   float64          `json:"sharpness"`
+	Brightness  float64          `json:"brightness"`
+	Contrast    float64          `json:"contrast"`
+	Size        int              `json:"size"`
+	IOD         float64          `json:"iod"`
+	Frontalness *float64         `json:"frontalness,omitempty"`
+	Rotation    *float64         `json:"rotation,omitempty"`
+	Score       float64          `json:"score"`
+	Pass        bool             `json:"pass"`
+	Reasons     []quality.Reason `json:"reasons,omitempty"`
+}
+
+// detection holds the detection points of the various detection types in the v1 json schema
 type detection struct {
-	EyePoints      []coord `json:"eyes,omitempty"`
-	LandmarkPoints []coord `json:"landmark_points,omitempty"`
-	FacePoints     coord   `json:"face,omitempty"`
+	EyePoints      []coord      `json:"eyes,omitempty"`
+	LandmarkPoints []coord      `json:"landmark_points,omitempty"`
+	FacePoints     coord        `json:"face,omitempty"`
+	Gaze           *gaze        `json:"gaze,omitempty"`
+	Openness       *openness    `json:"openness,omitempty"`
+	Pose           *headPose    `json:"pose,omitempty"`
+	Quality        *faceQuality `json:"quality,omitempty"`
 }
 
 func main() {
+	log.SetFlags(0)
+
+	args := os.Args[1:]
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		if len(args) > 0 && inSlice(args[0], []string{"-h", "-help", "--help"}) {
+			printUsage()
+			return
+		}
+		// The face detection is run without naming it, for backward compatibility.
+		runDetect(args)
+		return
+	}
+
+	name, args := args[0], args[1:]
+	if name == "help" {
+		if len(args) == 0 {
+			printUsage()
+			return
+		}
+		// Print the help text of the subcommand.
+		name, args = args[0], []string{"-h"}
+	}
+	cmd, ok := lookupCommand(name)
+	if !ok {
+		log.Printf("%sUnknown command: %s%s", errorColor, name, defaultColor)
+		printUsage()
+		os.Exit(exitUsage)
+	}
+	cmd.run(args)
+}
+
+// runDetect runs the detect subcommand, which marks the detected faces on the source image and optionally
+// writes the detection results into a file. Directories, glob patterns and file lists are processed in batch mode.
+func runDetect(args []string) {
+	fs := newFlagSet("detect",
+		"pigo [detect] -in input.jpg|dir/|\"*.jpg\" -out out.jpg|dir/|empty [-json results.json]",
+		"Detect the faces, pupils and facial landmark points and mark them on the source image.",
+	)
 	var (
-		// Flags
-		source       = flag.String("in", pipeName, "Source image")
-		destination  = flag.String("out", pipeName, "Destination image")
-		cascadeFile  = flag.String("cf", "", "Cascade binary file")
-		minSize      = flag.Int("min", 20, "Minimum size of face")
-		maxSize      = flag.Int("max", 1000, "Maximum size of face")
-		shiftFactor  = flag.Float64("shift", 0.15, "Shift detection window by percentage")
-		scaleFactor  = flag.Float64("scale", 1.15, "Scale detection window by percentage")
-		angle        = flag.Float64("angle", 0.0, "0.0 is 0 radians and 1.0 is 2*pi radians")
-		iouThreshold = flag.Float64("iou", 0.15, "Intersection over union (IoU) threshold")
-		marker       = flag.String("marker", "rect", "Detection marker: rect|circle|ellipse")
-		puploc       = flag.String("plc", "", "Pupils/eyes localization cascade file")
-		flploc       = flag.String("flpc", "", "Facial landmark points cascade directory")
-		markEyes     = flag.Bool("mark", true, "Mark detected eyes")
-		jsonf        = flag.String("json", "", "Output the detection points into a json file")
+		source      = fs.String("in", pipeName, "Source image")
+		destination = fs.String("out", pipeName, "Destination image")
+		jsonf       = fs.String("json", "", "Output the detection results into a file (a directory for the voc and yolo formats in batch mode)")
+		format      = fs.String("format", jsonFormat, "Detection results format: json|coco|voc|yolo|csv")
+		schema      = fs.String("json-schema", schemaV1, "Schema of the json results: v1|v2")
+		label       = fs.String("label", export.DefaultLabel, "Label of the face annotations")
+		confidence  = fs.Bool("confidence", false, "Append the detection confidence (0-1) to the yolo annotations, like the prediction files")
+		list        = fs.String("list", "", "File listing the source images of a batch run, one per line")
+		workers     = fs.Int("workers", 0, "Number of images processed concurrently in batch mode (defaults to the number of CPUs)")
+		ndjson      = fs.Bool("ndjson", false, "Write the batch results as newline delimited json")
+		fps         = fs.Float64("fps", 10, "Frame rate of the image sequences")
+		track       = fs.Bool("track", false, "Assign track identifiers to the faces detected on the frames of an animation")
+		svgImage    = fs.String("svg-image", svgEmbed, "Source image of the svg output: embed|link")
+		overlayOnly = fs.Bool("overlay-only", false, "Write only the annotations on a transparent background, into a png or svg file")
+		newDetector = detectorFlags(fs)
+		newFilter   = qualityFlags(fs)
+		newRender   = renderFlags(fs)
 	)
+	fs.Parse(args)
 
-	log.SetFlags(0)
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, fmt.Sprintf(banner, Version))
-		flag.PrintDefaults()
+	if len(*source) == 0 && len(*list) == 0 {
+		usageError(fs, "The source image is missing")
 	}
-	flag.Parse()
-
-	if len(*source) == 0 || len(*cascadeFile) == 0 {
-		log.Fatal("Usage: pigo -in input.jpg -out out.png -cf cascade/facefinder")
+	if !inSlice(*format, resultFormats) {
+		usageError(fs, "Unsupported results format: %s", *format)
+	}
+	if !inSlice(*schema, jsonSchemas) {
+		usageError(fs, "Unsupported json schema: %s", *schema)
+	}
+	if *ndjson && *format != jsonFormat {
+		usageError(fs, "The -ndjson flag can be used only with the json format")
+	}
+	if !inSlice(*svgImage, svgImages) {
+		usageError(fs, "Unsupported svg source image mode: %s", *svgImage)
+	}
+	for _, path := range []string{*source, *destination} {
+		if err := checkSequence(path); err != nil {
+			usageError(fs, "%v", err)
+		}
+	}
+	outExt := strings.ToLower(filepath.Ext(*destination))
+	animated := isAnimation(*source, *destination)
+	if animated {
+		ext := strings.ToLower(filepath.Ext(*destination))
+		switch {
+		case *destination == "empty", ext == ".gif", isSequence(*destination) && inSlice(ext, imageExts):
+		case *destination == pipeName:
+			if term.IsTerminal(int(os.Stdout.Fd())) {
+				usageError(fs, "`-` should be used with a pipe for stdout")
+			}
+		default:
+			usageError(fs, "Animation output not supported: %s (use a gif file or an image sequence pattern)", *destination)
+		}
+		if *format != jsonFormat {
+			usageError(fs, "The detection results of an animation can be written only in the json format")
+		}
+		if *fps <= 0 {
+			usageError(fs, "The frame rate should be positive")
+		}
+	} else if isSequence(*destination) {
+		usageError(fs, "The image sequence output requires an animated GIF or an image sequence source")
+	}
+	if outExt == svgExt || *overlayOnly {
+		switch {
+		case animated, *list != "", isBatch(*source):
+			usageError(fs, "The svg and the overlay only outputs are supported only for single images")
+		case *overlayOnly && outExt != ".png" && outExt != svgExt:
+			usageError(fs, "The overlay can be written only into a png or svg file")
+		case outExt == svgExt && !*overlayOnly && *svgImage == svgLink && *source == pipeName:
+			usageError(fs, "The source image read from stdin can't be linked, use -svg-image %s", svgEmbed)
+		}
 	}
 
 	start := time.Now()
 
+	det := newDetector()
+	det.destination = *destination
+	det.render = newRender()
+	det.schema = *schema
+	det.filter = newFilter()
+
+	if animated {
+		runAnimation(det, animationOptions{
+			source: *source,
+			jsonf:  *jsonf,
+			fps:    *fps,
+			track:  *track,
+		})
+		log.Printf("\nExecution time: %s%.2fs%s\n", successColor, time.Since(start).Seconds(), defaultColor)
+		return
+	}
+
+	if *list != "" || isBatch(*source) {
+		runBatch(det, batchOptions{
+			source:  *source,
+			list:    *list,
+			jsonf:   *jsonf,
+			ndjson:  *ndjson,
+			workers: *workers,
+			format:  *format,
+			export:  export.Options{Label: *label, Confidence: *confidence},
+		})
+		log.Printf("\nExecution time: %s%.2fs%s\n", successColor, time.Since(start).Seconds(), defaultColor)
+		return
+	}
+
 	// Progress indicator
 	spinner := utils.NewSpinner("Detecting faces...", time.Millisecond*100)
 	spinner.Start()
 
-	det = &faceDetector{
-		angle:        *angle,
-		destination:  *destination,
-		cascadeFile:  *cascadeFile,
-		minSize:      *minSize,
-		maxSize:      *maxSize,
-		shiftFactor:  *shiftFactor,
-		scaleFactor:  *scaleFactor,
-		iouThreshold: *iouThreshold,
-		puploc:       *puploc,
-		flploc:       *flploc,
-		markDetEyes:  *markEyes,
-	}
-
 	var dst io.Writer
 	if det.destination != "empty" {
 		if det.destination == pipeName {
 			if term.IsTerminal(int(os.Stdout.Fd())) {
-				log.Fatalln("`-` should be used with a pipe for stdout")
+				usageError(fs, "`-` should be used with a pipe for stdout")
 			}
 			dst = os.Stdout
 		} else {
-			fileTypes := []string{".jpg", ".jpeg", ".png"}
-			ext := filepath.Ext(strings.ToLower(det.destination))
-
-			if !inSlice(ext, fileTypes) {
-				log.Fatalf("Output file type not supported: %v", ext)
+			if outExt != svgExt && !inSlice(outExt, imageExts) {
+				usageError(fs, "Output file type not supported: %v", outExt)
 			}
 
-			fn, err := os.OpenFile(det.destination, os.O_CREATE|os.O_WRONLY, 0755)
+			fn, err := os.OpenFile(det.destination, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
 			if err != nil {
 				log.Fatalf("Unable to open output file: %v", err)
 			}
@@ -173,26 +302,57 @@ func main() {
 		}
 	}
 
-	faces, err := det.detectFaces(*source)
+	analyzer, err := det.newAnalyzer()
 	if err != nil {
 		spinner.StopMsg = fmt.Sprintf("Detecting faces... %s failed ✗%s\n", errorColor, defaultColor)
 		spinner.Stop()
 		log.Fatalf("Detection error: %s%v%s", errorColor, err, defaultColor)
 	}
-
-	dets, err := det.drawFaces(faces, *marker)
+	// The encoded source is kept, so the svg output can embed the original image.
+	data, err := readSource(*source)
 	if err != nil {
-		log.Fatalf("Error creating the image output: %s", err)
+		spinner.StopMsg = fmt.Sprintf("Detecting faces... %s failed ✗%s\n", errorColor, defaultColor)
+		spinner.Stop()
+		log.Fatalf("Detection error: %s%v%s", errorColor, err, defaultColor)
 	}
+	src, orientation, err := pigo.DecodeImageOrientation(bytes.NewReader(data))
+	if err != nil {
+		spinner.StopMsg = fmt.Sprintf("Detecting faces... %s failed ✗%s\n", errorColor, defaultColor)
+		spinner.Stop()
+		log.Fatalf("Detection error: %s%v%s", errorColor, err, defaultColor)
+	}
+	src = orientation.Upright(src)
+	faces, imgParams := det.analyze(analyzer, src)
 
 	if det.destination != "empty" {
-		if err := det.encodeImage(dst); err != nil {
+		out := imageOutput{
+			svg:         outExt == svgExt,
+			overlayOnly: *overlayOnly,
+			orientation: orientation,
+		}
+		if out.svg && !out.overlayOnly {
+			if *svgImage == svgLink {
+				out.href = sourceLink(*source, det.destination)
+			} else {
+				out.href = render.DataURI(data)
+			}
+		}
+		if err := det.writeImage(dst, src, faces, out); err != nil {
 			log.Fatalf("Error encoding the output image: %v", err)
 		}
 	}
 
+	if *jsonf != "" && *format != jsonFormat {
+		img := export.Image{File: *source, Width: src.Bounds().Dx(), Height: src.Bounds().Dy(), Faces: faces}
+		if err := writeAnnotations(*jsonf, export.Format(*format), []export.Image{img}, export.Options{Label: *label, Confidence: *confidence}); err != nil {
+			spinner.StopMsg = fmt.Sprintf("Detecting faces... %s failed ✗%s\n", errorColor, defaultColor)
+			spinner.Stop()
+			log.Fatalf("%sError writing the %s annotations: %v%s", errorColor, *format, err, defaultColor)
+		}
+	}
+
 	var out io.Writer
-	if *jsonf != "" {
+	if *jsonf != "" && *format == jsonFormat {
 		if *jsonf == pipeName {
 			out = os.Stdout
 		} else {
@@ -213,385 +373,267 @@ func main() {
 	spinner.StopMsg = fmt.Sprintf("Detecting faces... %s✔%s", successColor, defaultColor)
 	spinner.Stop()
 
-	if len(dets) > 0 {
-		log.Printf("\n%s%d%s face(s) detected", successColor, len(dets), defaultColor)
+	if len(faces) > 0 {
+		log.Printf("\n%s%d%s face(s) detected", successColor, len(faces), defaultColor)
 
 		if *jsonf != "" && out == os.Stdout {
 			log.Printf("\n%sThe detection coordinates of the found faces:%s", successColor, defaultColor)
 		}
-
-		if out != nil {
-			if err := json.NewEncoder(out).Encode(dets); err != nil {
-				log.Fatalf("Error encoding the json file: %s", err)
-			}
-		}
 	} else {
 		log.Printf("\n%sno detected faces!%s", errorColor, defaultColor)
 	}
 
-	log.Printf("\nExecution time: %s%.2fs%s\n", successColor, time.Since(start).Seconds(), defaultColor)
-}
-
-// detectFaces run the detection algorithm over the provided source image.
-func (fd *faceDetector) detectFaces(source string) ([]pigo.Detection, error) {
-	var srcFile io.Reader
-
-	// Check if source path is a local image or URL.
-	if utils.IsValidUrl(source) {
-		src, err := utils.DownloadImage(source)
-		if err != nil {
-			return nil, err
+	// The v1 results are written only if faces were found, while the v2 document describes the image anyway.
+	if out != nil && (len(faces) > 0 || det.schema == schemaV2) {
+		var res any = det.newDetections(faces, imgParams)
+		if det.schema == schemaV2 {
+			res = det.newResult(*source, faces, imgParams)
 		}
-		// Close and remove the generated temporary file.
-		defer src.Close()
-		defer os.Remove(src.Name())
-
-		img, err := os.Open(src.Name())
-		if err != nil {
-			return nil, err
-		}
-		srcFile = img
-	} else {
-		if source == pipeName {
-			if term.IsTerminal(int(os.Stdin.Fd())) {
-				log.Fatalln("`-` should be used with a pipe for stdin")
-			}
-			srcFile = os.Stdin
-		} else {
-			file, err := os.Open(source)
-			if err != nil {
-				return nil, err
-			}
-			defer file.Close()
-			srcFile = file
+		if err := json.NewEncoder(out).Encode(res); err != nil {
+			log.Fatalf("Error encoding the json file: %s", err)
 		}
 	}
 
-	src, err := pigo.DecodeImage(srcFile)
+	log.Printf("\nExecution time: %s%.2fs%s\n", successColor, time.Since(start).Seconds(), defaultColor)
+}
+
+// detectFaces runs the detection algorithm over the provided source image and marks the detected faces
+// on a copy of it. It returns the grayscale image as well, since the json results are measured over it.
+// The faces failing the quality filter are discarded.
+func (fd *faceDetector) detectFaces(analyzer *pigo.FaceAnalyzer, source string) (*gg.Context, []pigo.Face, pigo.ImageParams, error) {
+	src, err := decodeSource(source)
 	if err != nil {
-		return nil, err
+		return nil, nil, pigo.ImageParams{}, err
 	}
+	dc, faces, imgParams := fd.annotate(analyzer, src)
 
-	pixels := pigo.RgbToGrayscale(src)
-	cols, rows := src.Bounds().Max.X, src.Bounds().Max.Y
+	return dc, faces, imgParams, nil
+}
 
-	dc = gg.NewContext(cols, rows)
-	dc.DrawImage(src, 0, 0)
+// annotate runs the detection algorithm over the decoded image and marks the detected faces on a copy of it.
+func (fd *faceDetector) annotate(analyzer *pigo.FaceAnalyzer, src *image.NRGBA) (*gg.Context, []pigo.Face, pigo.ImageParams) {
+	faces, imgParams := fd.analyze(analyzer, src)
+	dc := newCanvas(src)
+	render.Draw(dc, faces, fd.render)
 
-	imgParams = &pigo.ImageParams{
-		Pixels: pixels,
-		Rows:   rows,
-		Cols:   cols,
-		Dim:    cols,
-	}
+	return dc, faces, imgParams
+}
 
-	cParams := pigo.CascadeParams{
-		MinSize:     det.minSize,
-		MaxSize:     det.maxSize,
-		ShiftFactor: det.shiftFactor,
-		ScaleFactor: det.scaleFactor,
-		ImageParams: *imgParams,
-	}
+// analyze runs the detection algorithm over the decoded image. The faces failing the quality filter are discarded.
+func (fd *faceDetector) analyze(analyzer *pigo.FaceAnalyzer, src *image.NRGBA) ([]pigo.Face, pigo.ImageParams) {
+	imgParams := grayscaleParams(src)
+	return fd.filter.apply(analyzer.AnalyzeParams(imgParams), imgParams), imgParams
+}
 
-	cascadeFile, err := ioutil.ReadFile(det.cascadeFile)
-	if err != nil {
-		return nil, fmt.Errorf("error reading the facefinder cascade file")
-	}
+// defaultConfig returns the face detection settings used when neither a preset nor a configuration file is provided.
+func defaultConfig() config.Config {
+	c := config.Default()
+	c.ShiftFactor = 0.15
+	c.ScaleFactor = 1.15
+	c.IoUThreshold = 0.15
+	return c
+}
 
-	contentType, err := utils.DetectFileContentType(det.cascadeFile)
-	if err != nil {
-		return nil, err
-	}
-	if contentType != "application/octet-stream" {
-		return nil, fmt.Errorf("the provided cascade classifier is not valid")
+// detectorFlags registers the face detection flags shared by the subcommands on the flag set
+// and returns the function initializing the face detector with the parsed flag values.
+// The detection settings are taken from the preset, overridden by the configuration file,
+// which in turn is overridden by the detection flags set explicitly on the command line.
+func detectorFlags(fs *flag.FlagSet) func() *faceDetector {
+	def := defaultConfig()
+	var (
+		cascadeFile = fs.String("cf", "", "Cascade binary file (defaults to the embedded facefinder cascade)")
+		configFile  = fs.String("config", "", "Detection settings file (json)")
+		preset      = fs.String("preset", "", "Detection settings preset: "+strings.Join(config.PresetNames(), "|"))
+		puploc      = fs.String("plc", "", "Pupils/eyes localization cascade file (defaults to the embedded cascade, `none` disables it)")
+		flploc      = fs.String("flpc", "", "Facial landmark points cascade directory (defaults to the embedded cascades, `none` disables it)")
+	)
+	config.RegisterFlags(fs, def)
+
+	return func() *faceDetector {
+		var err error
+		cfg := def
+		if *preset != "" {
+			if cfg, err = config.Preset(*preset); err != nil {
+				usageError(fs, "%v", err)
+			}
+		}
+		if *configFile != "" {
+			if cfg, err = config.Load(*configFile, cfg); err != nil {
+				usageError(fs, "Invalid configuration file: %v", err)
+			}
+		}
+		config.ApplyFlags(&cfg, fs)
+		if err := cfg.Validate(); err != nil {
+			usageError(fs, "Invalid detection settings: %v", err)
+		}
+
+		return &faceDetector{
+			cascadeFile: *cascadeFile,
+			config:      cfg,
+			puploc:      *puploc,
+			flploc:      *flploc,
+		}
 	}
+}
 
-	p := pigo.NewPigo()
-	// Unpack the binary file. This will return the number of cascade trees,
-	// the tree depth, the threshold and the prediction from tree's leaf nodes.
-	classifier, err := p.Unpack(cascadeFile)
+// newAnalyzer unpacks the cascade files provided as command line flags
+// and initializes the face analyzer with the detection parameters.
+func (fd *faceDetector) newAnalyzer() (*pigo.FaceAnalyzer, error) {
+	classifier, plc, flpcs, err := fd.loadCascades()
 	if err != nil {
 		return nil, err
 	}
+	analyzer := pigo.NewFaceAnalyzer(classifier, plc, flpcs)
+	fd.config.Apply(analyzer)
 
-	plcReader := func() (*pigo.PuplocCascade, error) {
-		plc := pigo.NewPuplocCascade()
-		cascade, err := ioutil.ReadFile(det.puploc)
+	return analyzer, nil
+}
+
+// loadCascades unpacks the cascade files provided as command line flags. The embedded cascades are used for
+// the cascade files which are not provided. The pupil and the landmark points cascades are nil if they are disabled.
+func (fd *faceDetector) loadCascades() (*pigo.Pigo, *pigo.PuplocCascade, map[string][]*pigo.FlpCascade, error) {
+	var (
+		classifier *pigo.Pigo
+		plc        *pigo.PuplocCascade
+		flpcs      map[string][]*pigo.FlpCascade
+		err        error
+	)
+
+	if len(fd.cascadeFile) > 0 {
+		cascadeFile, err := ioutil.ReadFile(fd.cascadeFile)
 		if err != nil {
-			return nil, fmt.Errorf("error reading the puploc cascade file")
+			return nil, nil, nil, fmt.Errorf("error reading the facefinder cascade file")
 		}
-		plc, err = plc.UnpackCascade(cascade)
+
+		contentType, err := utils.DetectFileContentType(fd.cascadeFile)
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, err
+		}
+		if contentType != "application/octet-stream" {
+			return nil, nil, nil, fmt.Errorf("the provided cascade classifier is not valid")
 		}
-		return plc, nil
-	}
 
-	if len(det.puploc) > 0 {
-		plc, err = plcReader()
+		p := pigo.NewPigo()
+		// Unpack the binary file. This will return the number of cascade trees,
+		// the tree depth, the threshold and the prediction from tree's leaf nodes.
+		classifier, err = p.Unpack(cascadeFile)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	} else {
+		classifier, err = cascade.DefaultFaceFinder()
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
 	}
 
-	if len(det.flploc) > 0 {
-		plc, err = plcReader()
+	switch fd.puploc {
+	case noCascade:
+		if len(fd.flploc) > 0 && fd.flploc != noCascade {
+			return nil, nil, nil, fmt.Errorf("the puploc cascade file is required: use the -plc flag")
+		}
+	case "":
+		plc, err = cascade.DefaultPuploc()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	default:
+		plc = pigo.NewPuplocCascade()
+		data, err := ioutil.ReadFile(fd.puploc)
 		if err != nil {
-			return nil, fmt.Errorf("the puploc cascade file is required: use the -plc flag")
+			return nil, nil, nil, fmt.Errorf("error reading the puploc cascade file")
 		}
-		flpcs, err = plc.ReadCascadeDir(det.flploc)
+		plc, err = plc.UnpackCascade(data)
 		if err != nil {
-			return nil, fmt.Errorf("error reading the facial landmark points directory")
+			return nil, nil, nil, err
 		}
 	}
 
-	// Run the classifier over the obtained leaf nodes and return the detection results.
-	// The result contains quadruplets representing the row, column, scale and detection score.
-	faces := classifier.RunCascade(cParams, det.angle)
-
-	// Calculate the intersection over union (IoU) of two clusters.
-	faces = classifier.ClusterDetections(faces, det.iouThreshold)
+	if plc != nil {
+		switch fd.flploc {
+		case noCascade:
+		case "":
+			flpcs, err = cascade.DefaultLandmarks()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+		default:
+			flpcs, err = plc.ReadCascadeDir(fd.flploc)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("error reading the facial landmark points directory")
+			}
+		}
+	}
 
-	return faces, nil
+	return classifier, plc, flpcs, nil
 }
 
-// drawFaces marks the detected faces with the marker type defined as parameter (rectangle|circle|ellipse).
-func (fd *faceDetector) drawFaces(faces []pigo.Detection, marker string) ([]detection, error) {
-	var qThresh float32 = 5.0
-
-	var (
-		detections     = make([]detection, 0, len(faces))
-		eyesCoords     = make([]coord, 0, len(faces))
-		landmarkCoords = make([]coord, 0, len(faces))
-		puploc         *pigo.Puploc
-	)
+// newGaze converts the gaze estimation into its json representation.
+func newGaze(g *pigo.Gaze) *gaze {
+	if g == nil {
+		return nil
+	}
+	res := &gaze{
+		X:         g.X,
+		Y:         g.Y,
+		Direction: string(g.Direction),
+	}
+	if g.Left != nil {
+		res.Left = &eyeGaze{X: g.Left.X, Y: g.Left.Y}
+	}
+	if g.Right != nil {
+		res.Right = &eyeGaze{X: g.Right.X, Y: g.Right.Y}
+	}
+	return res
+}
 
-	for _, face := range faces {
-		if face.Q > qThresh {
-			switch marker {
-			case markerRectangle:
-				dc.DrawRectangle(float64(face.Col-face.Scale/2),
-					float64(face.Row-face.Scale/2),
-					float64(face.Scale),
-					float64(face.Scale),
-				)
-			case markerCircle:
-				dc.DrawArc(
-					float64(face.Col),
-					float64(face.Row),
-					float64(face.Scale/2),
-					0,
-					2*math.Pi,
-				)
-			case markerEllipse:
-				dc.DrawEllipse(
-					float64(face.Col),
-					float64(face.Row),
-					float64(face.Scale)/2,
-					float64(face.Scale)/1.6,
-				)
-			}
-			faceCoord := &coord{
-				Col:   face.Row - face.Scale/2,
-				Row:   face.Col - face.Scale/2,
-				Scale: face.Scale,
-			}
+// newHeadPose converts the head pose estimation into its json representation.
+func newHeadPose(hp *pigo.HeadPose) *headPose {
+	if hp == nil {
+		return nil
+	}
+	return &headPose{
+		Yaw:   hp.Yaw,
+		Pitch: hp.Pitch,
+		Roll:  hp.Roll,
+		Fit:   hp.Fit,
+	}
+}
 
-			dc.SetLineWidth(2.0)
-			dc.SetStrokeStyle(gg.NewSolidPattern(color.RGBA{R: 255, G: 0, B: 0, A: 255}))
-			dc.Stroke()
-
-			if len(det.puploc) > 0 && face.Scale > 50 {
-				rect := image.Rect(
-					face.Col-face.Scale/2,
-					face.Row-face.Scale/2,
-					face.Col+face.Scale/2,
-					face.Row+face.Scale/2,
-				)
-				rows, cols := rect.Max.X-rect.Min.X, rect.Max.Y-rect.Min.Y
-				ctx := gg.NewContext(rows, cols)
-				faceZone := ctx.Image()
-
-				// left eye
-				puploc = &pigo.Puploc{
-					Row:      face.Row - int(0.075*float32(face.Scale)),
-					Col:      face.Col - int(0.175*float32(face.Scale)),
-					Scale:    float32(face.Scale) * 0.25,
-					Perturbs: perturb,
-				}
-				leftEye := plc.RunDetector(*puploc, *imgParams, det.angle, false)
-				if leftEye.Row > 0 && leftEye.Col > 0 {
-					if det.angle > 0 {
-						drawEyeDetectionMarker(ctx,
-							float64(cols/2-(face.Col-leftEye.Col)),
-							float64(rows/2-(face.Row-leftEye.Row)),
-							float64(leftEye.Scale),
-							color.RGBA{R: 255, G: 0, B: 0, A: 255},
-							det.markDetEyes,
-						)
-						angle := (det.angle * 180) / math.Pi
-						rotated := imaging.Rotate(faceZone, 2*angle, color.Transparent)
-						final := imaging.FlipH(rotated)
-
-						dc.DrawImage(final, face.Col-face.Scale/2, face.Row-face.Scale/2)
-					} else {
-						drawEyeDetectionMarker(dc,
-							float64(leftEye.Col),
-							float64(leftEye.Row),
-							float64(leftEye.Scale),
-							color.RGBA{R: 255, G: 0, B: 0, A: 255},
-							det.markDetEyes,
-						)
-					}
-					eyesCoords = append(eyesCoords, coord{
-						Col:   leftEye.Row,
-						Row:   leftEye.Col,
-						Scale: int(leftEye.Scale),
-					})
-				}
-
-				// right eye
-				puploc = &pigo.Puploc{
-					Row:      face.Row - int(0.075*float32(face.Scale)),
-					Col:      face.Col + int(0.185*float32(face.Scale)),
-					Scale:    float32(face.Scale) * 0.25,
-					Perturbs: perturb,
-				}
-
-				rightEye := plc.RunDetector(*puploc, *imgParams, det.angle, false)
-				if rightEye.Row > 0 && rightEye.Col > 0 {
-					if det.angle > 0 {
-						drawEyeDetectionMarker(ctx,
-							float64(cols/2-(face.Col-rightEye.Col)),
-							float64(rows/2-(face.Row-rightEye.Row)),
-							float64(rightEye.Scale),
-							color.RGBA{R: 255, G: 0, B: 0, A: 255},
-							det.markDetEyes,
-						)
-						// convert radians to angle
-						angle := (det.angle * 180) / math.Pi
-						rotated := imaging.Rotate(faceZone, 2*angle, color.Transparent)
-						final := imaging.FlipH(rotated)
-
-						dc.DrawImage(final, face.Col-face.Scale/2, face.Row-face.Scale/2)
-					} else {
-						drawEyeDetectionMarker(dc,
-							float64(rightEye.Col),
-							float64(rightEye.Row),
-							float64(rightEye.Scale),
-							color.RGBA{R: 255, G: 0, B: 0, A: 255},
-							det.markDetEyes,
-						)
-					}
-					eyesCoords = append(eyesCoords, coord{
-						Col:   rightEye.Row,
-						Row:   rightEye.Col,
-						Scale: int(rightEye.Scale),
-					})
-				}
-
-				if len(det.flploc) > 0 {
-					for _, eye := range eyeCascades {
-						for _, flpc := range flpcs[eye] {
-							flp := flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, perturb, false)
-							if flp.Row > 0 && flp.Col > 0 {
-								drawEyeDetectionMarker(dc,
-									float64(flp.Col),
-									float64(flp.Row),
-									float64(flp.Scale*0.5),
-									color.RGBA{R: 0, G: 0, B: 255, A: 255},
-									false,
-								)
-								landmarkCoords = append(landmarkCoords, coord{
-									Col:   flp.Row,
-									Row:   flp.Col,
-									Scale: int(flp.Scale),
-								})
-							}
-
-							flp = flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, perturb, true)
-							if flp.Row > 0 && flp.Col > 0 {
-								drawEyeDetectionMarker(dc,
-									float64(flp.Col),
-									float64(flp.Row),
-									float64(flp.Scale*0.5),
-									color.RGBA{R: 0, G: 0, B: 255, A: 255},
-									false,
-								)
-								landmarkCoords = append(landmarkCoords, coord{
-									Col:   flp.Row,
-									Row:   flp.Col,
-									Scale: int(flp.Scale),
-								})
-							}
-						}
-					}
-
-					for _, mouth := range mouthCascades {
-						for _, flpc := range flpcs[mouth] {
-							flp := flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, perturb, false)
-							if flp.Row > 0 && flp.Col > 0 {
-								drawEyeDetectionMarker(dc,
-									float64(flp.Col),
-									float64(flp.Row),
-									float64(flp.Scale*0.5),
-									color.RGBA{R: 0, G: 0, B: 255, A: 255},
-									false,
-								)
-								landmarkCoords = append(landmarkCoords, coord{
-									Col:   flp.Row,
-									Row:   flp.Col,
-									Scale: int(flp.Scale),
-								})
-							}
-						}
-					}
-					flp := flpcs["lp84"][0].GetLandmarkPoint(leftEye, rightEye, *imgParams, perturb, true)
-					if flp.Row > 0 && flp.Col > 0 {
-						drawEyeDetectionMarker(dc,
-							float64(flp.Col),
-							float64(flp.Row),
-							float64(flp.Scale*0.5),
-							color.RGBA{R: 0, G: 0, B: 255, A: 255},
-							false,
-						)
-						landmarkCoords = append(landmarkCoords, coord{
-							Col:   flp.Row,
-							Row:   flp.Col,
-							Scale: int(flp.Scale),
-						})
-					}
-				}
-			}
-			detections = append(detections, detection{
-				FacePoints:     *faceCoord,
-				EyePoints:      eyesCoords,
-				LandmarkPoints: landmarkCoords,
-			})
-		}
+// newQuality converts the quality assessment, leaving out the unknown metrics.
+func newQuality(a quality.Assessment) *faceQuality {
+	q := &faceQuality{
+		Sharpness:  a.Sharpness,
+		Brightness: a.Brightness,
+		Contrast:   a.Contrast,
+		Size:       a.Size,
+		IOD:        a.IOD,
+		Score:      a.Score,
+		Pass:       a.Pass,
+		Reasons:    a.Reasons,
+	}
+	if !math.IsNaN(a.Frontalness) {
+		q.Frontalness = &a.Frontalness
+	}
+	if !math.IsNaN(a.Rotation) {
+		q.Rotation = &a.Rotation
 	}
-	return detections, nil
+	return q
 }
 
-func (fd *faceDetector) encodeImage(dst io.Writer) error {
-	var err error
-	img := dc.Image()
-
-	switch dst := dst.(type) {
-	case *os.File:
-		ext := filepath.Ext(strings.ToLower(dst.Name()))
-		switch ext {
-		case "", ".jpg", ".jpeg":
-			err = jpeg.Encode(dst, img, &jpeg.Options{Quality: 100})
-		case ".png":
-			err = png.Encode(dst, img)
-		default:
-			err = errors.New("unsupported image format")
-		}
-	default:
-		err = jpeg.Encode(dst, img, &jpeg.Options{Quality: 100})
+// newOpenness measures the openness of the eyes with a localized pupil.
+func newOpenness(face pigo.Face, imgParams pigo.ImageParams) *openness {
+	var res openness
+	if o, ok := blink.Openness(face, blink.LeftEye, imgParams); ok {
+		res.Left = &o
+	}
+	if o, ok := blink.Openness(face, blink.RightEye, imgParams); ok {
+		res.Right = &o
+	}
+	if res.Left == nil && res.Right == nil {
+		return nil
 	}
-	return err
+	return &res
 }
 
 // inSlice checks if the item exists in the slice.
@@ -603,17 +645,3 @@ func inSlice(item string, slice []string) bool {
 	}
 	return false
 }
-
-// drawEyeDetectionMarker is a helper function to draw the detection marks
-func drawEyeDetectionMarker(ctx *gg.Context, x, y, r float64, c color.RGBA, markDet bool) {
-	ctx.DrawArc(x, y, r*0.15, 0, 2*math.Pi)
-	ctx.SetFillStyle(gg.NewSolidPattern(c))
-	ctx.Fill()
-
-	if markDet {
-		ctx.DrawRectangle(x-(r*1.5), y-(r*1.5), r*3, r*3)
-		ctx.SetLineWidth(2.0)
-		ctx.SetStrokeStyle(gg.NewSolidPattern(color.RGBA{R: 255, G: 255, B: 0, A: 255}))
-		ctx.Stroke()
-	}
-}
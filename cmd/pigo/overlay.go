@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/esimov/pigo/overlay"
+)
+
+// overlayFaces runs the overlay subcommand, which composites an image asset over the detected faces.
+func overlayFaces(args []string) {
+	fs := newFlagSet("overlay", "pigo overlay -in input.jpg -out output.png -asset glasses.png [-anchors LeftPupil:130,140;RightPupil:372,140]",
+		"Composite an image asset over the detected faces, mapping the asset anchor points onto the face points.")
+	var (
+		source      = fs.String("in", "", "Source image")
+		destination = fs.String("out", "", "Destination image (jpg, png, bmp or tiff)")
+		assetPath   = fs.String("asset", "", "Overlay image asset, with its anchor points defined in a json file of the same name")
+		anchors     = fs.String("anchors", "", "Asset anchor points in the Point:x,y;Point:x,y format, overriding the json file")
+		newDetector = detectorFlags(fs)
+	)
+	fs.Parse(args)
+
+	if len(*source) == 0 || len(*destination) == 0 || len(*assetPath) == 0 {
+		usageError(fs, "The source, the destination and the asset are required")
+	}
+	ext := strings.ToLower(filepath.Ext(*destination))
+	if !inSlice(ext, imageExts) {
+		usageError(fs, "Output file type not supported: %s", ext)
+	}
+
+	var asset *overlay.Asset
+	if len(*anchors) > 0 {
+		img, err := decodeSource(*assetPath)
+		if err != nil {
+			log.Fatalf("%sError decoding the asset image: %v%s", errorColor, err, defaultColor)
+		}
+		points, err := overlay.ParseAnchors(*anchors)
+		if err != nil {
+			usageError(fs, "%v", err)
+		}
+		asset = &overlay.Asset{Image: img, Anchors: points}
+	} else {
+		var err error
+		if asset, err = overlay.LoadAsset(*assetPath); err != nil {
+			log.Fatalf("%sError loading the asset: %v%s", errorColor, err, defaultColor)
+		}
+	}
+
+	src, err := decodeSource(*source)
+	if err != nil {
+		log.Fatalf("%sError decoding the source image: %v%s", errorColor, err, defaultColor)
+	}
+	analyzer, err := newDetector().newAnalyzer()
+	if err != nil {
+		log.Fatalf("%sDetection error: %v%s", errorColor, err, defaultColor)
+	}
+	faces := analyzer.Analyze(src)
+
+	dst, err := overlay.Apply(src, faces, *asset)
+	if err != nil {
+		log.Fatalf("%s%v%s", errorColor, err, defaultColor)
+	}
+
+	f, err := os.Create(*destination)
+	if err != nil {
+		log.Fatalf("%sUnable to create the output file: %v%s", errorColor, err, defaultColor)
+	}
+	err = encodeFormat(f, dst, ext)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Fatalf("%sError encoding the output image: %v%s", errorColor, err, defaultColor)
+	}
+	log.Printf("%s%d%s face(s) overlaid", successColor, len(faces), defaultColor)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/quality"
+)
+
+// qualityFilter holds the face quality thresholds and whether the faces failing them should be discarded.
+type qualityFilter struct {
+	params  quality.Params
+	enabled bool
+}
+
+// qualityFlags registers the face quality flags on the flag set. The returned function
+// should be called once the flags are parsed, for obtaining the configured filter.
+func qualityFlags(fs *flag.FlagSet) func() *qualityFilter {
+	def := quality.DefaultParams()
+	var (
+		enabled        = fs.Bool("quality", false, "Discard the faces failing the quality checks")
+		minSharpness   = fs.Float64("min-sharpness", def.MinSharpness, "Minimum face sharpness (variance of the Laplacian)")
+		minBrightness  = fs.Float64("min-brightness", def.MinBrightness, "Minimum mean face brightness (0-1)")
+		maxBrightness  = fs.Float64("max-brightness", def.MaxBrightness, "Maximum mean face brightness (0-1)")
+		minContrast    = fs.Float64("min-contrast", def.MinContrast, "Minimum face contrast (0-1)")
+		minFaceSize    = fs.Int("min-face-size", def.MinSize, "Minimum face size in pixels")
+		minIOD         = fs.Float64("min-iod", def.MinIOD, "Minimum interocular distance in pixels")
+		minFrontalness = fs.Float64("min-frontalness", def.MinFrontalness, "Minimum face frontalness (0-1)")
+		maxRotation    = fs.Float64("max-rotation", def.MaxRotation, "Maximum in-plane face rotation in degrees")
+		minScore       = fs.Float64("min-score", def.MinScore, "Minimum overall face quality score (0-1)")
+	)
+	return func() *qualityFilter {
+		p := quality.DefaultParams()
+		p.MinSharpness = *minSharpness
+		p.MinBrightness = *minBrightness
+		p.MaxBrightness = *maxBrightness
+		p.MinContrast = *minContrast
+		p.MinSize = *minFaceSize
+		p.MinIOD = *minIOD
+		p.MinFrontalness = *minFrontalness
+		p.MaxRotation = *maxRotation
+		p.MinScore = *minScore
+
+		return &qualityFilter{params: p, enabled: *enabled}
+	}
+}
+
+// apply returns the faces passing the quality checks, if the filter is enabled.
+func (qf *qualityFilter) apply(faces []pigo.Face, img pigo.ImageParams) []pigo.Face {
+	if !qf.enabled {
+		return faces
+	}
+	passed := faces[:0:0]
+	for _, face := range faces {
+		if quality.Assess(face, img, qf.params).Pass {
+			passed = append(passed, face)
+		}
+	}
+	return passed
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/redact"
+	"github.com/esimov/pigo/render"
+)
+
+// redactExts are the file types processed by the redact subcommand.
+var redactExts = append([]string{".gif"}, imageExts...)
+
+// redactJob is a source file to be redacted into the destination file.
+type redactJob struct {
+	src, dst string
+}
+
+// redactFaces runs the redact subcommand, which anonymizes the detected faces of an image,
+// of an animated GIF or of all the images found in a directory.
+func redactFaces(args []string) {
+	fs := newFlagSet("redact", "pigo redact -in input.jpg|input.gif|dir/ -out output.jpg|output.gif|dir/",
+		"Anonymize the detected faces of an image, of an animated GIF or of all the images found in a directory.")
+	var (
+		source      = fs.String("in", "", "Source image, animated GIF or directory")
+		destination = fs.String("out", "", "Destination image or directory")
+		method      = fs.String("method", string(redact.Blur), "Redaction method: blur|pixelate|fill")
+		shape       = fs.String("shape", string(redact.Ellipse), "Redacted region shape: box|ellipse")
+		padding     = fs.Float64("padding", 0.1, "Padding added around the faces, as a fraction of the face size")
+		feather     = fs.Float64("feather", 0.05, "Width of the soft region edges, as a fraction of the face size")
+		strength    = fs.Float64("strength", 0.1, "Blur sigma or pixelation block size, as a fraction of the face size")
+		fillColor   = fs.String("color", "#000000", "Fill color used by the fill method (#rrggbb or #rrggbbaa)")
+		failClosed  = fs.Bool("fail-closed", false, "Produce no output at all if any of the files fails to be processed")
+		requireFace = fs.Bool("require-faces", false, "Treat the images and the GIF frames without any detected face as failures")
+		newDetector = detectorFlags(fs)
+	)
+	fs.Parse(args)
+
+	if len(*source) == 0 || len(*destination) == 0 {
+		usageError(fs, "The source and the destination are required")
+	}
+
+	col, err := render.ParseColor(*fillColor)
+	if err != nil {
+		usageError(fs, "Invalid fill color: %v", err)
+	}
+	params := redact.Params{
+		Method:   redact.Method(*method),
+		Shape:    redact.Shape(*shape),
+		Padding:  *padding,
+		Feather:  *feather,
+		Strength: *strength,
+		Color:    col,
+	}
+	if err := params.Validate(); err != nil {
+		usageError(fs, "Invalid redaction settings: %v", err)
+	}
+
+	jobs, err := redactJobs(*source, *destination)
+	if err != nil {
+		log.Fatalf("%s%v%s", errorColor, err, defaultColor)
+	}
+	analyzer, err := newDetector().newAnalyzer()
+	if err != nil {
+		log.Fatalf("%sDetection error: %v%s", errorColor, err, defaultColor)
+	}
+
+	opts := redactOptions{params: params, failClosed: *failClosed, requireFaces: *requireFace}
+	failed, err := redactAll(analyzer, jobs, opts)
+	if err != nil {
+		log.Fatalf("%sUnable to write the redacted files: %v%s", errorColor, err, defaultColor)
+	}
+	if failed > 0 {
+		if *failClosed {
+			log.Fatalf("%sRedaction failed, no output was produced%s", errorColor, defaultColor)
+		}
+		log.Fatalf("%s%d of %d file(s) failed to be redacted%s", errorColor, failed, len(jobs), defaultColor)
+	}
+	log.Printf("%s%d%s file(s) redacted", successColor, len(jobs), defaultColor)
+}
+
+// redactOptions holds the settings of the redact subcommand.
+// failClosed: write no file at all if any of the files fails to be redacted.
+// requireFaces: fail the images and the GIF frames on which no face is detected.
+type redactOptions struct {
+	params       redact.Params
+	failClosed   bool
+	requireFaces bool
+}
+
+// errNoFaces is returned in the require faces mode if no face is detected on an image or on a GIF frame.
+var errNoFaces = errors.New("no face detected")
+
+// redactAll redacts the files of the jobs and returns the number of failed files, which are reported as they fail.
+// The redacted files are written into temporary files first, which are renamed once they are complete.
+// In fail closed mode the renaming waits for all the files to succeed, and the redaction stops at the first failure.
+// The returned error reports the failure of the final renaming in fail closed mode.
+func redactAll(analyzer *pigo.FaceAnalyzer, jobs []redactJob, opts redactOptions) (int, error) {
+	var (
+		pending []redactJob
+		failed  int
+	)
+	for _, job := range jobs {
+		tmp, err := redactFile(analyzer, opts, job)
+		if err == nil {
+			pending = append(pending, redactJob{src: tmp, dst: job.dst})
+			if opts.failClosed {
+				continue
+			}
+			err = commitFiles(pending)
+			pending = nil
+			if err == nil {
+				continue
+			}
+		}
+		failed++
+		log.Printf("%s%s: %v%s", errorColor, job.src, err, defaultColor)
+		if opts.failClosed {
+			break
+		}
+	}
+
+	if failed > 0 && opts.failClosed {
+		for _, p := range pending {
+			os.Remove(p.src)
+		}
+		return failed, nil
+	}
+	return failed, commitFiles(pending)
+}
+
+// redactJobs lists the files to be redacted. If the source is a directory, all the supported
+// files found in it are redacted into the destination directory, using the same file names.
+func redactJobs(source, destination string) ([]redactJob, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		if fi, err := os.Stat(destination); err == nil && fi.IsDir() {
+			destination = filepath.Join(destination, filepath.Base(source))
+		}
+		if !inSlice(strings.ToLower(filepath.Ext(destination)), redactExts) {
+			return nil, fmt.Errorf("output file type not supported: %s", filepath.Ext(destination))
+		}
+		return []redactJob{{src: source, dst: destination}}, nil
+	}
+
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(destination, 0755); err != nil {
+		return nil, err
+	}
+	var jobs []redactJob
+	for _, entry := range entries {
+		if entry.IsDir() || !inSlice(strings.ToLower(filepath.Ext(entry.Name())), redactExts) {
+			continue
+		}
+		jobs = append(jobs, redactJob{
+			src: filepath.Join(source, entry.Name()),
+			dst: filepath.Join(destination, entry.Name()),
+		})
+	}
+	return jobs, nil
+}
+
+// redactFile redacts the source file of the job into a temporary file created
+// next to the destination file and returns the name of the temporary file.
+func redactFile(analyzer *pigo.FaceAnalyzer, opts redactOptions, job redactJob) (string, error) {
+	in, err := os.Open(job.src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(job.dst), ".pigo-redact-*")
+	if err != nil {
+		return "", err
+	}
+	// The temporary files are created readable only by their owner, while the
+	// redacted files get the usual permissions of the written images.
+	if err := out.Chmod(0644); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return "", err
+	}
+	if err := redactImage(analyzer, opts, in, out, job); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+// redactImage decodes the source image, redacts the detected faces and encodes the result
+// in the format given by the destination file extension. Animated GIFs are redacted frame by frame.
+func redactImage(analyzer *pigo.FaceAnalyzer, opts redactOptions, r io.Reader, w io.Writer, job redactJob) error {
+	params := opts.params
+	detect := redactDetector(analyzer, opts.requireFaces)
+	srcExt := strings.ToLower(filepath.Ext(job.src))
+	dstExt := strings.ToLower(filepath.Ext(job.dst))
+
+	if srcExt == ".gif" && dstExt == ".gif" {
+		g, err := gif.DecodeAll(r)
+		if err != nil {
+			return err
+		}
+		redacted, err := redact.GIF(g, detect, params)
+		if err != nil {
+			return err
+		}
+		return gif.EncodeAll(w, redacted)
+	}
+
+	src, err := pigo.DecodeImage(r)
+	if err != nil {
+		return err
+	}
+	dets, err := detect(src)
+	if err != nil {
+		return err
+	}
+	redacted, err := redact.Apply(src, dets, params)
+	if err != nil {
+		return err
+	}
+	if dstExt == ".gif" {
+		return gif.Encode(w, redacted, nil)
+	}
+	return encodeFormat(w, redacted, dstExt)
+}
+
+// redactDetector returns the face detection function of the redaction, which reports the images
+// the faces can't be searched on as errors, rather than returning no faces for them.
+// In the require faces mode the images without any detected face are reported as errors too.
+func redactDetector(analyzer *pigo.FaceAnalyzer, requireFaces bool) func(*image.NRGBA) ([]pigo.Detection, error) {
+	return func(img *image.NRGBA) ([]pigo.Detection, error) {
+		if b := img.Bounds(); b.Dx() < analyzer.MinSize || b.Dy() < analyzer.MinSize {
+			return nil, fmt.Errorf("face detection failed: the %dx%d image is smaller than the minimum face size (%dpx)",
+				b.Dx(), b.Dy(), analyzer.MinSize)
+		}
+		dets := analyzer.DetectFaces(grayscaleParams(img))
+		if requireFaces && len(dets) == 0 {
+			return nil, errNoFaces
+		}
+		return dets, nil
+	}
+}
+
+// commitFiles renames the temporary files to their destination. The temporary files
+// are removed if any of them fails to be renamed and the first error is returned.
+func commitFiles(jobs []redactJob) error {
+	for i, job := range jobs {
+		if err := os.Rename(job.src, job.dst); err != nil {
+			for _, rest := range jobs[i:] {
+				os.Remove(rest.src)
+			}
+			return err
+		}
+	}
+	return nil
+}
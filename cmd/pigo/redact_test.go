@@ -0,0 +1,113 @@
+package main
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/esimov/pigo/redact"
+)
+
+// redactDir creates the source directory of the redaction holding the sample face image
+// and the additional blank image, and returns the source and the destination directories.
+func redactDir(t *testing.T, blankSize int) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("../../testdata/sample.jpg")
+	if err != nil {
+		t.Fatalf("failed reading the sample image: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "a_face.jpg"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(src, "b_blank.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewGray(image.Rect(0, 0, blankSize, blankSize))); err != nil {
+		t.Fatal(err)
+	}
+	return src, dst
+}
+
+func redactedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestRedact_ShouldFailClosed(t *testing.T) {
+	analyzer, err := (&faceDetector{config: defaultConfig(), puploc: noCascade, flploc: noCascade}).newAnalyzer()
+	if err != nil {
+		t.Fatalf("failed loading the cascades: %v", err)
+	}
+
+	cases := map[string]struct {
+		blankSize int
+		opts      redactOptions
+		failed    int
+		expected  []string
+	}{
+		"no face found": {
+			blankSize: 100,
+			opts:      redactOptions{failClosed: true, requireFaces: true},
+			failed:    1,
+		},
+		"detection failure": {
+			blankSize: 8,
+			opts:      redactOptions{failClosed: true},
+			failed:    1,
+		},
+		"fail open": {
+			blankSize: 100,
+			opts:      redactOptions{requireFaces: true},
+			failed:    1,
+			expected:  []string{"a_face.jpg"},
+		},
+		"faces not required": {
+			blankSize: 100,
+			opts:      redactOptions{failClosed: true},
+			expected:  []string{"a_face.jpg", "b_blank.png"},
+		},
+	}
+	for name, c := range cases {
+		src, dst := redactDir(t, c.blankSize)
+		jobs, err := redactJobs(src, dst)
+		if err != nil {
+			t.Fatalf("%s: failed listing the jobs: %v", name, err)
+		}
+		c.opts.params = redact.DefaultParams()
+
+		failed, err := redactAll(analyzer, jobs, c.opts)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if failed != c.failed {
+			t.Errorf("%s: expected %d failed file(s), got %d", name, c.failed, failed)
+		}
+
+		files := redactedFiles(t, dst)
+		if len(files) != len(c.expected) {
+			t.Fatalf("%s: expected the %v files to be written, got %v", name, c.expected, files)
+		}
+		for i, f := range files {
+			if f != c.expected[i] {
+				t.Errorf("%s: expected the %v files to be written, got %v", name, c.expected, files)
+			}
+		}
+	}
+}
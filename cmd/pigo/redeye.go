@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/esimov/pigo/redeye"
+)
+
+// redEyeReport holds the red-eye detection and correction report of an eye
+type redEyeReport struct {
+	Face      int     `json:"face"`
+	Eye       string  `json:"eye"`
+	X         int     `json:"x"`
+	Y         int     `json:"y"`
+	Radius    float64 `json:"radius"`
+	Coverage  float64 `json:"coverage"`
+	Corrected bool    `json:"corrected"`
+	Pixels    int     `json:"pixels"`
+}
+
+// correctRedEyes runs the redeye subcommand, which removes the red-eye effect from the localized pupils.
+func correctRedEyes(args []string) {
+	fs := newFlagSet("redeye", "pigo redeye -in input.jpg -out output.jpg [-json report.json]",
+		"Remove the red-eye effect from the localized pupils of the detected faces.")
+	var (
+		source       = fs.String("in", "", "Source image")
+		destination  = fs.String("out", "", "Destination image (jpg, png, bmp or tiff)")
+		jsonf        = fs.String("json", "", "Output the per eye report into a json file")
+		searchRadius = fs.Float64("search", 0.5, "Radius of the inspected pupil neighbourhood, as a fraction of the pupil scale")
+		redness      = fs.Float64("redness", 0.4, "Minimum redness of the red-eye pixels (0-1)")
+		coverage     = fs.Float64("coverage", 0.05, "Minimum fraction of red pixels around the pupil for the eye to be corrected")
+		darken       = fs.Float64("darken", 0.8, "Brightness of the corrected pixels (0-1)")
+		newDetector  = detectorFlags(fs)
+	)
+	fs.Parse(args)
+
+	if len(*source) == 0 || len(*destination) == 0 {
+		usageError(fs, "The source and the destination are required")
+	}
+	ext := strings.ToLower(filepath.Ext(*destination))
+	if !inSlice(ext, imageExts) {
+		usageError(fs, "Output file type not supported: %s", ext)
+	}
+
+	params := redeye.DefaultParams()
+	params.SearchRadius = *searchRadius
+	params.Redness = *redness
+	params.MinCoverage = *coverage
+	params.Darken = *darken
+	if err := params.Validate(); err != nil {
+		usageError(fs, "Invalid red-eye settings: %v", err)
+	}
+
+	src, err := decodeSource(*source)
+	if err != nil {
+		log.Fatalf("%sError decoding the source image: %v%s", errorColor, err, defaultColor)
+	}
+	fd := newDetector()
+	if fd.puploc == noCascade {
+		usageError(fs, "The red-eye correction requires the pupil localization")
+	}
+	analyzer, err := fd.newAnalyzer()
+	if err != nil {
+		log.Fatalf("%sDetection error: %v%s", errorColor, err, defaultColor)
+	}
+
+	dst, reports, err := redeye.Correct(src, analyzer.Analyze(src), params)
+	if err != nil {
+		log.Fatalf("%sRed-eye correction error: %v%s", errorColor, err, defaultColor)
+	}
+
+	f, err := os.Create(*destination)
+	if err != nil {
+		log.Fatalf("%sUnable to create the output file: %v%s", errorColor, err, defaultColor)
+	}
+	err = encodeFormat(f, dst, ext)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Fatalf("%sError encoding the output image: %v%s", errorColor, err, defaultColor)
+	}
+
+	out := make([]redEyeReport, 0, len(reports))
+	corrected := 0
+	for _, r := range reports {
+		out = append(out, redEyeReport{
+			Face:      r.Face,
+			Eye:       string(r.Eye),
+			X:         r.Center.X,
+			Y:         r.Center.Y,
+			Radius:    r.Radius,
+			Coverage:  r.Coverage,
+			Corrected: r.Corrected,
+			Pixels:    r.Pixels,
+		})
+		if r.Corrected {
+			corrected++
+		}
+	}
+	if *jsonf != "" {
+		var w io.Writer = os.Stdout
+		if *jsonf != pipeName {
+			jf, err := os.Create(*jsonf)
+			if err != nil {
+				log.Fatalf("%sCould not create the json file: %v%s", errorColor, err, defaultColor)
+			}
+			defer jf.Close()
+			w = jf
+		}
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			log.Fatalf("%sError encoding the json file: %v%s", errorColor, err, defaultColor)
+		}
+	}
+	log.Printf("%s%d%s of %d eye(s) corrected", successColor, corrected, defaultColor, len(reports))
+}
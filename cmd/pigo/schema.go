@@ -0,0 +1,176 @@
+package main
+
+import (
+	"github.com/esimov/pigo/blink"
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/quality"
+)
+
+// The versions of the json results schema.
+const (
+	// schemaV1 - the original schema: a list of detections for a single image.
+	schemaV1 = "v1"
+	// schemaV2 - the versioned schema: a document holding the image metadata and the faces for each image.
+	schemaV2 = "v2"
+)
+
+// jsonSchemas are the supported values of the -json-schema flag.
+var jsonSchemas = []string{schemaV1, schemaV2}
+
+// schemaVersion is the value of the schema_version field of the v2 documents.
+const schemaVersion = 2
+
+// result is the v2 json document describing the faces detected on an image.
+// Error: the reason the image failed to be processed, in which case faces is null.
+type result struct {
+	SchemaVersion int          `json:"schema_version"`
+	Image         imageInfo    `json:"image"`
+	Faces         []faceResult `json:"faces"`
+	Error         string       `json:"error,omitempty"`
+}
+
+// imageInfo holds the source image metadata. The size is 0 if the image could not be decoded.
+type imageInfo struct {
+	File   string `json:"file"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// faceResult describes a detected face in the v2 json schema.
+// X, Y, Width, Height: the bounding box of the face in pixels, with X, Y at its top-left corner.
+// The box is not clipped, so it might extend past the image bounds.
+// Score: the detection score. Angle: the rotation of the detection window in degrees.
+// Eyes: the localized pupils, null if they were not detected. Landmarks: the named facial landmark points.
+// Track: the identifier of the face followed across the frames of an animation, omitted if tracking is disabled.
+type faceResult struct {
+	X         int          `json:"x"`
+	Y         int          `json:"y"`
+	Width     int          `json:"width"`
+	Height    int          `json:"height"`
+	Score     float32      `json:"score"`
+	Angle     float64      `json:"angle"`
+	Eyes      eyePoints    `json:"eyes"`
+	Landmarks []landmark   `json:"landmarks"`
+	Gaze      *gaze        `json:"gaze"`
+	Openness  *openness    `json:"openness"`
+	Pose      *headPose    `json:"pose"`
+	Quality   *faceQuality `json:"quality"`
+	Track     int          `json:"track,omitempty"`
+	Blinks    []blinkEvent `json:"blinks,omitempty"`
+}
+
+// blinkEvent is a blink of a tracked face, which ended on the frame of an animation.
+// Start is the display time of the frame the eye closed on and Duration the time the eye stayed closed,
+// both in milliseconds.
+type blinkEvent struct {
+	Eye      blink.Eye `json:"eye"`
+	Start    int64     `json:"start"`
+	Duration int64     `json:"duration"`
+}
+
+// eyePoints holds the pupils of the face, from the viewer's perspective.
+type eyePoints struct {
+	Left  *point `json:"left"`
+	Right *point `json:"right"`
+}
+
+// point is a localized point: X is the column and Y the row of the point in pixels.
+// Size: the size of the localization window. Confidence: the agreement of the perturbed localization runs, between 0 and 1.
+type point struct {
+	X          int     `json:"x"`
+	Y          int     `json:"y"`
+	Size       float32 `json:"size"`
+	Confidence float64 `json:"confidence"`
+}
+
+// landmark is a named facial landmark point.
+type landmark struct {
+	Name string `json:"name"`
+	point
+}
+
+// newDetections converts the faces into the v1 json schema.
+// The eye openness and the face quality are measured over the grayscale image the faces were detected on.
+func (fd *faceDetector) newDetections(faces []pigo.Face, imgParams pigo.ImageParams) []detection {
+	detections := make([]detection, 0, len(faces))
+	for _, face := range faces {
+		det := detection{
+			FacePoints: coord{
+				Col:   face.Box.Min.Y,
+				Row:   face.Box.Min.X,
+				Scale: face.Detection.Scale,
+			},
+			Gaze:     newGaze(pigo.EstimateGaze(face, pigo.DefaultGazeParams())),
+			Openness: newOpenness(face, imgParams),
+			Pose:     newHeadPose(face.Pose),
+			Quality:  newQuality(quality.Assess(face, imgParams, fd.filter.params)),
+		}
+		for _, eye := range []*pigo.Puploc{face.LeftEye, face.RightEye} {
+			if eye != nil {
+				det.EyePoints = append(det.EyePoints, coord{Col: eye.Row, Row: eye.Col, Scale: int(eye.Scale)})
+			}
+		}
+		for _, flp := range face.Landmarks {
+			det.LandmarkPoints = append(det.LandmarkPoints, coord{Col: flp.Row, Row: flp.Col, Scale: int(flp.Scale)})
+		}
+		detections = append(detections, det)
+	}
+	return detections
+}
+
+// newResult converts the faces detected on the image into the v2 json schema.
+func (fd *faceDetector) newResult(file string, faces []pigo.Face, imgParams pigo.ImageParams) result {
+	res := result{
+		SchemaVersion: schemaVersion,
+		Image:         imageInfo{File: file, Width: imgParams.Cols, Height: imgParams.Rows},
+		Faces:         make([]faceResult, 0, len(faces)),
+	}
+	for _, face := range faces {
+		fr := faceResult{
+			X:         face.Box.Min.X,
+			Y:         face.Box.Min.Y,
+			Width:     face.Box.Dx(),
+			Height:    face.Box.Dy(),
+			Score:     face.Score,
+			Angle:     face.Angle * 360,
+			Eyes:      eyePoints{Left: newPoint(face.LeftEye), Right: newPoint(face.RightEye)},
+			Landmarks: make([]landmark, 0, len(face.Landmarks)),
+			Gaze:      newGaze(pigo.EstimateGaze(face, pigo.DefaultGazeParams())),
+			Openness:  newOpenness(face, imgParams),
+			Pose:      newHeadPose(face.Pose),
+			Quality:   newQuality(quality.Assess(face, imgParams, fd.filter.params)),
+		}
+		for _, lp := range face.Landmarks {
+			fr.Landmarks = append(fr.Landmarks, landmark{Name: lp.Name, point: *newPoint(&lp.Puploc)})
+		}
+		res.Faces = append(res.Faces, fr)
+	}
+	return res
+}
+
+// newRecord returns the json record of an image processed in batch mode, using the configured schema.
+func (fd *faceDetector) newRecord(file string, faces []pigo.Face, imgParams pigo.ImageParams, err error) any {
+	if fd.schema == schemaV2 {
+		if err != nil {
+			return result{SchemaVersion: schemaVersion, Image: imageInfo{File: file}, Error: err.Error()}
+		}
+		return fd.newResult(file, faces, imgParams)
+	}
+	if err != nil {
+		return batchResult{File: file, Error: err.Error()}
+	}
+	return batchResult{File: file, Faces: fd.newDetections(faces, imgParams)}
+}
+
+// newPoint converts the localized point into the v2 json schema.
+func newPoint(p *pigo.Puploc) *point {
+	if p == nil {
+		return nil
+	}
+	return &point{
+		X:          p.Col,
+		Y:          p.Row,
+		Size:       p.Scale,
+		Confidence: p.Confidence(),
+	}
+}
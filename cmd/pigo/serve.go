@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// detectionServer serves the face detection results of the uploaded images.
+// The face analyzer is shared by the requests, since it's safe for concurrent use.
+type detectionServer struct {
+	fd        *faceDetector
+	analyzer  *pigo.FaceAnalyzer
+	maxUpload int64
+	maxPixels int64
+}
+
+// serveDetector runs the serve subcommand, which exposes the face detection as an HTTP service.
+func serveDetector(args []string) {
+	fs := newFlagSet("serve", "pigo serve [-addr :8080] [-json-schema v2]",
+		"Run the face detection as an HTTP service. The images are posted to the /detect endpoint, either as the\n"+
+			"request body or as the \"image\" field of a multipart form, and the detection results are returned as json.\n"+
+			"The /healthz endpoint reports whether the service is up.")
+	var (
+		addr         = fs.String("addr", ":8080", "Address the service listens on")
+		maxUpload    = fs.Int64("max-upload", 10, "Maximum size of the uploaded images in MB")
+		maxPixels    = fs.Int64("max-pixels", 50, "Maximum resolution of the uploaded images in megapixels")
+		readTimeout  = fs.Duration("read-timeout", 30*time.Second, "Maximum duration of reading a request")
+		writeTimeout = fs.Duration("write-timeout", time.Minute, "Maximum duration of processing a request and writing its response")
+		schema       = fs.String("json-schema", schemaV2, "Schema of the json results: v1|v2")
+		newDetector  = detectorFlags(fs)
+		newFilter    = qualityFlags(fs)
+	)
+	fs.Parse(args)
+
+	if !inSlice(*schema, jsonSchemas) {
+		usageError(fs, "Unsupported json schema: %s", *schema)
+	}
+	if *maxUpload <= 0 {
+		usageError(fs, "The maximum upload size should be positive")
+	}
+	if *maxPixels <= 0 {
+		usageError(fs, "The maximum image resolution should be positive")
+	}
+	if *readTimeout <= 0 || *writeTimeout <= 0 {
+		usageError(fs, "The timeouts should be positive")
+	}
+
+	fd := newDetector()
+	fd.schema = *schema
+	fd.filter = newFilter()
+	analyzer, err := fd.newAnalyzer()
+	if err != nil {
+		log.Fatalf("%sDetection error: %v%s", errorColor, err, defaultColor)
+	}
+
+	srv := &detectionServer{fd: fd, analyzer: analyzer, maxUpload: *maxUpload << 20, maxPixels: *maxPixels * 1e6}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/detect", srv.detect)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       *readTimeout,
+		WriteTimeout:      *writeTimeout,
+	}
+
+	// The service is shut down gracefully on interrupt, letting the running requests complete.
+	done := make(chan struct{})
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("%sShutdown error: %v%s", errorColor, err, defaultColor)
+		}
+		close(done)
+	}()
+
+	log.Printf("Listening on %s%s%s", successColor, *addr, defaultColor)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("%sServer error: %v%s", errorColor, err, defaultColor)
+	}
+	<-done
+}
+
+// detect handles the detection requests.
+func (s *detectionServer) detect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "the images should be posted")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUpload)
+
+	var (
+		body io.Reader = r.Body
+		name string
+	)
+	// The raw request bodies are decoded as they are, whatever their content type.
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+		file, header, err := r.FormFile("image")
+		if err != nil {
+			writeError(w, requestErrorCode(err), err.Error())
+			return
+		}
+		defer file.Close()
+		body, name = file, header.Filename
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		writeError(w, requestErrorCode(err), err.Error())
+		return
+	}
+	// The image size is checked before decoding the image, since a small file can hold a huge image.
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "error decoding the image: "+err.Error())
+		return
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > s.maxPixels {
+		writeError(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("the %dx%d image exceeds the maximum resolution of %d pixels", cfg.Width, cfg.Height, s.maxPixels))
+		return
+	}
+
+	src, err := pigo.DecodeImage(bytes.NewReader(data))
+	if err != nil {
+		writeError(w, requestErrorCode(err), "error decoding the image: "+err.Error())
+		return
+	}
+	imgParams := grayscaleParams(src)
+	faces := s.fd.filter.apply(s.analyzer.AnalyzeParams(imgParams), imgParams)
+
+	var res any = s.fd.newDetections(faces, imgParams)
+	if s.fd.schema == schemaV2 {
+		res = s.fd.newResult(name, faces, imgParams)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Printf("%sError encoding the response: %v%s", errorColor, err, defaultColor)
+	}
+}
+
+// requestErrorCode returns the status code reported for the errors of reading the request.
+func requestErrorCode(err error) int {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// writeError writes the error message as a json response with the provided status code.
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{msg})
+}
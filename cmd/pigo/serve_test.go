@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServe_ShouldRejectLargeImages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 200, 100))); err != nil {
+		t.Fatal(err)
+	}
+	// The images are rejected before running the detection, so the server doesn't need the cascades.
+	srv := &detectionServer{maxUpload: 1 << 20, maxPixels: 10000}
+
+	cases := map[string]struct {
+		body []byte
+		code int
+	}{
+		"too many pixels": {buf.Bytes(), http.StatusRequestEntityTooLarge},
+		"not an image":    {[]byte("not an image"), http.StatusBadRequest},
+		"too large file":  {make([]byte, 2<<20), http.StatusRequestEntityTooLarge},
+	}
+	for name, c := range cases {
+		rec := httptest.NewRecorder()
+		srv.detect(rec, httptest.NewRequest(http.MethodPost, "/detect", bytes.NewReader(c.body)))
+		if rec.Code != c.code {
+			t.Errorf("%s: expected the %d status code, got %d: %s", name, c.code, rec.Code, rec.Body.String())
+		}
+	}
+}
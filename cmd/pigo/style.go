@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/esimov/pigo/render"
+)
+
+// renderFlags registers the annotation rendering flags on the flag set and returns
+// the function building the rendering options from the parsed flag values.
+func renderFlags(fs *flag.FlagSet) func() render.Params {
+	def := render.DefaultParams()
+	var defLayers []string
+	for _, l := range def.Layers {
+		defLayers = append(defLayers, string(l))
+	}
+	var (
+		marker   = fs.String("marker", string(def.Marker), "Detection marker: rect|circle|ellipse")
+		markEyes = fs.Bool("mark", def.EyeBoxes, "Mark detected eyes")
+		drawAxes = fs.Bool("axes", false, "Draw the estimated head pose axes")
+		layers   = fs.String("layers", strings.Join(defLayers, ","), "Annotation layers: faces,labels,pupils,landmarks,contours,pose")
+		styles   = fs.String("style", "", "Annotation styles in the element:color,width,fill;... format (elements: face|eyebox|pupil|landmark|contour|pose|label)")
+	)
+	return func() render.Params {
+		p := def
+		p.Marker = render.Marker(*marker)
+		p.EyeBoxes = *markEyes
+
+		var err error
+		if p.Layers, err = render.ParseLayers(*layers); err != nil {
+			usageError(fs, "%v", err)
+		}
+		if *drawAxes && !p.Has(render.Pose) {
+			p.Layers = append(p.Layers, render.Pose)
+		}
+		if p, err = render.ParseStyles(*styles, p); err != nil {
+			usageError(fs, "Invalid annotation style: %v", err)
+		}
+		if err := p.Validate(); err != nil {
+			usageError(fs, "Invalid annotation options: %v", err)
+		}
+		return p
+	}
+}
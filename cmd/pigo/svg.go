@@ -0,0 +1,79 @@
+package main
+
+import (
+	"image"
+	"io"
+	"net/url"
+	"path/filepath"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/render"
+	"github.com/esimov/pigo/utils"
+	"github.com/fogleman/gg"
+)
+
+// svgExt is the file extension of the vector annotation output.
+const svgExt = ".svg"
+
+// The ways the source image is included into the svg output.
+const (
+	svgEmbed = "embed"
+	svgLink  = "link"
+)
+
+var svgImages = []string{svgEmbed, svgLink}
+
+// imageOutput describes how the annotated image is written.
+// svg: write the annotations as an svg document instead of drawing them over the decoded image.
+// overlayOnly: leave the source image out, writing only the annotations on a transparent background.
+// href: the link to the source image or its data URI, shown behind the svg annotations.
+// orientation: the EXIF orientation of the source image.
+type imageOutput struct {
+	svg         bool
+	overlayOnly bool
+	href        string
+	orientation pigo.Orientation
+}
+
+// writeImage writes the annotations of the faces detected on the upright source image into the destination.
+func (fd *faceDetector) writeImage(dst io.Writer, src *image.NRGBA, faces []pigo.Face, out imageOutput) error {
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	if out.svg {
+		var bg *render.Background
+		if !out.overlayOnly {
+			bg = &render.Background{Href: out.href, Orientation: out.orientation}
+		}
+		return render.WriteSVG(dst, faces, width, height, bg, fd.render)
+	}
+
+	var dc *gg.Context
+	if out.overlayOnly {
+		dc = gg.NewContext(width, height)
+	} else {
+		dc = newCanvas(src)
+	}
+	render.Draw(dc, faces, fd.render)
+
+	return encodeImage(dst, dc.Image())
+}
+
+// sourceLink returns the link to the source image from the svg document written into the destination:
+// the URL of the remote images and the path relative to the destination directory of the local files.
+func sourceLink(source, destination string) string {
+	if utils.IsValidUrl(source) {
+		return source
+	}
+	src, err := filepath.Abs(source)
+	if err != nil {
+		return filepath.ToSlash(source)
+	}
+	dir, err := filepath.Abs(filepath.Dir(destination))
+	if err != nil {
+		return filepath.ToSlash(src)
+	}
+	if rel, err := filepath.Rel(dir, src); err == nil {
+		src = rel
+	}
+	// The path is escaped, since the link is read as an URL.
+	return (&url.URL{Path: filepath.ToSlash(src)}).String()
+}
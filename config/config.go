@@ -0,0 +1,264 @@
+// Package config loads the face detection settings from configuration files and provides named presets
+// tuned for the common use cases, so that the tools built on the library don't need to hard-code them.
+//
+// The configuration files are JSON documents. The settings missing from a file keep their base values,
+// which allows a file to adjust only a few settings of a preset:
+//
+//	{
+//		"min_size": 40,
+//		"iou_threshold": 0.2,
+//		"landmarks": false
+//	}
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// The names of the built-in presets.
+const (
+	// Webcam640 - faces close to a 640x480 webcam, processed in real time.
+	Webcam640 = "webcam-640"
+	// GroupPhoto - many medium sized faces on a high resolution photo.
+	GroupPhoto = "group-photo"
+	// Passport - a single large frontal face, localized with the highest accuracy.
+	Passport = "passport"
+	// SurveillanceSmallFaces - small and distant faces, like the ones recorded by surveillance cameras.
+	SurveillanceSmallFaces = "surveillance-small-faces"
+)
+
+// Config holds the face detection settings and the face analysis pipeline options.
+// MinSize, MaxSize: the range of the detected face sizes in pixels.
+// ShiftFactor: the detection window shift relative to its size. ScaleFactor: the detection window scaling between the scans.
+// Angle: the cascade rotation angle. 0.0 is 0 radians and 1.0 is 2*pi radians.
+// IoUThreshold: the intersection over union threshold used for clustering the detections.
+// QThreshold: faces with a detection score below this value are discarded.
+// Perturbs: the number of perturbations used by the pupil and landmark points localization.
+// Pupils, Landmarks: toggle the pupil and the facial landmark points localization.
+type Config struct {
+	MinSize      int     `json:"min_size"`
+	MaxSize      int     `json:"max_size"`
+	ShiftFactor  float64 `json:"shift_factor"`
+	ScaleFactor  float64 `json:"scale_factor"`
+	Angle        float64 `json:"angle"`
+	IoUThreshold float64 `json:"iou_threshold"`
+	QThreshold   float32 `json:"q_threshold"`
+	Perturbs     int     `json:"perturbs"`
+	Pupils       bool    `json:"pupils"`
+	Landmarks    bool    `json:"landmarks"`
+}
+
+// Default returns the default settings of the face analysis pipeline (see pigo.NewFaceAnalyzer).
+func Default() Config {
+	return Config{
+		MinSize:      20,
+		MaxSize:      1000,
+		ShiftFactor:  0.1,
+		ScaleFactor:  1.1,
+		IoUThreshold: 0.2,
+		QThreshold:   5,
+		Perturbs:     63,
+		Pupils:       true,
+		Landmarks:    true,
+	}
+}
+
+// presets holds the built-in presets, keyed by their names.
+var presets = map[string]Config{
+	Webcam640: {
+		MinSize:      200,
+		MaxSize:      480,
+		ShiftFactor:  0.1,
+		ScaleFactor:  1.1,
+		IoUThreshold: 0.1,
+		QThreshold:   50,
+		Perturbs:     63,
+		Pupils:       true,
+		Landmarks:    true,
+	},
+	GroupPhoto: {
+		MinSize:      30,
+		MaxSize:      400,
+		ShiftFactor:  0.08,
+		ScaleFactor:  1.08,
+		IoUThreshold: 0.2,
+		QThreshold:   5,
+		Perturbs:     63,
+		Pupils:       true,
+		Landmarks:    true,
+	},
+	Passport: {
+		MinSize:      200,
+		MaxSize:      2000,
+		ShiftFactor:  0.1,
+		ScaleFactor:  1.1,
+		IoUThreshold: 0.3,
+		QThreshold:   10,
+		Perturbs:     127,
+		Pupils:       true,
+		Landmarks:    true,
+	},
+	// The landmark points are not reliable on small faces, while the dense scan finds faces close to the minimum size.
+	SurveillanceSmallFaces: {
+		MinSize:      20,
+		MaxSize:      200,
+		ShiftFactor:  0.05,
+		ScaleFactor:  1.05,
+		IoUThreshold: 0.2,
+		QThreshold:   3,
+		Perturbs:     31,
+		Pupils:       true,
+		Landmarks:    false,
+	},
+}
+
+// Preset returns the settings of the built-in preset with the provided name.
+func Preset(name string) (Config, error) {
+	c, ok := presets[name]
+	if !ok {
+		return Config{}, fmt.Errorf("unknown preset: %s", name)
+	}
+	return c, nil
+}
+
+// PresetNames returns the names of the built-in presets in alphabetical order.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load reads the settings from the configuration file found at path, on top of the base settings.
+func Load(path string, base Config) (Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer f.Close()
+
+	c, err := Decode(f, base)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Decode reads the settings from a JSON document, on top of the base settings.
+// The unknown settings are rejected, so that the typos don't pass unnoticed, and the resulting settings are validated.
+func Decode(r io.Reader, base Config) (Config, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&base); err != nil {
+		return Config{}, err
+	}
+	if err := base.Validate(); err != nil {
+		return Config{}, err
+	}
+	return base, nil
+}
+
+// Validate checks whether the settings are usable by the face detector.
+func (c Config) Validate() error {
+	var errs []error
+	if c.MinSize <= 0 {
+		errs = append(errs, errors.New("min_size should be positive"))
+	}
+	if c.MaxSize < c.MinSize {
+		errs = append(errs, errors.New("max_size should not be smaller than min_size"))
+	}
+	if c.ShiftFactor <= 0 || c.ShiftFactor > 1 {
+		errs = append(errs, errors.New("shift_factor should be within the (0, 1] range"))
+	}
+	if c.ScaleFactor <= 1 {
+		errs = append(errs, errors.New("scale_factor should be greater than 1"))
+	}
+	if c.Angle < 0 || c.Angle > 1 {
+		errs = append(errs, errors.New("angle should be within the [0, 1] range"))
+	}
+	if c.IoUThreshold < 0 || c.IoUThreshold > 1 {
+		errs = append(errs, errors.New("iou_threshold should be within the [0, 1] range"))
+	}
+	if c.Perturbs <= 0 {
+		errs = append(errs, errors.New("perturbs should be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// RegisterFlags defines the detection flags on the flag set, with the default values taken from the settings:
+// -min, -max, -shift, -scale, -angle and -iou.
+func RegisterFlags(fs *flag.FlagSet, def Config) {
+	fs.Int("min", def.MinSize, "Minimum size of face")
+	fs.Int("max", def.MaxSize, "Maximum size of face")
+	fs.Float64("shift", def.ShiftFactor, "Shift detection window by percentage")
+	fs.Float64("scale", def.ScaleFactor, "Scale detection window by percentage")
+	fs.Float64("angle", def.Angle, "0.0 is 0 radians and 1.0 is 2*pi radians")
+	fs.Float64("iou", def.IoUThreshold, "Intersection over union (IoU) threshold")
+}
+
+// ApplyFlags overrides the settings with the detection flags (see RegisterFlags) set explicitly on the command line,
+// so that they take precedence over the preset and the configuration file. The flags left to their default values are ignored.
+func ApplyFlags(c *Config, fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		getter, ok := f.Value.(flag.Getter)
+		if !ok {
+			return
+		}
+		switch v := getter.Get().(type) {
+		case int:
+			switch f.Name {
+			case "min":
+				c.MinSize = v
+			case "max":
+				c.MaxSize = v
+			}
+		case float64:
+			switch f.Name {
+			case "shift":
+				c.ShiftFactor = v
+			case "scale":
+				c.ScaleFactor = v
+			case "angle":
+				c.Angle = v
+			case "iou":
+				c.IoUThreshold = v
+			}
+		}
+	})
+}
+
+// CascadeParams returns the face detection parameters for scanning the image.
+func (c Config) CascadeParams(img pigo.ImageParams) pigo.CascadeParams {
+	return pigo.CascadeParams{
+		MinSize:     c.MinSize,
+		MaxSize:     c.MaxSize,
+		ShiftFactor: c.ShiftFactor,
+		ScaleFactor: c.ScaleFactor,
+		ImageParams: img,
+	}
+}
+
+// Apply configures a newly initialized face analyzer with the settings. Since the pupil and the landmark points
+// localization depend on the cascades the analyzer was initialized with, the settings can only disable them.
+func (c Config) Apply(fa *pigo.FaceAnalyzer) {
+	fa.MinSize = c.MinSize
+	fa.MaxSize = c.MaxSize
+	fa.ShiftFactor = c.ShiftFactor
+	fa.ScaleFactor = c.ScaleFactor
+	fa.Angle = c.Angle
+	fa.IoUThreshold = c.IoUThreshold
+	fa.QThreshold = c.QThreshold
+	fa.Perturbs = c.Perturbs
+	fa.DetectPupils = fa.DetectPupils && c.Pupils
+	fa.DetectLandmarks = fa.DetectLandmarks && c.Landmarks
+}
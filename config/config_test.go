@@ -0,0 +1,93 @@
+package config_test
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/esimov/pigo/config"
+	pigo "github.com/esimov/pigo/core"
+)
+
+func TestConfig_Presets(t *testing.T) {
+	names := config.PresetNames()
+	for _, name := range []string{config.Webcam640, config.GroupPhoto, config.Passport, config.SurveillanceSmallFaces} {
+		c, err := config.Preset(name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if err := c.Validate(); err != nil {
+			t.Errorf("%s: invalid preset: %v", name, err)
+		}
+		if !strings.Contains(strings.Join(names, ","), name) {
+			t.Errorf("%s: missing from the preset names: %v", name, names)
+		}
+	}
+	if err := config.Default().Validate(); err != nil {
+		t.Errorf("invalid default settings: %v", err)
+	}
+	// The WebAssembly detector relies on the webcam preset matching its former settings.
+	if c, _ := config.Preset(config.Webcam640); c.MinSize != 200 || c.MaxSize != 480 || c.QThreshold != 50 {
+		t.Errorf("unexpected webcam preset: %+v", c)
+	}
+	if _, err := config.Preset("unknown"); err == nil {
+		t.Errorf("expected an error for an unknown preset")
+	}
+}
+
+func TestConfig_Decode(t *testing.T) {
+	base, _ := config.Preset(config.Passport)
+
+	c, err := config.Decode(strings.NewReader(`{"min_size": 150, "landmarks": false}`), base)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.MinSize != 150 || c.Landmarks || c.MaxSize != base.MaxSize || c.Perturbs != base.Perturbs {
+		t.Errorf("expected the file values over the base settings, got: %+v", c)
+	}
+
+	for _, doc := range []string{
+		`{"minsize": 150}`,
+		`{"min_size": 3000}`,
+		`{"scale_factor": 1}`,
+		`{"min_size": "large"}`,
+	} {
+		if _, err := config.Decode(strings.NewReader(doc), base); err == nil {
+			t.Errorf("%s: expected an error", doc)
+		}
+	}
+}
+
+func TestConfig_Apply(t *testing.T) {
+	c, _ := config.Preset(config.SurveillanceSmallFaces)
+	img := pigo.ImageParams{Rows: 10, Cols: 20, Dim: 20}
+	if cp := c.CascadeParams(img); cp.MinSize != c.MinSize || cp.ScaleFactor != c.ScaleFactor || cp.Cols != 20 {
+		t.Errorf("unexpected cascade params: %+v", cp)
+	}
+
+	// The analyzer holds no pupil cascade, so the pupil localization can't be enabled.
+	fa := pigo.NewFaceAnalyzer(nil, nil, nil)
+	c.Apply(fa)
+	if fa.MinSize != c.MinSize || fa.QThreshold != c.QThreshold || fa.Perturbs != c.Perturbs || fa.DetectPupils || fa.DetectLandmarks {
+		t.Errorf("unexpected analyzer settings: %+v", fa)
+	}
+}
+
+func TestConfig_ApplyFlags(t *testing.T) {
+	base, _ := config.Preset(config.GroupPhoto)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	config.RegisterFlags(fs, config.Default())
+	if err := fs.Parse([]string{"-min", "40", "-iou", "0.35"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c := base
+	config.ApplyFlags(&c, fs)
+	if c.MinSize != 40 || c.IoUThreshold != 0.35 {
+		t.Errorf("expected the explicit flags to override the settings, got: %+v", c)
+	}
+	// The flags left to their default values keep the base settings.
+	if c.MaxSize != base.MaxSize || c.ShiftFactor != base.ShiftFactor || c.ScaleFactor != base.ScaleFactor {
+		t.Errorf("expected the base settings for the unset flags, got: %+v", c)
+	}
+}
@@ -0,0 +1,229 @@
+package pigo
+
+import (
+	"image"
+)
+
+// EyeParams defines the heuristics used for placing the pupil localization windows relative to the detected face.
+// All the values are expressed as a fraction of the detected face size.
+// RowOffset: the vertical distance of the eyes above the face center.
+// LeftColOffset: the horizontal distance of the left eye from the face center.
+// RightColOffset: the horizontal distance of the right eye from the face center.
+// Scale: the size of the pupil localization window.
+// MinFaceSize: the pupils are localized only on faces bigger than this value (in pixels).
+type EyeParams struct {
+	RowOffset      float64
+	LeftColOffset  float64
+	RightColOffset float64
+	Scale          float64
+	MinFaceSize    int
+}
+
+// DefaultEyeParams returns the eye placement heuristics tuned for still images.
+func DefaultEyeParams() EyeParams {
+	return EyeParams{
+		RowOffset:      0.075,
+		LeftColOffset:  0.175,
+		RightColOffset: 0.185,
+		Scale:          0.25,
+		MinFaceSize:    50,
+	}
+}
+
+// Landmark is a facial landmark point identified by its name (see LandmarkManifest).
+type Landmark struct {
+	Name string
+	Puploc
+}
+
+// Face holds the results of the face analysis pipeline for a single detected face.
+// Box: the bounding box of the detected face.
+// Score: the detection score of the face.
+// Angle: the rotation angle used on detection (0.0 is 0 radians and 1.0 is 2*pi radians).
+// LeftEye, RightEye: the localized pupils, nil if they were not detected.
+// Landmarks: the detected facial landmark points.
+// Pose: the head pose estimated from the pupils and the landmark points, nil if it could not be estimated.
+type Face struct {
+	Detection Detection
+	Box       image.Rectangle
+	Score     float32
+	Angle     float64
+	LeftEye   *Puploc
+	RightEye  *Puploc
+	Landmarks []Landmark
+	Pose      *HeadPose
+}
+
+// Landmark returns the landmark point with the provided name.
+func (f Face) Landmark(name string) (Landmark, bool) {
+	for _, lp := range f.Landmarks {
+		if lp.Name == name {
+			return lp, true
+		}
+	}
+	return Landmark{}, false
+}
+
+// FaceAnalyzer runs the face detection, pupil localization and facial landmark points detection
+// in one step. It owns the unpacked cascades and it's safe for concurrent use once configured.
+// MinSize, MaxSize, ShiftFactor, ScaleFactor: the face detection window parameters (see CascadeParams).
+// Angle: the cascade rotation angle. 0.0 is 0 radians and 1.0 is 2*pi radians.
+// IoUThreshold: the intersection over union threshold used for clustering the detections.
+// QThreshold: faces with a detection score below this value are discarded.
+// Perturbs: the number of perturbations used by the pupil and landmark points localization.
+// Eyes: the heuristics used for placing the pupil localization windows.
+// DetectPupils, DetectLandmarks: toggle the pupil and landmark points localization.
+type FaceAnalyzer struct {
+	MinSize      int
+	MaxSize      int
+	ShiftFactor  float64
+	ScaleFactor  float64
+	Angle        float64
+	IoUThreshold float64
+	QThreshold   float32
+	Perturbs     int
+	Eyes         EyeParams
+
+	DetectPupils    bool
+	DetectLandmarks bool
+
+	faceCascade   *Pigo
+	puplocCascade *PuplocCascade
+	flpCascades   map[string][]*FlpCascade
+}
+
+// NewFaceAnalyzer initializes the FaceAnalyzer constructor method with the unpacked cascades.
+// The pupil localization and the facial landmark points cascades are optional, they can be nil.
+func NewFaceAnalyzer(face *Pigo, puploc *PuplocCascade, flpcs map[string][]*FlpCascade) *FaceAnalyzer {
+	return &FaceAnalyzer{
+		MinSize:      20,
+		MaxSize:      1000,
+		ShiftFactor:  0.1,
+		ScaleFactor:  1.1,
+		IoUThreshold: 0.2,
+		QThreshold:   5.0,
+		Perturbs:     63,
+		Eyes:         DefaultEyeParams(),
+
+		DetectPupils:    puploc != nil,
+		DetectLandmarks: puploc != nil && flpcs != nil,
+
+		faceCascade:   face,
+		puplocCascade: puploc,
+		flpCascades:   flpcs,
+	}
+}
+
+// Analyze converts the image to grayscale and runs the whole analysis pipeline over it.
+func (fa *FaceAnalyzer) Analyze(img image.Image) []Face {
+	src := ImgToNRGBA(img)
+	cols, rows := src.Bounds().Dx(), src.Bounds().Dy()
+
+	return fa.AnalyzeParams(ImageParams{
+		Pixels: RgbToGrayscale(src),
+		Rows:   rows,
+		Cols:   cols,
+		Dim:    cols,
+	})
+}
+
+// AnalyzeParams runs the whole analysis pipeline over the grayscale image pixels.
+func (fa *FaceAnalyzer) AnalyzeParams(img ImageParams) []Face {
+	dets := fa.DetectFaces(img)
+	faces := make([]Face, 0, len(dets))
+
+	for _, det := range dets {
+		faces = append(faces, fa.AnalyzeFace(det, img))
+	}
+	return faces
+}
+
+// DetectFaces runs the face detection and returns the clustered detections
+// having a detection score above the QThreshold value.
+func (fa *FaceAnalyzer) DetectFaces(img ImageParams) []Detection {
+	cParams := CascadeParams{
+		MinSize:     fa.MinSize,
+		MaxSize:     fa.MaxSize,
+		ShiftFactor: fa.ShiftFactor,
+		ScaleFactor: fa.ScaleFactor,
+		ImageParams: img,
+	}
+
+	// Run the classifier over the obtained leaf nodes and return the detection results.
+	// The result contains quadruplets representing the row, column, scale and detection score.
+	dets := fa.faceCascade.RunCascade(cParams, fa.Angle)
+
+	// Calculate the intersection over union (IoU) of two clusters.
+	dets = fa.faceCascade.ClusterDetections(dets, fa.IoUThreshold)
+
+	faces := make([]Detection, 0, len(dets))
+	for _, det := range dets {
+		if det.Q > fa.QThreshold {
+			faces = append(faces, det)
+		}
+	}
+	return faces
+}
+
+// AnalyzeFace localizes the pupils and the facial landmark points of an already detected face.
+func (fa *FaceAnalyzer) AnalyzeFace(det Detection, img ImageParams) Face {
+	face := Face{
+		Detection: det,
+		Box: image.Rect(
+			det.Col-det.Scale/2,
+			det.Row-det.Scale/2,
+			det.Col-det.Scale/2+det.Scale,
+			det.Row-det.Scale/2+det.Scale,
+		),
+		Score: det.Q,
+		Angle: fa.Angle,
+	}
+
+	if !fa.DetectPupils || fa.puplocCascade == nil || det.Scale <= fa.Eyes.MinFaceSize {
+		return face
+	}
+
+	leftEye := fa.puplocCascade.RunDetector(Puploc{
+		Row:      det.Row - int(fa.Eyes.RowOffset*float64(det.Scale)),
+		Col:      det.Col - int(fa.Eyes.LeftColOffset*float64(det.Scale)),
+		Scale:    float32(float64(det.Scale) * fa.Eyes.Scale),
+		Perturbs: fa.Perturbs,
+	}, img, fa.Angle, false)
+	if leftEye.Row > 0 && leftEye.Col > 0 {
+		face.LeftEye = leftEye
+	}
+
+	rightEye := fa.puplocCascade.RunDetector(Puploc{
+		Row:      det.Row - int(fa.Eyes.RowOffset*float64(det.Scale)),
+		Col:      det.Col + int(fa.Eyes.RightColOffset*float64(det.Scale)),
+		Scale:    float32(float64(det.Scale) * fa.Eyes.Scale),
+		Perturbs: fa.Perturbs,
+	}, img, fa.Angle, false)
+	if rightEye.Row > 0 && rightEye.Col > 0 {
+		face.RightEye = rightEye
+	}
+
+	if fa.DetectLandmarks && face.LeftEye != nil && face.RightEye != nil {
+		face.Landmarks = fa.detectLandmarks(face.LeftEye, face.RightEye, img)
+		face.Pose = EstimateHeadPose(face)
+	}
+	return face
+}
+
+// detectLandmarks runs the facial landmark points cascades over the face delimited by the pupils.
+func (fa *FaceAnalyzer) detectLandmarks(leftEye, rightEye *Puploc, img ImageParams) []Landmark {
+	landmarks := make([]Landmark, 0, len(LandmarkManifest))
+
+	for _, spec := range LandmarkManifest {
+		for _, flpc := range fa.flpCascades[spec.Name] {
+			if flpc.PuplocCascade == nil {
+				continue
+			}
+			flp := flpc.GetLandmarkPoint(leftEye, rightEye, img, fa.Perturbs, spec.FlipV)
+			if flp.Row > 0 && flp.Col > 0 {
+				landmarks = append(landmarks, Landmark{Name: spec.Name, Puploc: *flp})
+			}
+		}
+	}
+	return landmarks
+}
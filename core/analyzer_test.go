@@ -0,0 +1,67 @@
+package pigo_test
+
+import (
+	"testing"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+func TestFaceAnalyzer_ShouldReturnFacesWithEyesAndLandmarks(t *testing.T) {
+	p, err := p.Unpack(faceCasc)
+	if err != nil {
+		t.Fatalf("error reading the cascade file: %s", err)
+	}
+	plc, err := pl.UnpackCascade(puplocCasc)
+	if err != nil {
+		t.Fatalf("error reading the puploc cascade file: %s", err)
+	}
+	flpcs, err := plc.ReadCascadeDir("../cascade/lps/")
+	if err != nil {
+		t.Fatalf("error reading the facial landmark points cascade directory: %s", err)
+	}
+
+	fa := pigo.NewFaceAnalyzer(p, plc, flpcs)
+	faces := fa.Analyze(srcImg)
+	if len(faces) == 0 {
+		t.Fatal("should have detected at least one face")
+	}
+
+	for _, face := range faces {
+		if face.Box.Dx() != face.Detection.Scale || face.Score != face.Detection.Q {
+			t.Fatalf("the face box and score should match the detection: %+v", face)
+		}
+		if face.Detection.Scale <= fa.Eyes.MinFaceSize {
+			continue
+		}
+		if face.LeftEye == nil || face.RightEye == nil {
+			t.Fatalf("the pupils should have been detected: %+v", face)
+		}
+		if face.LeftEye.Col >= face.RightEye.Col {
+			t.Fatalf("the left eye should be on the left side of the right eye")
+		}
+		if len(face.Landmarks) != 15 {
+			t.Fatalf("expected 15 facial landmark points, got: %d", len(face.Landmarks))
+		}
+		if face.Pose == nil {
+			t.Fatalf("the head pose should have been estimated: %+v", face)
+		}
+	}
+}
+
+func TestFaceAnalyzer_ShouldSkipOptionalCascades(t *testing.T) {
+	p, err := p.Unpack(faceCasc)
+	if err != nil {
+		t.Fatalf("error reading the cascade file: %s", err)
+	}
+
+	fa := pigo.NewFaceAnalyzer(p, nil, nil)
+	faces := fa.AnalyzeParams(*imgParams)
+	if len(faces) == 0 {
+		t.Fatal("should have detected at least one face")
+	}
+	for _, face := range faces {
+		if face.LeftEye != nil || face.RightEye != nil || len(face.Landmarks) > 0 {
+			t.Fatalf("only the face should have been detected: %+v", face)
+		}
+	}
+}
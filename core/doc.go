@@ -53,6 +53,14 @@ and finally to run the cascade function which returns a slice containing the row
 	// Calculate the intersection over union (IoU) of two clusters.
 	dets = classifier.ClusterDetections(dets, 0.2)
 
+Face analysis pipeline
+
+The FaceAnalyzer runs the face detection, pupil localization and facial landmark points detection in one step
+and returns the box, score, pupils and the named landmark points of each detected face.
+
+	analyzer := pigo.NewFaceAnalyzer(classifier, puplocClassifier, flpcs)
+	faces := analyzer.Analyze(src)
+
 For pupil/eyes localization and facial landmark points detection API example check the source code.
 */
 package pigo
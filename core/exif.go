@@ -0,0 +1,169 @@
+package pigo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+)
+
+// Orientation is the EXIF orientation of an image, which tells how the stored pixels should be transformed
+// for displaying the image upright. Phone cameras store the pixels as they are captured by the sensor,
+// so the photos taken in portrait mode are usually stored sideways, with a rotating orientation.
+type Orientation int
+
+// The EXIF orientation values, named by the transformation applied to the stored pixels for displaying them upright.
+const (
+	OrientationNormal     Orientation = 1 // the pixels are stored upright
+	OrientationFlipH      Orientation = 2 // mirrored horizontally
+	OrientationRotate180  Orientation = 3 // rotated by 180°
+	OrientationFlipV      Orientation = 4 // mirrored vertically
+	OrientationTranspose  Orientation = 5 // mirrored along the top-left to bottom-right diagonal
+	OrientationRotate90   Orientation = 6 // rotated by 90° clockwise
+	OrientationTransverse Orientation = 7 // mirrored along the top-right to bottom-left diagonal
+	OrientationRotate270  Orientation = 8 // rotated by 90° counter-clockwise
+)
+
+// exifOrientationTag is the EXIF tag holding the image orientation.
+const exifOrientationTag = 0x0112
+
+// errInvalidExif is returned when the EXIF metadata is malformed.
+var errInvalidExif = errors.New("invalid exif data")
+
+// ReadOrientation reads the EXIF orientation of a JPEG or TIFF encoded image.
+// OrientationNormal is returned if the image has no EXIF orientation.
+func ReadOrientation(data []byte) (Orientation, error) {
+	if len(data) >= 4 && (bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*"))) {
+		return tiffOrientation(data)
+	}
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return OrientationNormal, nil
+	}
+	// Walk the JPEG segments until the APP1 segment holding the EXIF metadata.
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return OrientationNormal, errInvalidExif
+		}
+		marker := data[pos+1]
+		if marker == 0xd8 || marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) || marker == 0xff {
+			// These markers have no payload.
+			pos += 2
+			continue
+		}
+		// The image data follows the start of scan marker, so the metadata can't be found past it.
+		if marker == 0xda || marker == 0xd9 {
+			break
+		}
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if size < 2 || pos+2+size > len(data) {
+			return OrientationNormal, errInvalidExif
+		}
+		payload := data[pos+4 : pos+2+size]
+		if marker == 0xe1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+			return tiffOrientation(payload[6:])
+		}
+		pos += 2 + size
+	}
+	return OrientationNormal, nil
+}
+
+// tiffOrientation reads the orientation tag from the first image file directory of the TIFF structure.
+func tiffOrientation(data []byte) (Orientation, error) {
+	if len(data) < 8 {
+		return OrientationNormal, errInvalidExif
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return OrientationNormal, errInvalidExif
+	}
+	ifd := int(order.Uint32(data[4:]))
+	if ifd < 8 || ifd+2 > len(data) {
+		return OrientationNormal, errInvalidExif
+	}
+	entries := int(order.Uint16(data[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + 12*i
+		if entry+12 > len(data) {
+			return OrientationNormal, errInvalidExif
+		}
+		if order.Uint16(data[entry:]) != exifOrientationTag {
+			continue
+		}
+		// The orientation is a SHORT value, stored in the first two bytes of the value field.
+		o := Orientation(order.Uint16(data[entry+8:]))
+		if o < OrientationNormal || o > OrientationRotate270 {
+			return OrientationNormal, nil
+		}
+		return o, nil
+	}
+	return OrientationNormal, nil
+}
+
+// Transposed checks whether the orientation swaps the width and the height of the image.
+func (o Orientation) Transposed() bool {
+	return o >= OrientationTranspose && o <= OrientationRotate270
+}
+
+// SourcePoint maps the x, y coordinates of a point of the upright image, sized width x height,
+// back to the coordinates of the same point in the stored image.
+func (o Orientation) SourcePoint(x, y, width, height int) (int, int) {
+	// The size of the stored image.
+	w, h := width, height
+	if o.Transposed() {
+		w, h = height, width
+	}
+	switch o {
+	case OrientationFlipH:
+		return w - 1 - x, y
+	case OrientationRotate180:
+		return w - 1 - x, h - 1 - y
+	case OrientationFlipV:
+		return x, h - 1 - y
+	case OrientationTranspose:
+		return y, x
+	case OrientationRotate90:
+		return y, h - 1 - x
+	case OrientationTransverse:
+		return w - 1 - y, h - 1 - x
+	case OrientationRotate270:
+		return w - 1 - y, x
+	}
+	return x, y
+}
+
+// SourceRect maps a rectangle of the upright image, sized width x height, back to the stored image.
+func (o Orientation) SourceRect(r image.Rectangle, width, height int) image.Rectangle {
+	x0, y0 := o.SourcePoint(r.Min.X, r.Min.Y, width, height)
+	x1, y1 := o.SourcePoint(r.Max.X-1, r.Max.Y-1, width, height)
+	return image.Rect(min(x0, x1), min(y0, y1), max(x0, x1)+1, max(y0, y1)+1)
+}
+
+// Upright transforms the stored image into its upright version. The image is returned unchanged
+// if its orientation is normal.
+func (o Orientation) Upright(img *image.NRGBA) *image.NRGBA {
+	if o <= OrientationNormal || o > OrientationRotate270 {
+		return img
+	}
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if o.Transposed() {
+		width, height = height, width
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		di := dst.PixOffset(0, y)
+		for x := 0; x < width; x++ {
+			sx, sy := o.SourcePoint(x, y, width, height)
+			si := img.PixOffset(b.Min.X+sx, b.Min.Y+sy)
+			copy(dst.Pix[di:di+4], img.Pix[si:si+4])
+			di += 4
+		}
+	}
+	return dst
+}
@@ -0,0 +1,147 @@
+package pigo_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	pigo "github.com/esimov/pigo/core"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+// exifSegment builds a JPEG APP1 segment holding the orientation tag in the provided byte order.
+func exifSegment(o pigo.Orientation, order binary.ByteOrder) []byte {
+	var tiffData bytes.Buffer
+	if order == binary.LittleEndian {
+		tiffData.WriteString("II*\x00")
+	} else {
+		tiffData.WriteString("MM\x00*")
+	}
+	binary.Write(&tiffData, order, uint32(8)) // offset of the first image file directory
+	binary.Write(&tiffData, order, uint16(1)) // number of entries
+	binary.Write(&tiffData, order, uint16(0x0112))
+	binary.Write(&tiffData, order, uint16(3)) // SHORT
+	binary.Write(&tiffData, order, uint32(1))
+	binary.Write(&tiffData, order, uint16(o))
+	binary.Write(&tiffData, order, uint16(0))
+	binary.Write(&tiffData, order, uint32(0)) // no next directory
+
+	payload := append([]byte("Exif\x00\x00"), tiffData.Bytes()...)
+	segment := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// orientedJPEG encodes the image as JPEG with the EXIF orientation inserted after the start of image marker.
+func orientedJPEG(t *testing.T, img image.Image, o pigo.Orientation, order binary.ByteOrder) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("failed encoding the jpeg image: %v", err)
+	}
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), exifSegment(o, order)...), data[2:]...)
+}
+
+func TestExif_ReadOrientation(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 4))
+	for o := pigo.OrientationNormal; o <= pigo.OrientationRotate270; o++ {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			got, err := pigo.ReadOrientation(orientedJPEG(t, img, o, order))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != o {
+				t.Errorf("expected orientation %d, got %d", o, got)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, nil)
+	if o, err := pigo.ReadOrientation(buf.Bytes()); err != nil || o != pigo.OrientationNormal {
+		t.Errorf("expected the normal orientation without exif data, got %d (%v)", o, err)
+	}
+	if _, err := pigo.ReadOrientation([]byte{0xff, 0xd8, 0xff, 0xe1, 0xff, 0xff}); err == nil {
+		t.Errorf("expected an error for a truncated exif segment")
+	}
+}
+
+func TestExif_DecodeImageShouldBeUpright(t *testing.T) {
+	// The left half of the stored image is dark and the right half is bright.
+	src := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	for y := 0; y < 32; y++ {
+		for x := 32; x < 64; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+		}
+	}
+	data := orientedJPEG(t, src, pigo.OrientationRotate90, binary.BigEndian)
+
+	img, err := pigo.DecodeImage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed decoding the image: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 32 || b.Dy() != 64 {
+		t.Fatalf("expected the rotated image size to be 32x64, got %dx%d", b.Dx(), b.Dy())
+	}
+	// Rotated clockwise, the right half of the stored image becomes the bottom half.
+	if top, bottom := img.NRGBAAt(16, 8).R, img.NRGBAAt(16, 56).R; top > 64 || bottom < 192 {
+		t.Errorf("the image is not upright: top %d, bottom %d", top, bottom)
+	}
+
+	stored, o, err := pigo.DecodeImageOrientation(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed decoding the image: %v", err)
+	}
+	if o != pigo.OrientationRotate90 || stored.Bounds().Dx() != 64 {
+		t.Errorf("expected the stored image with its orientation, got %v and %d", stored.Bounds(), o)
+	}
+}
+
+func TestExif_SourcePointShouldMatchUpright(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 5, 3))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i)
+	}
+	for o := pigo.OrientationNormal; o <= pigo.OrientationRotate270; o++ {
+		up := o.Upright(src)
+		w, h := up.Bounds().Dx(), up.Bounds().Dy()
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				sx, sy := o.SourcePoint(x, y, w, h)
+				if up.NRGBAAt(x, y) != src.NRGBAAt(sx, sy) {
+					t.Fatalf("orientation %d: the point %d,%d is not mapped to %d,%d", o, x, y, sx, sy)
+				}
+			}
+		}
+		if r := o.SourceRect(up.Bounds(), w, h); r != src.Bounds() {
+			t.Errorf("orientation %d: expected the whole image to be mapped to %v, got %v", o, src.Bounds(), r)
+		}
+	}
+}
+
+func TestImage_DecodeBMPAndTIFF(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 6, 4))
+	src.SetNRGBA(1, 2, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+
+	encoders := map[string]func(*bytes.Buffer) error{
+		"bmp":  func(w *bytes.Buffer) error { return bmp.Encode(w, src) },
+		"tiff": func(w *bytes.Buffer) error { return tiff.Encode(w, src, nil) },
+	}
+	for name, encode := range encoders {
+		var buf bytes.Buffer
+		if err := encode(&buf); err != nil {
+			t.Fatalf("%s: failed encoding the image: %v", name, err)
+		}
+		img, err := pigo.DecodeImage(&buf)
+		if err != nil {
+			t.Fatalf("%s: failed decoding the image: %v", name, err)
+		}
+		if got := img.NRGBAAt(1, 2); got.R != 200 || got.G != 100 || got.B != 50 {
+			t.Errorf("%s: unexpected pixel color %v", name, got)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package pigo
+
+// Median exposes the median of the perturbed pupil localizations to the tests.
+var Median = median
+
+// ErrInvalidCascade exposes the error of the malformed cascade files to the tests.
+var ErrInvalidCascade = errInvalidCascade
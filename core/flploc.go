@@ -2,9 +2,11 @@ package pigo
 
 import (
 	"errors"
-	"io/ioutil"
+	"fmt"
+	"io/fs"
 	"math"
-	"path/filepath"
+	"os"
+	"path"
 	"sync"
 )
 
@@ -25,7 +27,16 @@ var flplocPool = sync.Pool{
 // UnpackFlp unpacks the facial landmark points cascade file.
 // This will return the binary representation of the cascade file.
 func (plc *PuplocCascade) UnpackFlp(cf string) (*PuplocCascade, error) {
-	flpc, err := ioutil.ReadFile(cf)
+	flpc, err := os.ReadFile(cf)
+	if err != nil {
+		return nil, err
+	}
+	return plc.UnpackCascade(flpc)
+}
+
+// UnpackFlpFS unpacks the facial landmark points cascade file found in the provided file system.
+func (plc *PuplocCascade) UnpackFlpFS(fsys fs.FS, name string) (*PuplocCascade, error) {
+	flpc, err := fs.ReadFile(fsys, name)
 	if err != nil {
 		return nil, err
 	}
@@ -57,25 +68,50 @@ func (plc *PuplocCascade) GetLandmarkPoint(leftEye, rightEye *Puploc, img ImageP
 }
 
 // ReadCascadeDir reads the facial landmark points cascade files from the provided directory.
+// The returned cascades are keyed by the landmark point names defined in the LandmarkManifest.
 func (plc *PuplocCascade) ReadCascadeDir(path string) (map[string][]*FlpCascade, error) {
-	cascades, err := ioutil.ReadDir(path)
+	return plc.ReadCascadeFS(os.DirFS(path), ".")
+}
+
+// ReadCascadeFS reads the facial landmark points cascade files defined in the LandmarkManifest
+// from the dir directory of the provided file system (like an embed.FS or a zip archive).
+func (plc *PuplocCascade) ReadCascadeFS(fsys fs.FS, dir string) (map[string][]*FlpCascade, error) {
+	return plc.ReadCascadeManifest(fsys, dir, LandmarkManifest)
+}
+
+// ReadCascadeManifest reads the facial landmark points cascade files defined in the provided manifest
+// and returns them keyed by the landmark point names. Since some of the cascades are run on both sides
+// of the face, the same cascade might be returned under two different names.
+// The files not listed in the manifest are ignored. The errors of the individual cascade files
+// are reported together, while the cascades which could be unpacked are still returned.
+func (plc *PuplocCascade) ReadCascadeManifest(fsys fs.FS, dir string, manifest []LandmarkSpec) (map[string][]*FlpCascade, error) {
+	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(cascades) == 0 {
+	if len(entries) == 0 {
 		return nil, errors.New("the provided directory is empty")
 	}
 
-	flpcs := make(map[string][]*FlpCascade, len(cascades))
+	var (
+		flpcs    = make(map[string][]*FlpCascade, len(manifest))
+		cascades = make(map[string]*FlpCascade, len(manifest))
+		errs     []error
+	)
 
-	for _, cascade := range cascades {
-		cf, err := filepath.Abs(path + "/" + cascade.Name())
-		if err != nil {
-			return nil, err
+	for _, spec := range manifest {
+		flpc, ok := cascades[spec.Cascade]
+		if !ok {
+			cascade, err := plc.UnpackFlpFS(fsys, path.Join(dir, spec.Cascade))
+			if err != nil {
+				err = fmt.Errorf("%s: %w", spec.Cascade, err)
+				errs = append(errs, err)
+			}
+			flpc = &FlpCascade{cascade, err}
+			cascades[spec.Cascade] = flpc
 		}
-		flpc, err := plc.UnpackFlp(cf)
-		flpcs[cascade.Name()] = append(flpcs[cascade.Name()], &FlpCascade{flpc, err})
+		flpcs[spec.Name] = append(flpcs[spec.Name], flpc)
 	}
-	return flpcs, err
+	return flpcs, errors.Join(errs...)
 }
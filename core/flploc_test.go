@@ -4,7 +4,9 @@ import (
 	"io/ioutil"
 	"log"
 	"runtime"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	pigo "github.com/esimov/pigo/core"
 )
@@ -74,9 +76,7 @@ func TestFlploc_LandmarkDetectorShouldReturnDetectionPoints(t *testing.T) {
 
 func TestFlploc_LandmarkDetectorShouldReturnCorrectDetectionPoints(t *testing.T) {
 	var (
-		eyeCascades   = []string{"lp46", "lp44", "lp42", "lp38", "lp312"}
-		mouthCascades = []string{"lp93", "lp84", "lp82", "lp81"}
-		flpcs         map[string][]*pigo.FlpCascade
+		flpcs map[string][]*pigo.FlpCascade
 
 		detLandmarkPts int
 	)
@@ -119,40 +119,45 @@ func TestFlploc_LandmarkDetectorShouldReturnCorrectDetectionPoints(t *testing.T)
 			}
 			rightEye := plc.RunDetector(*puploc, *imgParams, 0.0, false)
 
-			for _, eye := range eyeCascades {
-				for _, flpc := range flpcs[eye] {
-					flp := flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, perturb, false)
-					if flp.Row > 0 && flp.Col > 0 {
-						detLandmarkPts++
-					}
-					flp = flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, perturb, true)
+			for _, spec := range pigo.LandmarkManifest {
+				for _, flpc := range flpcs[spec.Name] {
+					flp := flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, perturb, spec.FlipV)
 					if flp.Row > 0 && flp.Col > 0 {
 						detLandmarkPts++
 					}
 				}
 			}
-			for _, mouth := range mouthCascades {
-				for _, flpc := range flpcs[mouth] {
-					flp := flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, perturb, false)
-					if flp.Row > 0 && flp.Col > 0 {
-						detLandmarkPts++
-					}
-				}
-			}
-
-			flp := flpcs["lp84"][0].GetLandmarkPoint(leftEye, rightEye, *imgParams, perturb, true)
-			if flp.Row > 0 && flp.Col > 0 {
-				detLandmarkPts++
-			}
-
 		}
 	}
-	expLandmarkPts := 2*len(eyeCascades) + len(mouthCascades) + 1 // landmark points of the left/right eyes, mouth + nose
+	// landmark points of the left/right eyebrows, eyes, mouth + nose
+	expLandmarkPts := len(pigo.LandmarkManifest)
 	if expLandmarkPts != detLandmarkPts {
 		t.Fatalf("expected facial landmark points to be detected: %d, got: %d", expLandmarkPts, detLandmarkPts)
 	}
 }
 
+func TestFlploc_ReadCascadeDirShouldReturnNamedLandmarks(t *testing.T) {
+	flpcs, err := plc.ReadCascadeDir("../cascade/lps/")
+	if err != nil {
+		t.Fatalf("error reading the facial landmark points cascade directory: %s", err)
+	}
+	if len(flpcs) != len(pigo.LandmarkManifest) {
+		t.Fatalf("expected %d named landmarks, got: %d", len(pigo.LandmarkManifest), len(flpcs))
+	}
+	for _, spec := range pigo.LandmarkManifest {
+		if len(flpcs[spec.Name]) != 1 {
+			t.Fatalf("missing cascade for the landmark point: %s", spec.Name)
+		}
+		if got, ok := pigo.LookupLandmark(spec.Name); !ok || got != spec {
+			t.Fatalf("the %s landmark point should be found in the manifest", spec.Name)
+		}
+	}
+	// The same cascade is shared between the two sides of the face.
+	if flpcs[pigo.MouthLeft][0].PuplocCascade != flpcs[pigo.MouthRight][0].PuplocCascade {
+		t.Fatal("the mouth corners should be detected by the same cascade")
+	}
+}
+
 func BenchmarkFlplocReadCascadeDir(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		plc.ReadCascadeDir("../cascade/lps/")
@@ -231,3 +236,34 @@ func BenchmarkFlplocDetection(b *testing.B) {
 	}
 	_ = dets
 }
+
+func TestFlploc_ReadCascadeFSShouldIgnoreStrayFilesAndReportErrors(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for _, spec := range pigo.LandmarkManifest {
+		data, err := ioutil.ReadFile("../cascade/lps/" + spec.Cascade)
+		if err != nil {
+			t.Fatalf("missing cascade file: %v", err)
+		}
+		fsys["lps/"+spec.Cascade] = &fstest.MapFile{Data: data}
+	}
+	fsys["lps/.DS_Store"] = &fstest.MapFile{Data: []byte{0x00, 0x01}}
+
+	flpcs, err := plc.ReadCascadeFS(fsys, "lps")
+	if err != nil {
+		t.Fatalf("stray files should be ignored: %v", err)
+	}
+	if len(flpcs) != len(pigo.LandmarkManifest) {
+		t.Fatalf("expected %d named landmarks, got: %d", len(pigo.LandmarkManifest), len(flpcs))
+	}
+
+	fsys["lps/lp38"] = &fstest.MapFile{Data: []byte{0x00, 0x01}}
+	delete(fsys, "lps/lp81")
+
+	flpcs, err = plc.ReadCascadeFS(fsys, "lps")
+	if err == nil || !strings.Contains(err.Error(), "lp38") || !strings.Contains(err.Error(), "lp81") {
+		t.Fatalf("the errors of all the invalid cascade files should be reported, got: %v", err)
+	}
+	if flpcs[pigo.LeftEyeInnerCorner][0].PuplocCascade != nil || flpcs[pigo.NoseTip][0].PuplocCascade == nil {
+		t.Fatal("only the valid cascade files should be unpacked")
+	}
+}
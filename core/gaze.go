@@ -0,0 +1,179 @@
+package pigo
+
+import "math"
+
+// GazeDirection is a coarse estimation of where the subject is looking at.
+// The directions are meant from the subject's perspective facing the camera,
+// which means that looking to the left moves the pupils towards the right side of the image.
+type GazeDirection string
+
+// The possible gaze directions.
+const (
+	GazeCenter GazeDirection = "center"
+	GazeLeft   GazeDirection = "left"
+	GazeRight  GazeDirection = "right"
+	GazeUp     GazeDirection = "up"
+	GazeDown   GazeDirection = "down"
+)
+
+// GazeParams contains the settings of the gaze estimation.
+// EyeAspectRatio: the height of the eye box relative to the distance between the eye corners.
+// HorizontalThreshold: the normalized horizontal pupil offset above which the gaze is considered to be left or right.
+// VerticalThreshold: the normalized vertical pupil offset above which the gaze is considered to be up or down.
+type GazeParams struct {
+	EyeAspectRatio      float64
+	HorizontalThreshold float64
+	VerticalThreshold   float64
+}
+
+// DefaultGazeParams returns the default gaze estimation settings.
+func DefaultGazeParams() GazeParams {
+	return GazeParams{
+		EyeAspectRatio:      0.35,
+		HorizontalThreshold: 0.2,
+		VerticalThreshold:   0.3,
+	}
+}
+
+// EyeGaze holds the pupil offset from the center of the eye box, normalized to the [-1, 1] range.
+// The X axis goes from the image left eye corner to the image right eye corner, the Y axis points downwards.
+type EyeGaze struct {
+	X float64
+	Y float64
+}
+
+// Gaze holds the gaze estimation of a face.
+// Left, Right: the normalized pupil offsets of the left and right eye, nil if they could not be computed.
+// X, Y: the mean normalized pupil offset of the available eyes.
+// Direction: the coarse gaze direction.
+type Gaze struct {
+	Left      *EyeGaze
+	Right     *EyeGaze
+	X         float64
+	Y         float64
+	Direction GazeDirection
+}
+
+// EstimateGaze computes the normalized pupil offsets of the face within the eye boxes delimited by the eye corners
+// and classifies them into a coarse gaze direction. It returns nil if neither of the eyes has a pupil and both eye corners.
+func EstimateGaze(face Face, gp GazeParams) *Gaze {
+	var gaze Gaze
+
+	if face.LeftEye != nil {
+		outer, ok1 := face.Landmark(LeftEyeOuterCorner)
+		inner, ok2 := face.Landmark(LeftEyeInnerCorner)
+		if ok1 && ok2 {
+			gaze.Left = eyeGaze(face.LeftEye, &outer.Puploc, &inner.Puploc, gp.EyeAspectRatio)
+		}
+	}
+	if face.RightEye != nil {
+		inner, ok1 := face.Landmark(RightEyeInnerCorner)
+		outer, ok2 := face.Landmark(RightEyeOuterCorner)
+		if ok1 && ok2 {
+			gaze.Right = eyeGaze(face.RightEye, &inner.Puploc, &outer.Puploc, gp.EyeAspectRatio)
+		}
+	}
+
+	var n float64
+	for _, eye := range []*EyeGaze{gaze.Left, gaze.Right} {
+		if eye != nil {
+			gaze.X += eye.X
+			gaze.Y += eye.Y
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	gaze.X /= n
+	gaze.Y /= n
+	gaze.Direction = gp.Direction(gaze.X, gaze.Y)
+
+	return &gaze
+}
+
+// Direction classifies the normalized pupil offset into a coarse gaze direction.
+func (gp GazeParams) Direction(x, y float64) GazeDirection {
+	// Compare the offsets relative to their own thresholds, so the dominant axis wins.
+	h := math.Abs(x) / gp.HorizontalThreshold
+	v := math.Abs(y) / gp.VerticalThreshold
+
+	switch {
+	case h <= 1 && v <= 1:
+		return GazeCenter
+	case h >= v && x > 0:
+		return GazeLeft
+	case h >= v:
+		return GazeRight
+	case y < 0:
+		return GazeUp
+	}
+	return GazeDown
+}
+
+// eyeGaze returns the pupil offset within the eye box delimited by the image left (c1) and image right (c2) eye corners.
+func eyeGaze(pupil, c1, c2 *Puploc, aspectRatio float64) *EyeGaze {
+	dx, dy := float64(c2.Col-c1.Col), float64(c2.Row-c1.Row)
+	width := math.Hypot(dx, dy)
+	if width == 0 || aspectRatio <= 0 {
+		return nil
+	}
+	// Unit vectors along and perpendicular to the line connecting the eye corners.
+	ux, uy := dx/width, dy/width
+	nx, ny := -uy, ux
+
+	px := float64(pupil.Col) - float64(c1.Col+c2.Col)/2
+	py := float64(pupil.Row) - float64(c1.Row+c2.Row)/2
+
+	return &EyeGaze{
+		X: clamp((px*ux+py*uy)/(width/2), -1, 1),
+		Y: clamp((px*nx+py*ny)/(width*aspectRatio/2), -1, 1),
+	}
+}
+
+// GazeSmoother smooths the successive gaze estimations of a tracked face
+// with an exponential moving average. A separate smoother should be used for each face.
+// Alpha: the weight of the newest estimation, between 0 (no update) and 1 (no smoothing).
+type GazeSmoother struct {
+	Alpha float64
+	GazeParams
+
+	last *Gaze
+}
+
+// NewGazeSmoother initializes the GazeSmoother constructor method.
+func NewGazeSmoother(alpha float64, gp GazeParams) *GazeSmoother {
+	return &GazeSmoother{Alpha: alpha, GazeParams: gp}
+}
+
+// Smooth blends the gaze estimation with the previous ones and returns the smoothed gaze.
+// A nil estimation (for example on a blink) keeps the previous state.
+func (gs *GazeSmoother) Smooth(gaze *Gaze) *Gaze {
+	if gaze == nil {
+		return gs.last
+	}
+	if gs.last == nil {
+		g := *gaze
+		gs.last = &g
+		return gs.last
+	}
+
+	ema := func(prev, curr float64) float64 {
+		return prev + gs.Alpha*(curr-prev)
+	}
+	smoothed := &Gaze{
+		Left:  gaze.Left,
+		Right: gaze.Right,
+		X:     ema(gs.last.X, gaze.X),
+		Y:     ema(gs.last.Y, gaze.Y),
+	}
+	smoothed.Direction = gs.Direction(smoothed.X, smoothed.Y)
+	gs.last = smoothed
+
+	return smoothed
+}
+
+// Reset clears the state of the smoother.
+func (gs *GazeSmoother) Reset() {
+	gs.last = nil
+}
@@ -0,0 +1,104 @@
+package pigo_test
+
+import (
+	"math"
+	"testing"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// syntheticFace builds a face with the eye corners placed symmetrically around the pupils.
+// The pupils are shifted by dx, dy pixels from the center of the eye boxes.
+func syntheticFace(dx, dy int) pigo.Face {
+	landmark := func(name string, row, col int) pigo.Landmark {
+		return pigo.Landmark{Name: name, Puploc: pigo.Puploc{Row: row, Col: col}}
+	}
+	return pigo.Face{
+		LeftEye:  &pigo.Puploc{Row: 100 + dy, Col: 100 + dx},
+		RightEye: &pigo.Puploc{Row: 100 + dy, Col: 200 + dx},
+		Landmarks: []pigo.Landmark{
+			landmark(pigo.LeftEyeOuterCorner, 100, 80),
+			landmark(pigo.LeftEyeInnerCorner, 100, 120),
+			landmark(pigo.RightEyeInnerCorner, 100, 180),
+			landmark(pigo.RightEyeOuterCorner, 100, 220),
+		},
+	}
+}
+
+func TestGaze_EstimateShouldClassifyTheDirection(t *testing.T) {
+	gp := pigo.DefaultGazeParams()
+
+	testCases := []struct {
+		dx, dy int
+		want   pigo.GazeDirection
+	}{
+		{0, 0, pigo.GazeCenter},
+		{2, 1, pigo.GazeCenter},
+		{10, 0, pigo.GazeLeft},
+		{-10, 0, pigo.GazeRight},
+		{0, -5, pigo.GazeUp},
+		{1, 5, pigo.GazeDown},
+	}
+	for _, tc := range testCases {
+		gaze := pigo.EstimateGaze(syntheticFace(tc.dx, tc.dy), gp)
+		if gaze == nil {
+			t.Fatal("the gaze should have been estimated")
+		}
+		if gaze.Direction != tc.want {
+			t.Errorf("offset (%d, %d): expected direction %s, got: %s (%+v)", tc.dx, tc.dy, tc.want, gaze.Direction, gaze)
+		}
+	}
+
+	gaze := pigo.EstimateGaze(syntheticFace(10, 0), gp)
+	if math.Abs(gaze.Left.X-0.5) > 1e-9 || math.Abs(gaze.Right.X-0.5) > 1e-9 || gaze.Left.Y != 0 {
+		t.Fatalf("the pupil offset should be normalized by the eye box size, got: %+v %+v", *gaze.Left, *gaze.Right)
+	}
+}
+
+func TestGaze_EstimateShouldHandleRolledFaces(t *testing.T) {
+	face := syntheticFace(10, 0)
+	// Rotate all the points by 30 degrees around the origin.
+	rotate := func(p *pigo.Puploc) {
+		a := math.Pi / 6
+		x, y := float64(p.Col), float64(p.Row)
+		p.Col = int(math.Round(x*math.Cos(a) - y*math.Sin(a)))
+		p.Row = int(math.Round(x*math.Sin(a) + y*math.Cos(a)))
+	}
+	rotate(face.LeftEye)
+	rotate(face.RightEye)
+	for i := range face.Landmarks {
+		rotate(&face.Landmarks[i].Puploc)
+	}
+
+	gaze := pigo.EstimateGaze(face, pigo.DefaultGazeParams())
+	if gaze.Direction != pigo.GazeLeft || math.Abs(gaze.Y) > 0.1 {
+		t.Fatalf("the gaze should be estimated along the eye line, got: %+v", gaze)
+	}
+}
+
+func TestGaze_EstimateShouldRequireThePupilsAndTheEyeCorners(t *testing.T) {
+	face := syntheticFace(0, 0)
+	face.LeftEye = nil
+	face.Landmarks = face.Landmarks[:3]
+
+	if gaze := pigo.EstimateGaze(face, pigo.DefaultGazeParams()); gaze != nil {
+		t.Fatalf("the gaze should not be estimated without the pupils and the eye corners, got: %+v", gaze)
+	}
+}
+
+func TestGaze_SmootherShouldDampenTheChanges(t *testing.T) {
+	gp := pigo.DefaultGazeParams()
+	gs := pigo.NewGazeSmoother(0.5, gp)
+
+	gs.Smooth(pigo.EstimateGaze(syntheticFace(0, 0), gp))
+	gaze := gs.Smooth(pigo.EstimateGaze(syntheticFace(10, 0), gp))
+	if math.Abs(gaze.X-0.25) > 1e-9 {
+		t.Fatalf("expected the smoothed horizontal offset to be 0.25, got: %v", gaze.X)
+	}
+	if gaze.Direction != pigo.GazeLeft {
+		t.Fatalf("expected the smoothed direction to be left, got: %s", gaze.Direction)
+	}
+	if gs.Smooth(nil) != gaze {
+		t.Fatal("a missing estimation should keep the previous state")
+	}
+}
@@ -1,15 +1,23 @@
 package pigo
 
 import (
+	"bytes"
 	"image"
 	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
 	"os"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
 )
 
 // GetImage retrieves and decodes the image file to *image.NRGBA type.
+// The image is returned upright, as given by its EXIF orientation.
 func GetImage(input string) (*image.NRGBA, error) {
 	file, err := os.Open(input)
 	if err != nil {
@@ -20,15 +28,35 @@ func GetImage(input string) (*image.NRGBA, error) {
 	return DecodeImage(file)
 }
 
-// DecodeImage decodes the image file to *image.NRGBA type.
+// DecodeImage decodes the image file (JPEG, PNG, GIF, BMP or TIFF) to *image.NRGBA type.
+// The image is returned upright, as given by its EXIF orientation, so the detection
+// runs on the pixels as they are displayed.
 func DecodeImage(f io.Reader) (*image.NRGBA, error) {
-	src, _, err := image.Decode(f)
+	img, orientation, err := DecodeImageOrientation(f)
 	if err != nil {
 		return nil, err
 	}
-	img := ImgToNRGBA(src)
+	return orientation.Upright(img), nil
+}
 
-	return img, nil
+// DecodeImageOrientation decodes the image file to *image.NRGBA type keeping the pixels as they are stored,
+// and returns the EXIF orientation of the image as well. The orientation can be used for transforming the image
+// upright or for mapping the coordinates detected on the upright image back to the stored image.
+// The malformed EXIF metadata is ignored and reported as the normal orientation.
+func DecodeImageOrientation(f io.Reader) (*image.NRGBA, Orientation, error) {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, OrientationNormal, err
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, OrientationNormal, err
+	}
+	orientation, err := ReadOrientation(data)
+	if err != nil {
+		orientation = OrientationNormal
+	}
+	return ImgToNRGBA(src), orientation, nil
 }
 
 // ImgToNRGBA converts any image type to *image.NRGBA with min-point at (0, 0).
@@ -88,3 +116,67 @@ func ImgToNRGBA(img image.Image) *image.NRGBA {
 	}
 	return dst
 }
+
+// GIFPalette returns the palette the composited i-th frame of the GIF should be quantized to when it's re-encoded.
+// The palette of the frame is used only if the frame covers the whole canvas, otherwise the composited frame
+// might contain colors of the previous frames, so the global palette is preferred. Plan9 is the fallback palette.
+func GIFPalette(g *gif.GIF, i int) color.Palette {
+	frame := g.Image[i]
+	if frame.Bounds().Eq(image.Rect(0, 0, g.Config.Width, g.Config.Height)) && len(frame.Palette) > 0 {
+		return frame.Palette
+	}
+	if pal, ok := g.Config.ColorModel.(color.Palette); ok && len(pal) > 0 {
+		return pal
+	}
+	return palette.Plan9
+}
+
+// GIFFrames composites the frames of an animated GIF and returns them as full size images,
+// as they are displayed. The frames of a GIF can cover only a part of the canvas and they are
+// drawn on top of the previous ones, depending on their disposal method.
+func GIFFrames(g *gif.GIF) []*image.NRGBA {
+	frames := make([]*image.NRGBA, 0, len(g.Image))
+	WalkGIFFrames(g, func(i int, frame *image.NRGBA) error {
+		frames = append(frames, frame)
+		return nil
+	})
+	return frames
+}
+
+// WalkGIFFrames composites the frames of an animated GIF one at a time, like GIFFrames, and calls fn
+// with the index and the full size image of each frame, so that only the frame being processed is held in memory.
+// The first error returned by fn stops the walk and it's returned.
+func WalkGIFFrames(g *gif.GIF, fn func(i int, frame *image.NRGBA) error) error {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	for _, frame := range g.Image {
+		bounds = bounds.Union(frame.Bounds())
+	}
+	canvas := image.NewNRGBA(bounds)
+
+	for i, frame := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var previous *image.NRGBA
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewNRGBA(bounds)
+			copy(previous.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		full := image.NewNRGBA(bounds)
+		copy(full.Pix, canvas.Pix)
+		if err := fn(i, ImgToNRGBA(full)); err != nil {
+			return err
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return nil
+}
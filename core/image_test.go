@@ -1,13 +1,17 @@
 package pigo_test
 
 import (
+	"errors"
 	"image"
 	"image/color"
 	"image/color/palette"
 	"image/draw"
+	"image/gif"
 	"io/ioutil"
 	"path/filepath"
 	"testing"
+
+	pigo "github.com/esimov/pigo/core"
 )
 
 func TestGetImage(t *testing.T) {
@@ -234,3 +238,62 @@ func absint(i int) int {
 	}
 	return i
 }
+
+func TestGIFFrames_ShouldCompositeTheFrames(t *testing.T) {
+	pal := color.Palette{color.Transparent, color.Black, color.White}
+	full := image.NewPaletted(image.Rect(0, 0, 4, 4), pal)
+	draw.Draw(full, full.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	// The second frame covers only the top left corner and it's restored to the background after display.
+	partial := image.NewPaletted(image.Rect(0, 0, 2, 2), pal)
+	draw.Draw(partial, partial.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	g := &gif.GIF{
+		Image:    []*image.Paletted{full, partial, image.NewPaletted(image.Rect(2, 2, 3, 3), pal)},
+		Delay:    []int{0, 0, 0},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalNone},
+		Config:   image.Config{Width: 4, Height: 4},
+	}
+	frames := pigo.GIFFrames(g)
+	if len(frames) != 3 {
+		t.Fatalf("expected 3 frames, got: %d", len(frames))
+	}
+	for i, frame := range frames {
+		if frame.Bounds() != image.Rect(0, 0, 4, 4) {
+			t.Fatalf("expected full size frames, got: %v", frame.Bounds())
+		}
+		corner, other := frame.NRGBAAt(0, 0), frame.NRGBAAt(3, 3)
+		switch i {
+		case 1:
+			if corner.R != 0 || corner.A != 255 || other.R != 255 {
+				t.Errorf("expected the partial frame drawn over the previous one, got: %v, %v", corner, other)
+			}
+		case 2:
+			if corner.A != 0 || other.R != 255 {
+				t.Errorf("expected the partial frame disposed to the background, got: %v, %v", corner, other)
+			}
+		}
+	}
+}
+
+func TestGIFFrames_WalkShouldStopOnError(t *testing.T) {
+	pal := color.Palette{color.Black, color.White}
+	frame := image.NewPaletted(image.Rect(0, 0, 2, 2), pal)
+	g := &gif.GIF{
+		Image:  []*image.Paletted{frame, frame, frame},
+		Delay:  []int{0, 0, 0},
+		Config: image.Config{Width: 2, Height: 2},
+	}
+	stop := errors.New("stop")
+	var visited []int
+	err := pigo.WalkGIFFrames(g, func(i int, img *image.NRGBA) error {
+		visited = append(visited, i)
+		if i == 1 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || len(visited) != 2 {
+		t.Errorf("expected the walk to stop at the second frame, got: %v, %v", visited, err)
+	}
+}
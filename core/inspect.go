@@ -0,0 +1,182 @@
+package pigo
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// PixelTest is the binary test of a tree node, which compares the intensities of two pixels.
+// The pixel offsets are relative to the center of the inspected region and are expressed
+// in 1/256 units of the region size, which means that they lie in the [-128, 127] interval.
+type PixelTest struct {
+	Row1 int8
+	Col1 int8
+	Row2 int8
+	Col2 int8
+}
+
+// Range is a closed interval of values.
+type Range struct {
+	Min float32
+	Max float32
+}
+
+// CascadeInfo contains the statistics of an unpacked cascade, used for inspecting and debugging the cascade files.
+// Stages: the number of cascade stages. Each tree of the face detection cascade is a stage with its own rejection threshold.
+// TreesPerStage: the number of trees evaluated on each stage.
+// Depth: the depth of each tree.
+// ScaleFactor: the multiplier applied to the region size after each stage of the localization cascades (1 for the face cascade).
+// Thresholds: the rejection threshold of each stage of the face detection cascade (empty for the localization cascades).
+// Predictions: the range of each value predicted by the tree leaves: the detection score for the face cascade,
+// the row and the column displacements for the pupil and the facial landmark points localization cascades.
+// Tests: the pixel tests of the tree nodes, tree by tree, in breadth-first order.
+type CascadeInfo struct {
+	Stages        int
+	TreesPerStage int
+	Depth         int
+	ScaleFactor   float32
+	Thresholds    []float32
+	Predictions   []Range
+	Tests         []PixelTest
+}
+
+// Info returns the statistics of the face detection cascade.
+func (pg *Pigo) Info() CascadeInfo {
+	var (
+		leaves = int(pow(2, int(pg.treeDepth)))
+		info   = CascadeInfo{
+			Stages:        int(pg.treeNum),
+			TreesPerStage: 1,
+			Depth:         int(pg.treeDepth),
+			ScaleFactor:   1,
+			Thresholds:    append([]float32(nil), pg.treeThreshold...),
+			Predictions:   []Range{predictionRange(pg.treePred, 1, 0)},
+			Tests:         make([]PixelTest, 0, int(pg.treeNum)*(leaves-1)),
+		}
+	)
+	// Each tree starts with four unused codes, so the nodes are indexed from 1.
+	for root := 0; root < int(pg.treeNum)*4*leaves; root += 4 * leaves {
+		for idx := 1; idx < leaves; idx++ {
+			info.Tests = append(info.Tests, pixelTest(pg.treeCodes[root+4*idx:]))
+		}
+	}
+	return info
+}
+
+// Info returns the statistics of the pupil or facial landmark point localization cascade.
+func (plc *PuplocCascade) Info() CascadeInfo {
+	var (
+		leaves = int(pow(2, int(plc.treeDepth)))
+		trees  = int(plc.stages) * int(plc.trees)
+		info   = CascadeInfo{
+			Stages:        int(plc.stages),
+			TreesPerStage: int(plc.trees),
+			Depth:         int(plc.treeDepth),
+			ScaleFactor:   plc.scales,
+			Predictions: []Range{
+				predictionRange(plc.treePreds, 2, 0),
+				predictionRange(plc.treePreds, 2, 1),
+			},
+			Tests: make([]PixelTest, 0, trees*(leaves-1)),
+		}
+	)
+	for root := 0; root < trees*(4*leaves-4); root += 4*leaves - 4 {
+		for idx := 0; idx < leaves-1; idx++ {
+			info.Tests = append(info.Tests, pixelTest(plc.treeCodes[root+4*idx:]))
+		}
+	}
+	return info
+}
+
+// Trees returns the total number of trees of the cascade.
+func (ci CascadeInfo) Trees() int {
+	return ci.Stages * ci.TreesPerStage
+}
+
+// Offsets returns the range of the row and the column pixel offsets compared by the tree nodes.
+func (ci CascadeInfo) Offsets() (rows, cols Range) {
+	if len(ci.Tests) == 0 {
+		return
+	}
+	rows = Range{math.MaxInt8, math.MinInt8}
+	cols = Range{math.MaxInt8, math.MinInt8}
+	for _, t := range ci.Tests {
+		rows = rows.extend(float32(t.Row1)).extend(float32(t.Row2))
+		cols = cols.extend(float32(t.Col1)).extend(float32(t.Col2))
+	}
+	return rows, cols
+}
+
+// Density counts how many times each pixel of a normalized size x size window is compared by the tree nodes.
+// The window covers the whole [-128, 127] offset interval, with the region center found in the middle of it.
+// The counts are returned in row-major order.
+func (ci CascadeInfo) Density(size int) []int {
+	density := make([]int, size*size)
+	cell := func(offset int8) int {
+		return (int(offset) + 128) * size / 256
+	}
+	for _, t := range ci.Tests {
+		density[cell(t.Row1)*size+cell(t.Col1)]++
+		density[cell(t.Row2)*size+cell(t.Col2)]++
+	}
+	return density
+}
+
+// Heatmap renders the density of the compared pixels (see Density) over a normalized size x size window.
+// The rarely compared pixels are drawn in dark red and the most compared ones in white, on a black background.
+func (ci CascadeInfo) Heatmap(size int) *image.NRGBA {
+	var (
+		img     = image.NewNRGBA(image.Rect(0, 0, size, size))
+		density = ci.Density(size)
+		peak    int
+	)
+	for _, d := range density {
+		peak = max(peak, d)
+	}
+	for i, d := range density {
+		var v float64
+		if peak > 0 {
+			// The square root brings out the rarely compared pixels next to the hot spots.
+			v = math.Sqrt(float64(d) / float64(peak))
+		}
+		img.SetNRGBA(i%size, i/size, heatColor(v))
+	}
+	return img
+}
+
+// heatColor maps a value of the [0, 1] interval to the black-red-yellow-white color scale.
+func heatColor(v float64) color.NRGBA {
+	channel := func(lo float64) uint8 {
+		return uint8(round(255 * clamp((v-lo)*3, 0, 1)))
+	}
+	return color.NRGBA{R: channel(0), G: channel(1.0 / 3), B: channel(2.0 / 3), A: 255}
+}
+
+// pixelTest returns the pixel test encoded by the first four codes.
+func pixelTest(codes []int8) PixelTest {
+	return PixelTest{Row1: codes[0], Col1: codes[1], Row2: codes[2], Col2: codes[3]}
+}
+
+// predictionRange returns the range of the values found at the offset of each group of n predictions.
+func predictionRange(preds []float32, n, offset int) Range {
+	if len(preds) <= offset {
+		return Range{}
+	}
+	r := Range{preds[offset], preds[offset]}
+	for i := offset; i < len(preds); i += n {
+		r = r.extend(preds[i])
+	}
+	return r
+}
+
+// extend returns the range extended to contain the value.
+func (r Range) extend(v float32) Range {
+	if v < r.Min {
+		r.Min = v
+	}
+	if v > r.Max {
+		r.Max = v
+	}
+	return r
+}
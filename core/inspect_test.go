@@ -0,0 +1,67 @@
+package pigo_test
+
+import (
+	"testing"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+func TestInspect_FaceCascadeInfo(t *testing.T) {
+	pg, err := pigo.NewPigo().Unpack(faceCasc)
+	if err != nil {
+		t.Fatalf("failed unpacking the cascade file: %v", err)
+	}
+	info := pg.Info()
+
+	if info.Stages == 0 || info.TreesPerStage != 1 || info.Depth == 0 {
+		t.Fatalf("unexpected cascade structure: %d stages, %d trees per stage, depth %d", info.Stages, info.TreesPerStage, info.Depth)
+	}
+	if len(info.Thresholds) != info.Trees() {
+		t.Errorf("expected a threshold for each of the %d trees, got %d", info.Trees(), len(info.Thresholds))
+	}
+	if len(info.Predictions) != 1 || info.Predictions[0].Min > info.Predictions[0].Max {
+		t.Errorf("invalid prediction ranges: %v", info.Predictions)
+	}
+	nodes := info.Trees() * (1<<info.Depth - 1)
+	if len(info.Tests) != nodes {
+		t.Fatalf("expected %d pixel tests, got %d", nodes, len(info.Tests))
+	}
+
+	density, total := info.Density(64), 0
+	for _, d := range density {
+		total += d
+	}
+	if total != 2*nodes {
+		t.Errorf("expected %d compared pixels, got %d", 2*nodes, total)
+	}
+	if b := info.Heatmap(64).Bounds(); b.Dx() != 64 || b.Dy() != 64 {
+		t.Errorf("unexpected heatmap size: %v", b)
+	}
+}
+
+func TestInspect_PuplocCascadeInfo(t *testing.T) {
+	for name, data := range map[string][]byte{"puploc": puplocCasc, "lp42": flpc} {
+		plc, err := pigo.NewPuplocCascade().UnpackCascade(data)
+		if err != nil {
+			t.Fatalf("%s: failed unpacking the cascade file: %v", name, err)
+		}
+		info := plc.Info()
+
+		if info.Stages == 0 || info.TreesPerStage == 0 || info.ScaleFactor <= 0 {
+			t.Fatalf("%s: unexpected cascade structure: %+v", name, info)
+		}
+		if len(info.Thresholds) != 0 {
+			t.Errorf("%s: the localization cascades should not have stage thresholds", name)
+		}
+		if len(info.Predictions) != 2 {
+			t.Fatalf("%s: expected the row and column prediction ranges, got %v", name, info.Predictions)
+		}
+		if got, want := len(info.Tests), info.Trees()*(1<<info.Depth-1); got != want {
+			t.Errorf("%s: expected %d pixel tests, got %d", name, want, got)
+		}
+		rows, cols := info.Offsets()
+		if rows.Min < -128 || rows.Max > 127 || cols.Min > cols.Max {
+			t.Errorf("%s: invalid offset ranges: %v %v", name, rows, cols)
+		}
+	}
+}
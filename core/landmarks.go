@@ -0,0 +1,73 @@
+package pigo
+
+// The names of the facial landmark points detected by the bundled cascades.
+// The left and right sides are meant from the viewer's perspective,
+// which means that the left eye is the one found on the left side of the image.
+const (
+	LeftEyebrowOuter    = "LeftEyebrowOuter"
+	RightEyebrowOuter   = "RightEyebrowOuter"
+	LeftEyebrowMiddle   = "LeftEyebrowMiddle"
+	RightEyebrowMiddle  = "RightEyebrowMiddle"
+	LeftEyebrowInner    = "LeftEyebrowInner"
+	RightEyebrowInner   = "RightEyebrowInner"
+	LeftEyeInnerCorner  = "LeftEyeInnerCorner"
+	RightEyeInnerCorner = "RightEyeInnerCorner"
+	LeftEyeOuterCorner  = "LeftEyeOuterCorner"
+	RightEyeOuterCorner = "RightEyeOuterCorner"
+	NoseTip             = "NoseTip"
+	MouthLeft           = "MouthLeft"
+	LowerLip            = "LowerLip"
+	UpperLip            = "UpperLip"
+	MouthRight          = "MouthRight"
+)
+
+// The names of the pupils, which key the pupils beside the landmark point names
+// wherever the face points are addressed by name (e.g. the overlay anchors or the COCO keypoints).
+const (
+	LeftPupil  = "LeftPupil"
+	RightPupil = "RightPupil"
+)
+
+// LandmarkSpec maps a facial landmark points cascade file and its flip state to a named landmark point.
+// Name: the semantic name of the landmark point.
+// Cascade: the name of the cascade file found in the cascade/lps directory.
+// FlipV: the cascade should be run with the column coordinates flipped (on the other side of the face).
+// AnchorX, AnchorY, AnchorZ: the mean position of the landmark point on a generic frontal face, relative to the midpoint
+// of the pupils and expressed in inter-pupil distance units. X points to the right, Y downwards and Z towards the camera.
+type LandmarkSpec struct {
+	Name    string
+	Cascade string
+	FlipV   bool
+	AnchorX float64
+	AnchorY float64
+	AnchorZ float64
+}
+
+// LandmarkManifest lists the landmark points provided by the bundled cascades in their canonical detection order.
+var LandmarkManifest = []LandmarkSpec{
+	{Name: LeftEyebrowOuter, Cascade: "lp46", FlipV: false, AnchorX: -0.9, AnchorY: -0.15, AnchorZ: -0.12},
+	{Name: RightEyebrowOuter, Cascade: "lp46", FlipV: true, AnchorX: 0.9, AnchorY: -0.15, AnchorZ: -0.12},
+	{Name: LeftEyebrowMiddle, Cascade: "lp44", FlipV: false, AnchorX: -0.55, AnchorY: -0.3, AnchorZ: 0.04},
+	{Name: RightEyebrowMiddle, Cascade: "lp44", FlipV: true, AnchorX: 0.55, AnchorY: -0.3, AnchorZ: 0.04},
+	{Name: LeftEyebrowInner, Cascade: "lp42", FlipV: false, AnchorX: -0.22, AnchorY: -0.2, AnchorZ: 0.08},
+	{Name: RightEyebrowInner, Cascade: "lp42", FlipV: true, AnchorX: 0.22, AnchorY: -0.2, AnchorZ: 0.08},
+	{Name: LeftEyeInnerCorner, Cascade: "lp38", FlipV: false, AnchorX: -0.3, AnchorY: 0.02, AnchorZ: -0.03},
+	{Name: RightEyeInnerCorner, Cascade: "lp38", FlipV: true, AnchorX: 0.3, AnchorY: 0.02, AnchorZ: -0.03},
+	{Name: LeftEyeOuterCorner, Cascade: "lp312", FlipV: false, AnchorX: -0.68, AnchorY: 0.02, AnchorZ: -0.12},
+	{Name: RightEyeOuterCorner, Cascade: "lp312", FlipV: true, AnchorX: 0.68, AnchorY: 0.02, AnchorZ: -0.12},
+	{Name: NoseTip, Cascade: "lp93", FlipV: false, AnchorX: 0, AnchorY: 0.6, AnchorZ: 0.35},
+	{Name: MouthLeft, Cascade: "lp84", FlipV: false, AnchorX: -0.4, AnchorY: 1.08, AnchorZ: -0.05},
+	{Name: LowerLip, Cascade: "lp82", FlipV: false, AnchorX: 0, AnchorY: 1.22, AnchorZ: 0.08},
+	{Name: UpperLip, Cascade: "lp81", FlipV: false, AnchorX: 0, AnchorY: 1.0, AnchorZ: 0.12},
+	{Name: MouthRight, Cascade: "lp84", FlipV: true, AnchorX: 0.4, AnchorY: 1.08, AnchorZ: -0.05},
+}
+
+// LookupLandmark returns the manifest entry of the landmark point with the provided name.
+func LookupLandmark(name string) (LandmarkSpec, bool) {
+	for _, spec := range LandmarkManifest {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return LandmarkSpec{}, false
+}
@@ -2,6 +2,7 @@ package pigo
 
 import (
 	"encoding/binary"
+	"errors"
 	"math"
 	"sort"
 	"sync"
@@ -42,6 +43,9 @@ type Pigo struct {
 	treeNum       uint32
 }
 
+// errInvalidCascade is returned when the binary data does not match the cascade file format.
+var errInvalidCascade = errors.New("invalid or truncated cascade file")
+
 // NewPigo initializes the Pigo constructor method.
 func NewPigo() *Pigo {
 	return &Pigo{}
@@ -57,6 +61,10 @@ func (pg *Pigo) Unpack(packet []byte) (*Pigo, error) {
 		treeThreshold []float32
 	)
 
+	if len(packet) < 16 {
+		return nil, errInvalidCascade
+	}
+
 	// We skip the first 8 bytes of the cascade file.
 	pos := 8
 
@@ -67,6 +75,12 @@ func (pg *Pigo) Unpack(packet []byte) (*Pigo, error) {
 	// Get the number of cascade trees as 32-bit unsigned integer.
 	treeNum = binary.LittleEndian.Uint32(packet[pos:])
 
+	// Make sure that the binary data contains all the trees described by the header.
+	depth := pow(2, int(treeDepth))
+	if treeDepth > 16 || float64(len(packet)-pos-4) < float64(treeNum)*(4*depth-4+4*depth+4) {
+		return nil, errInvalidCascade
+	}
+
 	// To avoid constant memory allocation on each append we predefine the slice capacity.
 	treeThreshold = make([]float32, 0, treeNum)
 	treeCodes = make([]int8, 0, 119808)
@@ -265,17 +279,6 @@ func (pg *Pigo) ClusterDetections(detections []Detection, iouThreshold float64)
 		return detections[i].Q < detections[j].Q
 	})
 
-	calcIoU := func(det1, det2 Detection) float64 {
-		// Unpack the position and size of each detection.
-		r1, c1, s1 := float64(det1.Row), float64(det1.Col), float64(det1.Scale)
-		r2, c2, s2 := float64(det2.Row), float64(det2.Col), float64(det2.Scale)
-
-		overRow := math.Max(0, math.Min(r1+s1/2, r2+s2/2)-math.Max(r1-s1/2, r2-s2/2))
-		overCol := math.Max(0, math.Min(c1+s1/2, c2+s2/2)-math.Max(c1-s1/2, c2-s2/2))
-
-		// Return intersection over union.
-		return overRow * overCol / (s1*s1 + s2*s2 - overRow*overCol)
-	}
 	assignments := make([]bool, len(detections))
 	clusters := []Detection{}
 
@@ -306,3 +309,16 @@ func (pg *Pigo) ClusterDetections(detections []Detection, iouThreshold float64)
 	}
 	return clusters
 }
+
+// calcIoU returns the intersection over union of two detections.
+func calcIoU(det1, det2 Detection) float64 {
+	// Unpack the position and size of each detection.
+	r1, c1, s1 := float64(det1.Row), float64(det1.Col), float64(det1.Scale)
+	r2, c2, s2 := float64(det2.Row), float64(det2.Col), float64(det2.Scale)
+
+	overRow := math.Max(0, math.Min(r1+s1/2, r2+s2/2)-math.Max(r1-s1/2, r2-s2/2))
+	overCol := math.Max(0, math.Min(c1+s1/2, c2+s2/2)-math.Max(c1-s1/2, c2-s2/2))
+
+	// Return intersection over union.
+	return overRow * overCol / (s1*s1 + s2*s2 - overRow*overCol)
+}
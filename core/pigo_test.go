@@ -1,6 +1,7 @@
 package pigo_test
 
 import (
+	"errors"
 	"image"
 	"io/ioutil"
 	"log"
@@ -57,6 +58,31 @@ func TestPigo_UnpackCascadeFileShouldNotBeNil(t *testing.T) {
 	}
 }
 
+func TestPigo_UnpackShouldRejectMalformedCascades(t *testing.T) {
+	for name, data := range malformedCascades(faceCasc) {
+		if _, err := pigo.NewPigo().Unpack(data); !errors.Is(err, pigo.ErrInvalidCascade) {
+			t.Errorf("%s: expected an invalid cascade error, got: %v", name, err)
+		}
+	}
+}
+
+// malformedCascades returns the truncated and the garbage variants of the cascade file.
+func malformedCascades(cascade []byte) map[string][]byte {
+	header := make([]byte, 16)
+	for i := range header {
+		header[i] = 0xff
+	}
+	return map[string][]byte{
+		"empty":           nil,
+		"short header":    cascade[:10],
+		"header only":     cascade[:16],
+		"truncated trees": cascade[:len(cascade)/2],
+		"missing byte":    cascade[:len(cascade)-1],
+		"garbage header":  append(header, cascade[16:]...),
+		"text":            []byte("this is not a cascade file, but a plain text file"),
+	}
+}
+
 func TestPigo_InputImageShouldBeGrayscale(t *testing.T) {
 	// Since an image converted grayscale has only one channel,we should assume
 	// that the grayscale image array length is the source image length / 4.
@@ -0,0 +1,195 @@
+package pigo
+
+import "math"
+
+// HeadPose holds the coarse head orientation of a face, expressed in degrees.
+// Yaw: the rotation around the vertical axis, positive when the face turns towards the right side of the image.
+// Pitch: the rotation around the horizontal axis, positive when the face looks up.
+// Roll: the in-plane rotation, positive when the face is tilted clockwise.
+// Fit: the fit quality, 1 for a perfect fit and 0 when the mean residual reaches a quarter of the inter-pupil distance.
+// Scale: the size of the inter-pupil distance of the template in pixels.
+type HeadPose struct {
+	Yaw   float64
+	Pitch float64
+	Roll  float64
+	Fit   float64
+	Scale float64
+}
+
+// minPosePoints is the minimum number of points needed for fitting the head pose.
+const minPosePoints = 5
+
+// EstimateHeadPose fits the pupils and the facial landmark points of the face to a generic 3D face template
+// (see LandmarkSpec) under a scaled orthographic projection and returns the head orientation.
+// It returns nil if the pupils, the nose tip or enough landmark points are missing.
+func EstimateHeadPose(face Face) *HeadPose {
+	if face.LeftEye == nil || face.RightEye == nil {
+		return nil
+	}
+	if _, ok := face.Landmark(NoseTip); !ok {
+		return nil
+	}
+
+	var img [][2]float64
+	var model [][3]float64
+
+	// The template Z axis points towards the camera, but the camera coordinate system
+	// with X pointing to the right and Y downwards has its Z axis pointing away from the camera.
+	add := func(p Puploc, x, y, z float64) {
+		img = append(img, [2]float64{float64(p.Col), float64(p.Row)})
+		model = append(model, [3]float64{x, y, -z})
+	}
+	add(*face.LeftEye, -0.5, 0, 0)
+	add(*face.RightEye, 0.5, 0, 0)
+	for _, lp := range face.Landmarks {
+		if spec, ok := LookupLandmark(lp.Name); ok {
+			add(lp.Puploc, spec.AnchorX, spec.AnchorY, spec.AnchorZ)
+		}
+	}
+	if len(img) < minPosePoints {
+		return nil
+	}
+
+	// Center both point sets.
+	n := float64(len(img))
+	var ic [2]float64
+	var mc [3]float64
+	for i := range img {
+		for j := 0; j < 2; j++ {
+			ic[j] += img[i][j] / n
+		}
+		for j := 0; j < 3; j++ {
+			mc[j] += model[i][j] / n
+		}
+	}
+
+	// Solve the least squares affine projection M = (P^T Q) (Q^T Q)^-1.
+	var qtq [3][3]float64
+	var ptq [2][3]float64
+	for i := range img {
+		for r := 0; r < 3; r++ {
+			q := model[i][r] - mc[r]
+			for c := 0; c < 3; c++ {
+				qtq[r][c] += q * (model[i][c] - mc[c])
+			}
+			for c := 0; c < 2; c++ {
+				ptq[c][r] += (img[i][c] - ic[c]) * q
+			}
+		}
+	}
+	inv, ok := invert3(qtq)
+	if !ok {
+		return nil
+	}
+	var m [2][3]float64
+	for r := 0; r < 2; r++ {
+		for c := 0; c < 3; c++ {
+			for k := 0; k < 3; k++ {
+				m[r][c] += ptq[r][k] * inv[k][c]
+			}
+		}
+	}
+
+	// Extract the scale and the closest rotation matrix from the affine projection.
+	r1, r2 := m[0], m[1]
+	n1, n2 := norm3(r1), norm3(r2)
+	if n1 == 0 || n2 == 0 {
+		return nil
+	}
+	scale := (n1 + n2) / 2
+	r1 = scale3(r1, 1/n1)
+	r2 = sub3(r2, scale3(r1, dot3(r1, r2)))
+	if n := norm3(r2); n > 0 {
+		r2 = scale3(r2, 1/n)
+	} else {
+		return nil
+	}
+	r3 := cross3(r1, r2)
+
+	// Measure the residual of the fit in inter-pupil distance units.
+	var sse float64
+	for i := range img {
+		q := sub3(model[i], mc)
+		du := ic[0] + scale*dot3(r1, q) - img[i][0]
+		dv := ic[1] + scale*dot3(r2, q) - img[i][1]
+		sse += du*du + dv*dv
+	}
+	rms := math.Sqrt(sse/n) / scale
+
+	// Decompose the rotation matrix as R = Rz(roll) * Ry(yaw) * Rx(pitch).
+	beta := math.Asin(clamp(-r3[0], -1, 1))
+	alpha := math.Atan2(r3[1], r3[2])
+	gamma := math.Atan2(r2[0], r1[0])
+
+	return &HeadPose{
+		Yaw:   -beta * 180 / math.Pi,
+		Pitch: -alpha * 180 / math.Pi,
+		Roll:  gamma * 180 / math.Pi,
+		Fit:   clamp(1-rms/0.25, 0, 1),
+		Scale: scale,
+	}
+}
+
+// Axes returns the directions of the head axes projected on the image, with a length of one inter-pupil distance.
+// x points towards the right side of the face (as seen in the image), y downwards and z out of the face, towards the nose.
+func (hp HeadPose) Axes() (x, y, z [2]float64) {
+	toRad := math.Pi / 180
+	sa, ca := math.Sincos(-hp.Pitch * toRad)
+	sb, cb := math.Sincos(-hp.Yaw * toRad)
+	sg, cg := math.Sincos(hp.Roll * toRad)
+
+	// The first two rows of Rz(roll) * Ry(yaw) * Rx(pitch).
+	r1 := [3]float64{cg * cb, cg*sb*sa - sg*ca, cg*sb*ca + sg*sa}
+	r2 := [3]float64{sg * cb, sg*sb*sa + cg*ca, sg*sb*ca - cg*sa}
+
+	x = [2]float64{hp.Scale * r1[0], hp.Scale * r2[0]}
+	y = [2]float64{hp.Scale * r1[1], hp.Scale * r2[1]}
+	z = [2]float64{-hp.Scale * r1[2], -hp.Scale * r2[2]}
+	return
+}
+
+// invert3 returns the inverse of a 3x3 matrix, or false if the matrix is (close to) singular.
+func invert3(a [3][3]float64) ([3][3]float64, bool) {
+	var inv [3][3]float64
+	det := a[0][0]*(a[1][1]*a[2][2]-a[1][2]*a[2][1]) -
+		a[0][1]*(a[1][0]*a[2][2]-a[1][2]*a[2][0]) +
+		a[0][2]*(a[1][0]*a[2][1]-a[1][1]*a[2][0])
+
+	trace := a[0][0] + a[1][1] + a[2][2]
+	if math.Abs(det) <= 1e-9*trace*trace*trace {
+		return inv, false
+	}
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			// The cofactors of the transposed matrix.
+			r1, r2 := (c+1)%3, (c+2)%3
+			c1, c2 := (r+1)%3, (r+2)%3
+			inv[r][c] = (a[r1][c1]*a[r2][c2] - a[r1][c2]*a[r2][c1]) / det
+		}
+	}
+	return inv, true
+}
+
+func dot3(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func norm3(a [3]float64) float64 {
+	return math.Sqrt(dot3(a, a))
+}
+
+func scale3(a [3]float64, s float64) [3]float64 {
+	return [3]float64{a[0] * s, a[1] * s, a[2] * s}
+}
+
+func sub3(a, b [3]float64) [3]float64 {
+	return [3]float64{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func cross3(a, b [3]float64) [3]float64 {
+	return [3]float64{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
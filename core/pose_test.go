@@ -0,0 +1,95 @@
+package pigo_test
+
+import (
+	"math"
+	"testing"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// projectTemplate renders the face template rotated by the provided angles (in degrees)
+// under a scaled orthographic projection centered on (400, 400).
+func projectTemplate(yaw, pitch, roll, scale float64) pigo.Face {
+	hp := pigo.HeadPose{Yaw: yaw, Pitch: pitch, Roll: roll, Scale: scale}
+	x, y, z := hp.Axes()
+
+	project := func(ax, ay, az float64) pigo.Puploc {
+		return pigo.Puploc{
+			Col: int(math.Round(400 + ax*x[0] + ay*y[0] + az*z[0])),
+			Row: int(math.Round(400 + ax*x[1] + ay*y[1] + az*z[1])),
+		}
+	}
+	left, right := project(-0.5, 0, 0), project(0.5, 0, 0)
+	face := pigo.Face{LeftEye: &left, RightEye: &right}
+	for _, spec := range pigo.LandmarkManifest {
+		face.Landmarks = append(face.Landmarks, pigo.Landmark{
+			Name:   spec.Name,
+			Puploc: project(spec.AnchorX, spec.AnchorY, spec.AnchorZ),
+		})
+	}
+	return face
+}
+
+func TestHeadPose_ShouldRecoverTheTemplateRotation(t *testing.T) {
+	testCases := []struct {
+		yaw, pitch, roll float64
+	}{
+		{0, 0, 0},
+		{25, 0, 0},
+		{-25, 0, 0},
+		{0, 20, 0},
+		{0, -20, 0},
+		{0, 0, 15},
+		{20, -10, -12},
+	}
+	for _, tc := range testCases {
+		hp := pigo.EstimateHeadPose(projectTemplate(tc.yaw, tc.pitch, tc.roll, 200))
+		if hp == nil {
+			t.Fatalf("expected the head pose to be estimated for %+v", tc)
+		}
+		if math.Abs(hp.Yaw-tc.yaw) > 2 || math.Abs(hp.Pitch-tc.pitch) > 2 || math.Abs(hp.Roll-tc.roll) > 2 {
+			t.Errorf("expected yaw: %v, pitch: %v, roll: %v, got: %+v", tc.yaw, tc.pitch, tc.roll, hp)
+		}
+		if hp.Fit < 0.95 || math.Abs(hp.Scale-200) > 2 {
+			t.Errorf("expected a close fit at the template scale, got: %+v", hp)
+		}
+	}
+}
+
+func TestHeadPose_AxesShouldFollowTheDocumentedDirections(t *testing.T) {
+	nose := func(face pigo.Face) pigo.Landmark {
+		lp, _ := face.Landmark(pigo.NoseTip)
+		return lp
+	}
+	frontal := nose(projectTemplate(0, 0, 0, 100))
+
+	if lp := nose(projectTemplate(20, 0, 0, 100)); lp.Col <= frontal.Col {
+		t.Errorf("expected the nose to move right on positive yaw")
+	}
+	if lp := nose(projectTemplate(0, 20, 0, 100)); lp.Row >= frontal.Row {
+		t.Errorf("expected the nose to move up on positive pitch")
+	}
+	if face := projectTemplate(0, 0, 15, 100); face.RightEye.Row <= face.LeftEye.Row {
+		t.Errorf("expected the right eye to move down on positive roll")
+	}
+}
+
+func TestHeadPose_ShouldReportTheFitQuality(t *testing.T) {
+	face := projectTemplate(0, 0, 0, 100)
+	// Move the mouth points far off the template.
+	for i, lp := range face.Landmarks {
+		switch lp.Name {
+		case pigo.MouthLeft, pigo.MouthRight, pigo.LowerLip, pigo.UpperLip:
+			face.Landmarks[i].Row += 40
+			face.Landmarks[i].Col += 30
+		}
+	}
+	if hp := pigo.EstimateHeadPose(face); hp == nil || hp.Fit > 0.8 {
+		t.Errorf("expected a poor fit quality, got: %+v", hp)
+	}
+
+	face.Landmarks = face.Landmarks[:2]
+	if hp := pigo.EstimateHeadPose(face); hp != nil {
+		t.Errorf("expected no head pose without the nose tip, got: %+v", hp)
+	}
+}
@@ -11,11 +11,27 @@ import (
 
 // Puploc contains all the information resulted from the pupil detection
 // needed for accessing from a global scope.
+// Perturbs defines how many randomly perturbed runs are used for computing the median result.
+// Any positive value is accepted: higher values give more stable results on high resolution images,
+// but the detection time grows linearly with the number of perturbations.
+// Spread is the dispersion in pixels of the perturbed results around the returned point, set by the detector.
+// A low spread means that the perturbed runs agree on the point location.
 type Puploc struct {
 	Row      int
 	Col      int
 	Scale    float32
 	Perturbs int
+	Spread   float32
+}
+
+// Confidence returns how reliable the localized point is, between 0 and 1, derived from the spread of the
+// perturbed results relative to the point scale. It's 1 when all the perturbed runs agree on the location
+// and it drops to 0 when the spread reaches a tenth of the scale.
+func (pl Puploc) Confidence() float64 {
+	if pl.Scale <= 0 {
+		return 0
+	}
+	return clamp(1-float64(pl.Spread)/(0.1*float64(pl.Scale)), 0, 1)
 }
 
 // PuplocCascade is a general struct for storing
@@ -46,6 +62,10 @@ func (plc *PuplocCascade) UnpackCascade(packet []byte) (*PuplocCascade, error) {
 		treePreds = make([]float32, 0, 204800)
 	)
 
+	if len(packet) < 16 {
+		return nil, errInvalidCascade
+	}
+
 	pos := 0
 	// Get the number of stages as 32-bit unsigned integer.
 	stages = binary.LittleEndian.Uint32(packet[pos:])
@@ -65,6 +85,12 @@ func (plc *PuplocCascade) UnpackCascade(packet []byte) (*PuplocCascade, error) {
 	treeDepth = binary.LittleEndian.Uint32(packet[pos:])
 	pos += 4
 
+	// Make sure that the binary data contains all the trees described by the header.
+	depth := pow(2, int(treeDepth))
+	if treeDepth > 16 || float64(len(packet)-pos) < float64(stages)*float64(trees)*(4*depth-4+8*depth) {
+		return nil, errInvalidCascade
+	}
+
 	// Traverse all the stages of the binary tree.
 	for s := 0; s < int(stages); s++ {
 		// Traverse the branches of each stage.
@@ -223,28 +249,51 @@ type puplocPool struct {
 	scale []float32
 }
 
+// defaultPerturbs is the number of perturbations the pooled buffers are preallocated for.
+const defaultPerturbs = 63
+
 // Create a sync.Pool for further reusing the allocated memory space
 // in order to keep the GC overhead as low as possible.
 var plcPool = sync.Pool{
 	New: func() interface{} {
 		return &puplocPool{
-			rows:  make([]float32, 63),
-			cols:  make([]float32, 63),
-			scale: make([]float32, 63),
+			rows:  make([]float32, 0, defaultPerturbs),
+			cols:  make([]float32, 0, defaultPerturbs),
+			scale: make([]float32, 0, defaultPerturbs),
 		}
 	},
 }
 
+// reset truncates the pooled buffers to the requested length,
+// growing them only when the perturbation count exceeds the current capacity.
+func (p *puplocPool) reset(n int) {
+	if cap(p.rows) < n {
+		p.rows = make([]float32, n)
+		p.cols = make([]float32, n)
+		p.scale = make([]float32, n)
+		return
+	}
+	p.rows = p.rows[:n]
+	p.cols = p.cols[:n]
+	p.scale = p.scale[:n]
+}
+
 // RunDetector runs the pupil localization function.
+// The detector is run Perturbs times over randomly shifted and scaled windows
+// and the median of the results is returned. Increasing the number of perturbations
+// reduces the jitter of the localized points at the cost of a linearly increasing run time.
 func (plc *PuplocCascade) RunDetector(pl Puploc, img ImageParams, angle float64, flipV bool) *Puploc {
 	var res []float32
 
 	det := plcPool.Get().(*puplocPool)
 	defer plcPool.Put(det)
 
+	perturbs := max(pl.Perturbs, 1)
+	det.reset(perturbs)
+
 	treeDepth := int(pow(2, int(plc.treeDepth)))
 
-	for i := 0; i < pl.Perturbs; i++ {
+	for i := 0; i < perturbs; i++ {
 		row := float32(pl.Row) + float32(pl.Scale)*0.15*(0.5-rand.Float32())
 		col := float32(pl.Col) + float32(pl.Scale)*0.15*(0.5-rand.Float32())
 		sc := float32(pl.Scale) * (0.925 + 0.15*rand.Float32())
@@ -263,17 +312,36 @@ func (plc *PuplocCascade) RunDetector(pl Puploc, img ImageParams, angle float64,
 		det.scale[i] = res[2]
 	}
 
-	// Sorting the perturbations in ascendent order
-	sort.Sort(plocSort(det.rows))
-	sort.Sort(plocSort(det.cols))
-	sort.Sort(plocSort(det.scale))
-
 	// Get the median value of the sorted perturbation results
+	row, col := median(det.rows), median(det.cols)
+
+	// The spread combines the median absolute deviations of the rows and the columns.
+	// The buffers are reused for storing the deviations, since they are no longer needed.
+	for i := range det.rows {
+		det.rows[i] = abs32(det.rows[i] - row)
+		det.cols[i] = abs32(det.cols[i] - col)
+	}
+	spread := math.Hypot(float64(median(det.rows)), float64(median(det.cols)))
+
 	return &Puploc{
-		Row:   int(det.rows[int(math.Round(float64(pl.Perturbs)/2))]),
-		Col:   int(det.cols[int(math.Round(float64(pl.Perturbs)/2))]),
-		Scale: det.scale[int(math.Round(float64(pl.Perturbs)/2))],
+		Row:      int(row),
+		Col:      int(col),
+		Scale:    median(det.scale),
+		Perturbs: perturbs,
+		Spread:   float32(spread),
+	}
+}
+
+// median sorts the values in ascending order and returns their median.
+// For an even number of values the mean of the two middle elements is returned.
+func median(vals []float32) float32 {
+	sort.Sort(plocSort(vals))
+
+	n := len(vals)
+	if n%2 == 0 {
+		return (vals[n/2-1] + vals[n/2]) / 2
 	}
+	return vals[n/2]
 }
 
 // Implement custom sorting function on detection values.
@@ -1,8 +1,11 @@
 package pigo_test
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
 	"runtime"
 	"testing"
 
@@ -31,6 +34,14 @@ func TestPuploc_UnpackCascadeFileShouldNotBeNil(t *testing.T) {
 	}
 }
 
+func TestPuploc_UnpackShouldRejectMalformedCascades(t *testing.T) {
+	for name, data := range malformedCascades(puplocCasc) {
+		if _, err := pigo.NewPuplocCascade().UnpackCascade(data); !errors.Is(err, pigo.ErrInvalidCascade) {
+			t.Errorf("%s: expected an invalid cascade error, got: %v", name, err)
+		}
+	}
+}
+
 func TestPuploc_Detector_ShouldDetectEyes(t *testing.T) {
 	// Unpack the facefinder binary cascade file. This will return the number of cascade trees,
 	// the tree depth, the threshold and the prediction from tree's leaf nodes.
@@ -160,3 +171,120 @@ func BenchmarkPuplocDetection(b *testing.B) {
 	}
 	_ = dets
 }
+
+func TestPuploc_Detector_ShouldSupportAnyPerturbationCount(t *testing.T) {
+	plc, err := pl.UnpackCascade(puplocCasc)
+	if err != nil {
+		t.Fatalf("failed unpacking the cascade file: %v", err)
+	}
+
+	for _, perturbs := range []int{0, 1, 2, 62, 63, 64, 128, 255} {
+		puploc := &pigo.Puploc{Row: 100, Col: 100, Scale: 40, Perturbs: perturbs}
+		res := plc.RunDetector(*puploc, *imgParams, 0.0, false)
+		if res.Row < 0 || res.Row >= imgParams.Rows || res.Col < 0 || res.Col >= imgParams.Cols {
+			t.Fatalf("perturbs %d: pupil should be located inside the image, got: %+v", perturbs, res)
+		}
+	}
+}
+
+func TestPuploc_Median(t *testing.T) {
+	for _, tc := range []struct {
+		vals []float32
+		want float32
+	}{
+		{[]float32{5}, 5},
+		{[]float32{3, 1, 2}, 2},
+		{[]float32{9, 1, 7, 3, 5}, 5},
+		{[]float32{4, 2}, 3},
+		{[]float32{8, 1, 6, 2}, 4},
+		{[]float32{1, 1, 2, 10, 10, 10}, 6},
+	} {
+		if got := pigo.Median(append([]float32(nil), tc.vals...)); got != tc.want {
+			t.Errorf("%v: expected median %.1f, got: %.1f", tc.vals, tc.want, got)
+		}
+	}
+}
+
+func TestPuploc_Confidence(t *testing.T) {
+	for _, tc := range []struct {
+		pl   pigo.Puploc
+		want float64
+	}{
+		{pigo.Puploc{Scale: 20, Spread: 0}, 1},
+		{pigo.Puploc{Scale: 20, Spread: 1}, 0.5},
+		{pigo.Puploc{Scale: 20, Spread: 5}, 0},
+		{pigo.Puploc{Scale: 0, Spread: 0}, 0},
+	} {
+		if got := tc.pl.Confidence(); math.Abs(got-tc.want) > 1e-6 {
+			t.Errorf("%+v: expected confidence %.2f, got: %.2f", tc.pl, tc.want, got)
+		}
+	}
+
+	plc, err := pl.UnpackCascade(puplocCasc)
+	if err != nil {
+		t.Fatalf("failed unpacking the cascade file: %v", err)
+	}
+	// A random noise image gives no clue about the pupil location, so the perturbed runs should disagree.
+	pixels := make([]uint8, 200*200)
+	for i := range pixels {
+		pixels[i] = uint8((i*7919 + i/200*104729) % 251)
+	}
+	noise := pigo.ImageParams{Pixels: pixels, Rows: 200, Cols: 200, Dim: 200}
+	res := plc.RunDetector(pigo.Puploc{Row: 100, Col: 100, Scale: 20, Perturbs: 63}, noise, 0.0, false)
+	if res.Spread <= 0 || res.Confidence() > 0.5 {
+		t.Errorf("expected a low confidence on a noise image, got spread: %.2f, confidence: %.2f", res.Spread, res.Confidence())
+	}
+}
+
+// BenchmarkPuplocPerturbs shows the trade-off between the number of perturbations and the detection time.
+// The reported px/jitter metric is the mean distance of the localized pupils from their average position
+// over repeated runs: the lower it is, the more stable (accurate) the result.
+func BenchmarkPuplocPerturbs(b *testing.B) {
+	classifier, err := p.Unpack(faceCasc)
+	if err != nil {
+		b.Fatalf("error reading the cascade file: %s", err)
+	}
+	plc, err := pl.UnpackCascade(puplocCasc)
+	if err != nil {
+		b.Fatalf("error reading the cascade file: %s", err)
+	}
+
+	dets := classifier.ClusterDetections(classifier.RunCascade(*cParams, 0.0), 0.1)
+	if len(dets) == 0 {
+		b.Fatal("no face detected")
+	}
+	det := dets[len(dets)-1]
+
+	for _, perturbs := range []int{15, 31, 63, 127, 255} {
+		b.Run(fmt.Sprintf("perturbs=%d", perturbs), func(b *testing.B) {
+			puploc := &pigo.Puploc{
+				Row:      det.Row - int(0.075*float32(det.Scale)),
+				Col:      det.Col - int(0.175*float32(det.Scale)),
+				Scale:    float32(det.Scale) * 0.25,
+				Perturbs: perturbs,
+			}
+			rows := make([]float64, 0, b.N)
+			cols := make([]float64, 0, b.N)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				res := plc.RunDetector(*puploc, *imgParams, 0.0, false)
+				rows = append(rows, float64(res.Row))
+				cols = append(cols, float64(res.Col))
+			}
+			b.StopTimer()
+
+			var mr, mc, jitter float64
+			for i := range rows {
+				mr += rows[i]
+				mc += cols[i]
+			}
+			mr /= float64(len(rows))
+			mc /= float64(len(cols))
+			for i := range rows {
+				jitter += math.Hypot(rows[i]-mr, cols[i]-mc)
+			}
+			b.ReportMetric(jitter/float64(len(rows)), "px/jitter")
+		})
+	}
+}
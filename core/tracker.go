@@ -0,0 +1,118 @@
+package pigo
+
+import "sort"
+
+// Track is a face followed across successive frames under a stable identifier.
+// ID: the identifier of the track, unique within the tracker.
+// Face: the face matched to the track on the last frame.
+// Age: the number of frames since the track was started.
+type Track struct {
+	ID   int
+	Face Face
+	Age  int
+}
+
+// Tracker assigns stable identifiers to the faces detected over successive frames
+// by greedily matching them to the tracks of the previous frames on their intersection over union.
+// IoUThreshold: the minimum intersection over union of a face and a track for being matched.
+// MaxMissed: the number of successive frames a track is kept alive without a matching face.
+type Tracker struct {
+	IoUThreshold float64
+	MaxMissed    int
+
+	nextID int
+	tracks []*tracked
+}
+
+// tracked holds the internal state of a track.
+type tracked struct {
+	Track
+	missed int
+}
+
+// NewTracker initializes the Tracker constructor method.
+func NewTracker() *Tracker {
+	return &Tracker{
+		IoUThreshold: 0.3,
+		MaxMissed:    5,
+		nextID:       1,
+	}
+}
+
+// Update matches the faces detected on the current frame to the existing tracks
+// and returns the tracks of the provided faces, in the same order.
+// The faces without a matching track start a new one.
+// The tracks left without a matching face for more than MaxMissed frames are dropped.
+func (t *Tracker) Update(faces []Face) []Track {
+	type pair struct {
+		face, track int
+		iou         float64
+	}
+	var pairs []pair
+	for i, face := range faces {
+		for j, tr := range t.tracks {
+			if iou := calcIoU(face.Detection, tr.Face.Detection); iou >= t.IoUThreshold {
+				pairs = append(pairs, pair{i, j, iou})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].iou > pairs[j].iou
+	})
+
+	var (
+		result        = make([]Track, len(faces))
+		matchedFaces  = make([]bool, len(faces))
+		matchedTracks = make([]bool, len(t.tracks))
+	)
+	for _, p := range pairs {
+		if matchedFaces[p.face] || matchedTracks[p.track] {
+			continue
+		}
+		matchedFaces[p.face] = true
+		matchedTracks[p.track] = true
+
+		tr := t.tracks[p.track]
+		tr.Face = faces[p.face]
+		tr.Age++
+		tr.missed = 0
+		result[p.face] = tr.Track
+	}
+
+	alive := t.tracks[:0]
+	for i, tr := range t.tracks {
+		if !matchedTracks[i] {
+			tr.missed++
+			if tr.missed > t.MaxMissed {
+				continue
+			}
+		}
+		alive = append(alive, tr)
+	}
+	t.tracks = alive
+
+	for i, face := range faces {
+		if matchedFaces[i] {
+			continue
+		}
+		tr := &tracked{Track: Track{ID: t.nextID, Face: face, Age: 1}}
+		t.nextID++
+		t.tracks = append(t.tracks, tr)
+		result[i] = tr.Track
+	}
+	return result
+}
+
+// Active returns the identifiers of the tracks which are still kept alive.
+func (t *Tracker) Active() []int {
+	ids := make([]int, 0, len(t.tracks))
+	for _, tr := range t.tracks {
+		ids = append(ids, tr.ID)
+	}
+	return ids
+}
+
+// Reset drops all the tracks. The identifiers are not reused.
+func (t *Tracker) Reset() {
+	t.tracks = nil
+}
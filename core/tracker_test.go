@@ -0,0 +1,55 @@
+package pigo_test
+
+import (
+	"testing"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+func faceAt(row, col, scale int) pigo.Face {
+	return pigo.Face{Detection: pigo.Detection{Row: row, Col: col, Scale: scale}}
+}
+
+func TestTracker_ShouldKeepTheIdentifiersOfMovingFaces(t *testing.T) {
+	tr := pigo.NewTracker()
+
+	first := tr.Update([]pigo.Face{faceAt(100, 100, 80), faceAt(100, 300, 80)})
+	if first[0].ID == first[1].ID {
+		t.Fatalf("expected distinct track identifiers, got: %v", first)
+	}
+
+	// The faces move slightly and are returned in reverse order.
+	second := tr.Update([]pigo.Face{faceAt(105, 310, 80), faceAt(104, 108, 82)})
+	if second[0].ID != first[1].ID || second[1].ID != first[0].ID {
+		t.Errorf("expected the faces to keep their identifiers, got: %v, then: %v", first, second)
+	}
+	if second[0].Age != 2 || second[0].Face.Detection.Col != 310 {
+		t.Errorf("expected the track to be updated with the matched face, got: %+v", second[0])
+	}
+}
+
+func TestTracker_ShouldDropTracksAfterMaxMissedFrames(t *testing.T) {
+	tr := pigo.NewTracker()
+	tr.MaxMissed = 2
+
+	id := tr.Update([]pigo.Face{faceAt(100, 100, 80)})[0].ID
+	for i := 0; i < 2; i++ {
+		tr.Update(nil)
+	}
+	if active := tr.Active(); len(active) != 1 || active[0] != id {
+		t.Fatalf("expected the track to be kept alive, got: %v", active)
+	}
+	if got := tr.Update([]pigo.Face{faceAt(100, 100, 80)})[0].ID; got != id {
+		t.Errorf("expected the face to resume its track %d, got: %d", id, got)
+	}
+
+	for i := 0; i < 3; i++ {
+		tr.Update(nil)
+	}
+	if active := tr.Active(); len(active) != 0 {
+		t.Errorf("expected the track to be dropped, got: %v", active)
+	}
+	if got := tr.Update([]pigo.Face{faceAt(100, 100, 80)})[0].ID; got == id {
+		t.Errorf("expected a new track identifier after the track was dropped")
+	}
+}
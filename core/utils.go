@@ -12,6 +12,14 @@ func abs(x int) int {
 	return x
 }
 
+// abs32 returns the absolute value of the provided float32 number
+func abs32(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
 // min returns the minum value between two numbers
 func min(val1, val2 int) int {
 	if val1 < val2 {
@@ -28,6 +36,11 @@ func max(val1, val2 int) int {
 	return val2
 }
 
+// clamp restricts the value to the [lo, hi] interval.
+func clamp(val, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, val))
+}
+
 // round returns the nearest integer, rounding ties away from zero.
 func round(x float64) float64 {
 	t := math.Trunc(x)
@@ -8,6 +8,7 @@ import (
 	"runtime"
 	"unsafe"
 
+	"github.com/esimov/pigo/config"
 	pigo "github.com/esimov/pigo/core"
 )
 
@@ -17,9 +18,13 @@ var (
 	faceClassifier   *pigo.Pigo
 	puplocClassifier *pigo.PuplocCascade
 	imageParams      *pigo.ImageParams
+	cfg              config.Config
 	err              error
 )
 
+// configFile holds the detection settings of the demo, on top of the webcam preset.
+const configFile = "blinkdet.json"
+
 func main() {}
 
 //export FindFaces
@@ -36,7 +41,7 @@ func FindFaces(pixels []uint8) uintptr {
 			Row:      results[i].Row - int(0.085*float32(results[i].Scale)),
 			Col:      results[i].Col - int(0.185*float32(results[i].Scale)),
 			Scale:    float32(results[i].Scale) * 0.4,
-			Perturbs: 50,
+			Perturbs: cfg.Perturbs,
 		}
 		det := puplocClassifier.RunDetector(*puploc, *imageParams, 0.0, false)
 		if det.Row > 0 && det.Col > 0 {
@@ -48,7 +53,7 @@ func FindFaces(pixels []uint8) uintptr {
 			Row:      results[i].Row - int(0.085*float32(results[i].Scale)),
 			Col:      results[i].Col + int(0.185*float32(results[i].Scale)),
 			Scale:    float32(results[i].Scale) * 0.4,
-			Perturbs: 50,
+			Perturbs: cfg.Perturbs,
 		}
 
 		det = puplocClassifier.RunDetector(*puploc, *imageParams, 0.0, false)
@@ -91,16 +96,18 @@ func clusterDetection(pixels []uint8, rows, cols int) []pigo.Detection {
 		Cols:   cols,
 		Dim:    cols,
 	}
-	cParams := pigo.CascadeParams{
-		MinSize:     260,
-		MaxSize:     640,
-		ShiftFactor: 0.1,
-		ScaleFactor: 1.1,
-		ImageParams: *imageParams,
-	}
 
-	// Ensure that the face detection classifier is loaded only once.
+	// Ensure that the face detection settings and classifier are loaded only once.
 	if len(cascade) == 0 {
+		base, err := config.Preset(config.Webcam640)
+		if err != nil {
+			log.Fatalf("Error loading the detection settings: %v", err)
+		}
+		cfg, err = config.Load(configFile, base)
+		if err != nil {
+			log.Fatalf("Error loading the detection settings: %v", err)
+		}
+
 		cascade, err = ioutil.ReadFile("../../cascade/facefinder")
 		if err != nil {
 			log.Fatalf("Error reading the cascade file: %v", err)
@@ -129,10 +136,16 @@ func clusterDetection(pixels []uint8, rows, cols int) []pigo.Detection {
 
 	// Run the classifier over the obtained leaf nodes and return the detection results.
 	// The result contains quadruplets representing the row, column, scale and detection score.
-	dets := faceClassifier.RunCascade(cParams, 0.0)
+	dets := faceClassifier.RunCascade(cfg.CascadeParams(*imageParams), cfg.Angle)
 
 	// Calculate the intersection over union (IoU) of two clusters.
-	dets = faceClassifier.ClusterDetections(dets, 0.0)
+	dets = faceClassifier.ClusterDetections(dets, cfg.IoUThreshold)
 
-	return dets
+	res := dets[:0]
+	for _, det := range dets {
+		if det.Q > cfg.QThreshold {
+			res = append(res, det)
+		}
+	}
+	return res
 }
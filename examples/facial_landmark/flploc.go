@@ -21,11 +21,6 @@ var (
 	err              error
 )
 
-var (
-	eyeCascades  = []string{"lp46", "lp44", "lp42", "lp38", "lp312"}
-	mouthCascade = []string{"lp93", "lp84", "lp82", "lp81"}
-)
-
 func main() {}
 
 //export FindFaces
@@ -62,34 +57,15 @@ func FindFaces(pixels []uint8) uintptr {
 			dets[i] = append(dets[i], rightEye.Row, rightEye.Col, int(rightEye.Scale), int(results[i].Q), 1)
 		}
 
-		// Traverse all the eye cascades and run the detector on each of them.
-		for _, eye := range eyeCascades {
-			for _, flpc := range flpcs[eye] {
-				flp := flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, puploc.Perturbs, false)
-				if flp.Row > 0 && flp.Col > 0 {
-					dets[i] = append(dets[i], flp.Row, flp.Col, int(flp.Scale), int(results[i].Q), 2)
-				}
-
-				flp = flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, puploc.Perturbs, true)
-				if flp.Row > 0 && flp.Col > 0 {
-					dets[i] = append(dets[i], flp.Row, flp.Col, int(flp.Scale), int(results[i].Q), 2)
-				}
-			}
-		}
-
-		// Traverse all the mouth cascades and run the detector on each of them.
-		for _, mouth := range mouthCascade {
-			for _, flpc := range flpcs[mouth] {
-				flp := flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, puploc.Perturbs, false)
+		// Traverse all the landmark points defined in the manifest and run the detector on each of them.
+		for _, spec := range pigo.LandmarkManifest {
+			for _, flpc := range flpcs[spec.Name] {
+				flp := flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, puploc.Perturbs, spec.FlipV)
 				if flp.Row > 0 && flp.Col > 0 {
 					dets[i] = append(dets[i], flp.Row, flp.Col, int(flp.Scale), int(results[i].Q), 2)
 				}
 			}
 		}
-		flp := flpcs["lp84"][0].GetLandmarkPoint(leftEye, rightEye, *imgParams, puploc.Perturbs, true)
-		if flp.Row > 0 && flp.Col > 0 {
-			dets[i] = append(dets[i], flp.Row, flp.Col, int(flp.Scale), int(results[i].Q), 2)
-		}
 	}
 
 	coords := make([]int, 0, len(dets))
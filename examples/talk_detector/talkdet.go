@@ -10,12 +10,9 @@ import (
 	"unsafe"
 
 	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/mouth"
 )
 
-type point struct {
-	x, y int
-}
-
 var (
 	cascade          []byte
 	puplocCascade    []byte
@@ -26,10 +23,10 @@ var (
 	err              error
 )
 
-var (
-	eyeCascades  = []string{"lp46", "lp44", "lp42", "lp38", "lp312"}
-	mouthCascade = []string{"lp93", "lp84", "lp82", "lp81"}
-)
+// openThreshold is the mouth aspect ratio above which the mouth is considered open. The demo used to flag
+// the open mouths with the inverted ratio: (width / height) * 0.19 < 0.4, which is the same as height / width > 0.475.
+// The threshold is kept, so the demo flags the same frames as before, unlike the mouth.DefaultParams threshold.
+const openThreshold = 0.19 / 0.4
 
 func main() {}
 
@@ -70,56 +67,31 @@ func FindFaces(pixels []uint8) uintptr {
 			dets[i] = append(dets[i], rightEye.Row, rightEye.Col, int(rightEye.Scale), int(results[i].Q), 1, 1)
 		}
 
-		// Traverse all the eye cascades and run the detector on each of them.
-		for _, eye := range eyeCascades {
-			for _, flpc := range flpcs[eye] {
-				flp := flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, puploc.Perturbs, false)
-				if flp.Row > 0 && flp.Col > 0 {
-					dets[i] = append(dets[i], flp.Row, flp.Col, int(flp.Scale), int(results[i].Q), 2, 1)
-				}
-
-				flp = flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, puploc.Perturbs, true)
+		var landmarks []pigo.Landmark
+		// Traverse all the landmark points defined in the manifest and run the detector on each of them.
+		for _, spec := range pigo.LandmarkManifest {
+			for _, flpc := range flpcs[spec.Name] {
+				flp := flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, puploc.Perturbs, spec.FlipV)
 				if flp.Row > 0 && flp.Col > 0 {
+					landmarks = append(landmarks, pigo.Landmark{Name: spec.Name, Puploc: *flp})
 					dets[i] = append(dets[i], flp.Row, flp.Col, int(flp.Scale), int(results[i].Q), 2, 1)
 				}
 			}
 		}
-
-		mouthPoints := []int{}
-		// Traverse all the mouth cascades and run the detector on each of them.
-		for _, mouth := range mouthCascade {
-			for _, flpc := range flpcs[mouth] {
-				flp := flpc.GetLandmarkPoint(leftEye, rightEye, *imgParams, puploc.Perturbs, false)
-				if flp.Row > 0 && flp.Col > 0 {
-					mouthPoints = append(mouthPoints, flp.Row, flp.Col)
-					dets[i] = append(dets[i], flp.Row, flp.Col, int(flp.Scale), int(results[i].Q), 2, 1)
-				}
-			}
+		// The mouth is considered open when the ratio between the vertical distance
+		// of the lips and the horizontal distance of the mouth corners is above a threshold.
+		face := pigo.Face{Landmarks: landmarks}
+		mar, ok := mouth.AspectRatio(face)
+		if !ok {
+			continue
 		}
-		flp := flpcs["lp84"][0].GetLandmarkPoint(leftEye, rightEye, *imgParams, puploc.Perturbs, true)
-		if flp.Row > 0 && flp.Col > 0 {
-			mouthPoints = append(mouthPoints, flp.Row, flp.Col)
-			dets[i] = append(dets[i], flp.Row, flp.Col, int(flp.Scale), int(results[i].Q), 2, 1)
-		}
-
-		// Calculate the distance ratio between the two horizontal and
-		// two vertical landmark points on the mouth section.
-		// If the ratio is below 1, it means that the mouth is open, otherwise it means that it's closed.
-		p1 := &point{x: mouthPoints[2], y: mouthPoints[3]}
-		p2 := &point{x: mouthPoints[len(mouthPoints)-2], y: mouthPoints[len(mouthPoints)-1]}
-		p3 := &point{x: mouthPoints[4], y: mouthPoints[5]}
-		p4 := &point{x: mouthPoints[len(mouthPoints)-4], y: mouthPoints[len(mouthPoints)-3]}
-
-		dist1 := math.Sqrt(math.Pow(float64(p2.y-p1.y), 2) + math.Pow(float64(p2.x-p1.x), 2))
-		dist2 := math.Sqrt(math.Pow(float64(p4.y-p3.y), 2) + math.Pow(float64(p4.x-p3.x), 2))
-
-		mar := (dist1 / dist2) * 0.19
-		if mar < 0.4 {
+		if mar > openThreshold {
 			talking = 1
 		} else {
 			talking = 0
 		}
-		dets[i] = append(dets[i], flp.Row, flp.Col, int(flp.Scale), int(results[i].Q), 3, talking)
+		p2, _ := face.Landmark(pigo.MouthRight)
+		dets[i] = append(dets[i], p2.Row, p2.Col, 0, int(results[i].Q), 3, talking)
 	}
 
 	coords := make([]int, 0, len(dets))
@@ -5,18 +5,19 @@ import (
 	"flag"
 	"fmt"
 	"image"
-	"image/color"
 	"image/jpeg"
 	"io"
 	"io/ioutil"
 	"log"
-	"math"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"os/exec"
+	"strings"
 
+	"github.com/esimov/pigo/config"
 	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/render"
 	"github.com/fogleman/gg"
 )
 
@@ -39,17 +40,25 @@ var (
 	source       = flag.String("in", "", "Source image")
 	destination  = flag.String("out", "", "Destination image")
 	cascadeFile  = flag.String("cf", "", "Cascade binary file")
-	minSize      = flag.Int("min", 20, "Minimum size of face")
-	maxSize      = flag.Int("max", 1000, "Maximum size of face")
-	shiftFactor  = flag.Float64("shift", 0.15, "Shift detection window by percentage")
-	scaleFactor  = flag.Float64("scale", 1.1, "Scale detection window by percentage")
-	angle        = flag.Float64("angle", 0.0, "0.0 is 0 radians and 1.0 is 2*pi radians")
-	iouThreshold = flag.Float64("iou", 0.2, "Intersection over union (IoU) threshold")
+	configFile   = flag.String("config", "", "Detection settings file (json)")
+	preset       = flag.String("preset", "", "Detection settings preset: "+strings.Join(config.PresetNames(), "|"))
 	circleMarker = flag.Bool("circle", false, "Use circle as detection marker")
 )
 var dc *gg.Context
 
+// def holds the detection settings used when neither a preset nor a configuration file is provided.
+var def = func() config.Config {
+	c := config.Default()
+	c.ShiftFactor = 0.15
+	return c
+}()
+
+// cfg holds the detection settings taken from the preset, overridden by the configuration file,
+// which in turn is overridden by the detection flags set explicitly on the command line.
+var cfg = def
+
 func main() {
+	config.RegisterFlags(flag.CommandLine, def)
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, fmt.Sprintf(banner, Version))
 		flag.PrintDefaults()
@@ -60,8 +69,20 @@ func main() {
 		log.Fatal("Usage: go run main.go -cf ../../cascade/facefinder")
 	}
 
-	if *scaleFactor < 1 {
-		log.Fatal("Scale factor must be greater than 1.")
+	var err error
+	if *preset != "" {
+		if cfg, err = config.Preset(*preset); err != nil {
+			log.Fatalf("[ERROR] %v", err)
+		}
+	}
+	if *configFile != "" {
+		if cfg, err = config.Load(*configFile, cfg); err != nil {
+			log.Fatalf("[ERROR] reading the configuration file: %v", err)
+		}
+	}
+	config.ApplyFlags(&cfg, flag.CommandLine)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("[ERROR] invalid detection settings: %v", err)
 	}
 
 	http.HandleFunc("/cam", webcam)
@@ -123,25 +144,19 @@ func webcam(w http.ResponseWriter, r *http.Request) {
 
 		cols, rows := src.Bounds().Max.X, src.Bounds().Max.Y
 
-		cParams := pigo.CascadeParams{
-			MinSize:     *minSize,
-			MaxSize:     *maxSize,
-			ShiftFactor: *shiftFactor,
-			ScaleFactor: *scaleFactor,
-			ImageParams: pigo.ImageParams{
-				Pixels: frame,
-				Rows:   rows,
-				Cols:   cols,
-				Dim:    cols,
-			},
-		}
+		cParams := cfg.CascadeParams(pigo.ImageParams{
+			Pixels: frame,
+			Rows:   rows,
+			Cols:   cols,
+			Dim:    cols,
+		})
 
 		// Run the classifier over the obtained leaf nodes and return the detection results.
 		// The result contains quadruplets representing the row, column, scale and detection score.
-		dets := classifier.RunCascade(cParams, *angle)
+		dets := classifier.RunCascade(cParams, cfg.Angle)
 
 		// Calculate the intersection over union (IoU) of two clusters.
-		dets = classifier.ClusterDetections(dets, 0)
+		dets = classifier.ClusterDetections(dets, cfg.IoUThreshold)
 
 		dc = gg.NewContext(cols, rows)
 		dc.DrawImage(src, 0, 0)
@@ -162,30 +177,24 @@ func webcam(w http.ResponseWriter, r *http.Request) {
 // drawMarker mark the detected face region with the provided
 // marker (rectangle or circle) and write it to io.Writer.
 func drawMarker(detections []pigo.Detection, w io.Writer, isCircle bool) error {
-	var qThresh float32 = 5.0
-
-	for i := 0; i < len(detections); i++ {
-		if detections[i].Q > qThresh {
-			if isCircle {
-				dc.DrawArc(
-					float64(detections[i].Col),
-					float64(detections[i].Row),
-					float64(detections[i].Scale/2),
-					0,
-					2*math.Pi,
-				)
-			} else {
-				dc.DrawRectangle(
-					float64(detections[i].Col-detections[i].Scale/2),
-					float64(detections[i].Row-detections[i].Scale/2),
-					float64(detections[i].Scale),
-					float64(detections[i].Scale),
-				)
-			}
-			dc.SetLineWidth(3.0)
-			dc.SetStrokeStyle(gg.NewSolidPattern(color.RGBA{R: 255, G: 0, B: 0, A: 255}))
-			dc.Stroke()
+	p := render.DefaultParams()
+	p.Layers = []render.Layer{render.Faces}
+	p.Face.LineWidth = 3
+	if isCircle {
+		p.Marker = render.Circle
+	}
+
+	var faces []pigo.Face
+	for _, det := range detections {
+		if det.Q > cfg.QThreshold {
+			faces = append(faces, pigo.Face{
+				Detection: det,
+				Box:       image.Rect(det.Col-det.Scale/2, det.Row-det.Scale/2, det.Col+det.Scale/2, det.Row+det.Scale/2),
+				Score:     det.Q,
+			})
 		}
 	}
+	render.Draw(dc, faces, p)
+
 	return dc.EncodePNG(w)
 }
@@ -0,0 +1,359 @@
+// Package export encodes the detected faces into the annotation formats used by the object detection datasets:
+// COCO json (with the pupils and the facial landmark points as keypoints), Pascal VOC xml, YOLO txt and flat CSV.
+//
+// The face boxes are clipped to the image bounds and the faces lying entirely outside of the image are skipped. COCO and CSV describe all the images in a single document,
+// while Pascal VOC and YOLO describe a single image per document.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// Format is an annotation format.
+type Format string
+
+// The supported annotation formats.
+const (
+	COCO Format = "coco"
+	VOC  Format = "voc"
+	YOLO Format = "yolo"
+	CSV  Format = "csv"
+)
+
+// DefaultLabel is the default category name of the face annotations.
+const DefaultLabel = "face"
+
+// Options contains the encoding settings.
+// Label: the category name of the face annotations.
+// Confidence: write the detection confidence (see Confidence) as the sixth column of the YOLO annotations,
+// like the YOLO prediction files do. It's disabled by default, since the YOLO training datasets have
+// exactly five columns and their loaders reject the additional ones.
+type Options struct {
+	Label      string
+	Confidence bool
+}
+
+// DefaultOptions returns the default encoding settings.
+func DefaultOptions() Options {
+	return Options{Label: DefaultLabel}
+}
+
+// scoreScale is the detection score mapped to a confidence of 1-1/e by Confidence.
+const scoreScale = 20
+
+// Confidence maps the unbounded detection score of a face to a confidence between 0 and 1. The mapping is
+// monotonic and saturating: a score of 5, the default detection threshold, maps to 0.22, a score of 20 to 0.63
+// and a score of 60 to 0.95. The faces scoring 0 or below get a confidence of 0.
+func Confidence(score float32) float64 {
+	if score <= 0 {
+		return 0
+	}
+	return 1 - math.Exp(-float64(score)/scoreScale)
+}
+
+// ErrUnknownFormat is returned when the annotation format is not supported.
+var ErrUnknownFormat = errors.New("unknown annotation format")
+
+// PerImage checks whether the format describes a single image per document.
+func (f Format) PerImage() bool {
+	return f == VOC || f == YOLO
+}
+
+// Ext returns the file extension of the format documents.
+func (f Format) Ext() string {
+	switch f {
+	case COCO:
+		return ".json"
+	case VOC:
+		return ".xml"
+	case YOLO:
+		return ".txt"
+	case CSV:
+		return ".csv"
+	}
+	return ""
+}
+
+// Image holds an image and the faces detected on it.
+// File: the path of the image file.
+// Width, Height: the size of the image in pixels.
+type Image struct {
+	File   string
+	Width  int
+	Height int
+	Faces  []pigo.Face
+}
+
+// Encode writes the annotations of the images in the provided format. The per image formats accept a single image.
+func Encode(w io.Writer, f Format, images []Image, opts Options) error {
+	if f.PerImage() && len(images) != 1 {
+		return fmt.Errorf("the %s format describes a single image, got: %d", f, len(images))
+	}
+	switch f {
+	case COCO:
+		return WriteCOCO(w, images, opts.Label)
+	case VOC:
+		return WriteVOC(w, images[0], opts.Label)
+	case YOLO:
+		return WriteYOLO(w, images[0], 0, opts.Confidence)
+	case CSV:
+		return WriteCSV(w, images, opts.Label)
+	}
+	return ErrUnknownFormat
+}
+
+// KeypointNames returns the names of the COCO keypoints: the pupils followed by the facial landmark points.
+func KeypointNames() []string {
+	names := []string{pigo.LeftPupil, pigo.RightPupil}
+	for _, spec := range pigo.LandmarkManifest {
+		names = append(names, spec.Name)
+	}
+	return names
+}
+
+type cocoDataset struct {
+	Images      []cocoImage      `json:"images"`
+	Annotations []cocoAnnotation `json:"annotations"`
+	Categories  []cocoCategory   `json:"categories"`
+}
+
+type cocoImage struct {
+	ID       int    `json:"id"`
+	FileName string `json:"file_name"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+type cocoAnnotation struct {
+	ID           int       `json:"id"`
+	ImageID      int       `json:"image_id"`
+	CategoryID   int       `json:"category_id"`
+	BBox         [4]int    `json:"bbox"`
+	Area         int       `json:"area"`
+	IsCrowd      int       `json:"iscrowd"`
+	Score        float32   `json:"score"`
+	Keypoints    []float64 `json:"keypoints"`
+	NumKeypoints int       `json:"num_keypoints"`
+}
+
+type cocoCategory struct {
+	ID            int      `json:"id"`
+	Name          string   `json:"name"`
+	Keypoints     []string `json:"keypoints"`
+	Skeleton      [][2]int `json:"skeleton"`
+	Supercategory string   `json:"supercategory"`
+}
+
+// WriteCOCO writes the annotations of the images as a single COCO dataset, using one category named by the label.
+// The keypoints follow the order of KeypointNames; the missing points have the visibility flag set to 0.
+func WriteCOCO(w io.Writer, images []Image, label string) error {
+	names := KeypointNames()
+	ds := cocoDataset{
+		Images:      make([]cocoImage, 0, len(images)),
+		Annotations: []cocoAnnotation{},
+		Categories: []cocoCategory{{
+			ID:            1,
+			Name:          label,
+			Keypoints:     names,
+			Skeleton:      [][2]int{},
+			Supercategory: label,
+		}},
+	}
+
+	for i, img := range images {
+		ds.Images = append(ds.Images, cocoImage{
+			ID:       i + 1,
+			FileName: filepath.ToSlash(img.File),
+			Width:    img.Width,
+			Height:   img.Height,
+		})
+		for _, face := range img.Faces {
+			box := clip(face.Box, img)
+			if box.Empty() {
+				continue
+			}
+			ann := cocoAnnotation{
+				ID:         len(ds.Annotations) + 1,
+				ImageID:    i + 1,
+				CategoryID: 1,
+				BBox:       [4]int{box.Min.X, box.Min.Y, box.Dx(), box.Dy()},
+				Area:       box.Dx() * box.Dy(),
+				Score:      face.Score,
+				Keypoints:  make([]float64, 0, 3*len(names)),
+			}
+			for _, p := range keypoints(face) {
+				if p == nil {
+					ann.Keypoints = append(ann.Keypoints, 0, 0, 0)
+					continue
+				}
+				ann.Keypoints = append(ann.Keypoints, float64(p.Col), float64(p.Row), 2)
+				ann.NumKeypoints++
+			}
+			ds.Annotations = append(ds.Annotations, ann)
+		}
+	}
+
+	return json.NewEncoder(w).Encode(ds)
+}
+
+type vocAnnotation struct {
+	XMLName   xml.Name    `xml:"annotation"`
+	Folder    string      `xml:"folder"`
+	Filename  string      `xml:"filename"`
+	Path      string      `xml:"path"`
+	Database  string      `xml:"source>database"`
+	Width     int         `xml:"size>width"`
+	Height    int         `xml:"size>height"`
+	Depth     int         `xml:"size>depth"`
+	Segmented int         `xml:"segmented"`
+	Objects   []vocObject `xml:"object"`
+}
+
+type vocObject struct {
+	Name      string  `xml:"name"`
+	Pose      string  `xml:"pose"`
+	Truncated int     `xml:"truncated"`
+	Difficult int     `xml:"difficult"`
+	Score     float32 `xml:"score"`
+	XMin      int     `xml:"bndbox>xmin"`
+	YMin      int     `xml:"bndbox>ymin"`
+	XMax      int     `xml:"bndbox>xmax"`
+	YMax      int     `xml:"bndbox>ymax"`
+}
+
+// WriteVOC writes the annotations of the image as a Pascal VOC xml document. The faces crossing
+// the image bounds are marked as truncated. The detection score is added to the objects.
+func WriteVOC(w io.Writer, img Image, label string) error {
+	ann := vocAnnotation{
+		Folder:   filepath.Base(filepath.Dir(img.File)),
+		Filename: filepath.Base(img.File),
+		Path:     img.File,
+		Database: "Unknown",
+		Width:    img.Width,
+		Height:   img.Height,
+		Depth:    3,
+	}
+	for _, face := range img.Faces {
+		box := clip(face.Box, img)
+		if box.Empty() {
+			continue
+		}
+		truncated := 0
+		if box != face.Box {
+			truncated = 1
+		}
+		// The Pascal VOC pixel coordinates are 1-based and inclusive.
+		ann.Objects = append(ann.Objects, vocObject{
+			Name:      label,
+			Pose:      "Unspecified",
+			Truncated: truncated,
+			Score:     face.Score,
+			XMin:      box.Min.X + 1,
+			YMin:      box.Min.Y + 1,
+			XMax:      box.Max.X,
+			YMax:      box.Max.Y,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(ann); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteYOLO writes the annotations of the image in the YOLO txt format: one line per face with the class id
+// and the box center and size normalized by the image size. If confidence is set, the lines are followed
+// by the detection confidence between 0 and 1 (see Confidence), like in the YOLO prediction files.
+func WriteYOLO(w io.Writer, img Image, classID int, confidence bool) error {
+	if img.Width <= 0 || img.Height <= 0 {
+		return fmt.Errorf("invalid image size: %dx%d", img.Width, img.Height)
+	}
+	for _, face := range img.Faces {
+		box := clip(face.Box, img)
+		if box.Empty() {
+			continue
+		}
+		iw, ih := float64(img.Width), float64(img.Height)
+		line := fmt.Sprintf("%d %.6f %.6f %.6f %.6f", classID,
+			(float64(box.Min.X)+float64(box.Dx())/2)/iw,
+			(float64(box.Min.Y)+float64(box.Dy())/2)/ih,
+			float64(box.Dx())/iw,
+			float64(box.Dy())/ih,
+		)
+		if confidence {
+			line += fmt.Sprintf(" %.6f", Confidence(face.Score))
+		}
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes the annotations of the images as a flat CSV table, with one row per face.
+// The columns are: file, image_width, image_height, label, score, xmin, ymin, xmax, ymax.
+func WriteCSV(w io.Writer, images []Image, label string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"file", "image_width", "image_height", "label", "score", "xmin", "ymin", "xmax", "ymax"}); err != nil {
+		return err
+	}
+	for _, img := range images {
+		for _, face := range img.Faces {
+			box := clip(face.Box, img)
+			if box.Empty() {
+				continue
+			}
+			err := cw.Write([]string{
+				img.File,
+				strconv.Itoa(img.Width),
+				strconv.Itoa(img.Height),
+				label,
+				strconv.FormatFloat(float64(face.Score), 'f', -1, 32),
+				strconv.Itoa(box.Min.X),
+				strconv.Itoa(box.Min.Y),
+				strconv.Itoa(box.Max.X),
+				strconv.Itoa(box.Max.Y),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// keypoints returns the pupils and the landmark points of the face in the order of KeypointNames.
+func keypoints(face pigo.Face) []*pigo.Puploc {
+	points := []*pigo.Puploc{face.LeftEye, face.RightEye}
+	for _, spec := range pigo.LandmarkManifest {
+		if lp, ok := face.Landmark(spec.Name); ok {
+			points = append(points, &lp.Puploc)
+		} else {
+			points = append(points, nil)
+		}
+	}
+	return points
+}
+
+// clip limits the face box to the image bounds.
+func clip(box image.Rectangle, img Image) image.Rectangle {
+	return box.Intersect(image.Rect(0, 0, img.Width, img.Height))
+}
@@ -0,0 +1,209 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"image"
+	"strings"
+	"testing"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/export"
+)
+
+// testImages returns two images, the first one with a face crossing its left edge and the second one without faces.
+func testImages() []export.Image {
+	face := pigo.Face{
+		Box:      image.Rect(-10, 20, 90, 120),
+		Score:    12.5,
+		LeftEye:  &pigo.Puploc{Row: 50, Col: 20},
+		RightEye: &pigo.Puploc{Row: 50, Col: 60},
+		Landmarks: []pigo.Landmark{
+			{Name: pigo.NoseTip, Puploc: pigo.Puploc{Row: 80, Col: 40}},
+		},
+	}
+	return []export.Image{
+		{File: "photos/a.jpg", Width: 200, Height: 100, Faces: []pigo.Face{face}},
+		{File: "photos/b.jpg", Width: 50, Height: 50},
+	}
+}
+
+func TestExport_COCO(t *testing.T) {
+	var buf bytes.Buffer
+	if err := export.Encode(&buf, export.COCO, testImages(), export.Options{Label: "person_face"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ds struct {
+		Images []struct {
+			ID     int `json:"id"`
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"images"`
+		Annotations []struct {
+			ImageID      int       `json:"image_id"`
+			BBox         []int     `json:"bbox"`
+			Score        float64   `json:"score"`
+			Keypoints    []float64 `json:"keypoints"`
+			NumKeypoints int       `json:"num_keypoints"`
+		} `json:"annotations"`
+		Categories []struct {
+			Name      string   `json:"name"`
+			Keypoints []string `json:"keypoints"`
+		} `json:"categories"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &ds); err != nil {
+		t.Fatalf("invalid COCO json: %v", err)
+	}
+	if len(ds.Images) != 2 || ds.Images[1].Width != 50 || len(ds.Annotations) != 1 {
+		t.Fatalf("unexpected dataset: %+v", ds)
+	}
+	ann := ds.Annotations[0]
+	if ann.ImageID != 1 || ann.Score != 12.5 || !equal(ann.BBox, []int{0, 20, 90, 80}) {
+		t.Errorf("unexpected annotation: %+v", ann)
+	}
+
+	names := ds.Categories[0].Keypoints
+	if ds.Categories[0].Name != "person_face" || len(ann.Keypoints) != 3*len(names) || ann.NumKeypoints != 3 {
+		t.Fatalf("unexpected keypoints: %v for %v", ann.Keypoints, names)
+	}
+	for i, name := range names {
+		if name == pigo.NoseTip && (ann.Keypoints[3*i] != 40 || ann.Keypoints[3*i+1] != 80 || ann.Keypoints[3*i+2] != 2) {
+			t.Errorf("unexpected nose tip keypoint: %v", ann.Keypoints[3*i:3*i+3])
+		}
+		if name == pigo.MouthLeft && ann.Keypoints[3*i+2] != 0 {
+			t.Errorf("expected the missing keypoint to be invisible")
+		}
+	}
+}
+
+func TestExport_VOC(t *testing.T) {
+	var buf bytes.Buffer
+	if err := export.Encode(&buf, export.VOC, testImages()[:1], export.DefaultOptions()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ann struct {
+		Filename string `xml:"filename"`
+		Width    int    `xml:"size>width"`
+		Objects  []struct {
+			Name      string  `xml:"name"`
+			Truncated int     `xml:"truncated"`
+			Score     float64 `xml:"score"`
+			XMin      int     `xml:"bndbox>xmin"`
+			XMax      int     `xml:"bndbox>xmax"`
+		} `xml:"object"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &ann); err != nil {
+		t.Fatalf("invalid VOC xml: %v", err)
+	}
+	if ann.Filename != "a.jpg" || ann.Width != 200 || len(ann.Objects) != 1 {
+		t.Fatalf("unexpected annotation: %+v", ann)
+	}
+	if obj := ann.Objects[0]; obj.Name != "face" || obj.Truncated != 1 || obj.Score != 12.5 || obj.XMin != 1 || obj.XMax != 90 {
+		t.Errorf("unexpected object: %+v", obj)
+	}
+
+	if err := export.Encode(&buf, export.VOC, testImages(), export.DefaultOptions()); err == nil {
+		t.Errorf("expected an error when encoding multiple images in a per image format")
+	}
+}
+
+func TestExport_YOLO(t *testing.T) {
+	var buf bytes.Buffer
+	if err := export.Encode(&buf, export.YOLO, testImages()[:1], export.DefaultOptions()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "0 0.225000 0.600000 0.450000 0.800000\n"; got != want {
+		t.Errorf("expected: %q, got: %q", want, got)
+	}
+
+	buf.Reset()
+	opts := export.DefaultOptions()
+	opts.Confidence = true
+	if err := export.Encode(&buf, export.YOLO, testImages()[:1], opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "0 0.225000 0.600000 0.450000 0.800000 0.464739\n"; got != want {
+		t.Errorf("expected: %q, got: %q", want, got)
+	}
+}
+
+func TestExport_Confidence(t *testing.T) {
+	prev := -1.0
+	for _, score := range []float32{-3, 0, 1, 5, 20, 60, 500} {
+		c := export.Confidence(score)
+		if c < 0 || c > 1 {
+			t.Errorf("score %.1f: the confidence should be between 0 and 1, got: %f", score, c)
+		}
+		if score > 0 && c <= prev {
+			t.Errorf("score %.1f: the confidence should increase with the score, got: %f", score, c)
+		}
+		prev = c
+	}
+}
+
+func TestExport_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := export.Encode(&buf, export.CSV, testImages(), export.DefaultOptions()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+	if len(rows) != 2 || strings.Join(rows[1], ",") != "photos/a.jpg,200,100,face,12.5,0,20,90,100" {
+		t.Errorf("unexpected rows: %v", rows)
+	}
+	if err := export.Encode(&buf, "txt", nil, export.DefaultOptions()); err != export.ErrUnknownFormat {
+		t.Errorf("expected the unknown format error, got: %v", err)
+	}
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestExport_ShouldSkipTheFacesOutsideOfTheImage(t *testing.T) {
+	img := export.Image{File: "c.jpg", Width: 100, Height: 100, Faces: []pigo.Face{
+		{Box: image.Rect(150, 150, 200, 200), Score: 8},
+		{Box: image.Rect(10, 10, 50, 50), Score: 10},
+	}}
+
+	for _, f := range []export.Format{export.COCO, export.VOC, export.YOLO, export.CSV} {
+		var buf bytes.Buffer
+		if err := export.Encode(&buf, f, []export.Image{img}, export.DefaultOptions()); err != nil {
+			t.Fatalf("%s: unexpected error: %v", f, err)
+		}
+		var count int
+		switch f {
+		case export.COCO:
+			var ds struct {
+				Annotations []struct {
+					Area int `json:"area"`
+				} `json:"annotations"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &ds); err != nil {
+				t.Fatalf("invalid COCO json: %v", err)
+			}
+			count = len(ds.Annotations)
+		case export.VOC:
+			count = strings.Count(buf.String(), "<object>")
+		case export.YOLO:
+			count = strings.Count(buf.String(), "\n")
+		case export.CSV:
+			count = strings.Count(buf.String(), "\n") - 1
+		}
+		if count != 1 {
+			t.Errorf("%s: expected only the face inside of the image, got %d faces:\n%s", f, count, buf.String())
+		}
+	}
+}
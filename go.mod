@@ -5,11 +5,11 @@ go 1.22
 require (
 	github.com/disintegration/imaging v1.6.2
 	github.com/fogleman/gg v1.3.0
+	golang.org/x/image v0.0.0-20200927104501-e162460cd6b5
 	golang.org/x/term v0.0.0-20191110171634-ad39bd3f0407
 )
 
 require (
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
-	golang.org/x/image v0.0.0-20200927104501-e162460cd6b5 // indirect
 	golang.org/x/sys v0.0.0-20201107080550-4d91cf3a1aaf // indirect
 )
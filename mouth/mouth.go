@@ -0,0 +1,248 @@
+// Package mouth measures the mouth opening of the analyzed faces and detects the speaking activity over time.
+//
+// The mouth aspect ratio (MAR) is the distance between the upper and lower lip landmark points
+// divided by the distance between the mouth corners. Since the mouth repeatedly opens and closes while talking,
+// the speaking activity is measured as the standard deviation of the mouth aspect ratio over a short sliding window.
+// Speaking and silent segments are separated with hysteresis: a segment starts when the activity rises above
+// the start threshold and stops once the activity stays below the stop threshold for longer than the hangover period.
+package mouth
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// AspectRatio returns the mouth aspect ratio of the face.
+// It returns false if any of the mouth landmark points is missing.
+func AspectRatio(face pigo.Face) (float64, bool) {
+	var pts [4]pigo.Landmark
+	for i, name := range []string{pigo.MouthLeft, pigo.MouthRight, pigo.UpperLip, pigo.LowerLip} {
+		lp, ok := face.Landmark(name)
+		if !ok {
+			return 0, false
+		}
+		pts[i] = lp
+	}
+	width := dist(pts[0], pts[1])
+	if width == 0 {
+		return 0, false
+	}
+	return dist(pts[2], pts[3]) / width, true
+}
+
+// Params contains the settings of the speaking activity detector.
+// OpenThreshold: the mouth is considered open when its aspect ratio is above this value.
+// Window: the length of the sliding window over which the activity is measured.
+// StartThreshold: a speaking segment starts when the activity rises above this value.
+// StopThreshold: a speaking segment stops when the activity stays below this value for longer than Hangover.
+// Hangover: the length of the pauses tolerated within a speaking segment.
+// Timeout: the tracks not updated for longer than this period are dropped, stopping their speaking segment.
+type Params struct {
+	OpenThreshold  float64
+	Window         time.Duration
+	StartThreshold float64
+	StopThreshold  float64
+	Hangover       time.Duration
+	Timeout        time.Duration
+}
+
+// DefaultParams returns the default speaking activity detector settings.
+func DefaultParams() Params {
+	return Params{
+		OpenThreshold:  0.45,
+		Window:         time.Second,
+		StartThreshold: 0.04,
+		StopThreshold:  0.025,
+		Hangover:       500 * time.Millisecond,
+		Timeout:        time.Second,
+	}
+}
+
+// EventType is the type of a speaking activity event.
+type EventType string
+
+// The speaking activity events.
+const (
+	SpeakingStarted EventType = "start"
+	SpeakingStopped EventType = "stop"
+)
+
+// Event describes the start or the stop of a speaking segment of a tracked face.
+// Time: the time of the first (start) or the last (stop) active frame of the segment.
+// Duration: the length of the segment, only set on stop.
+type Event struct {
+	Type     EventType
+	TrackID  int
+	Time     time.Time
+	Duration time.Duration
+}
+
+// Status holds the per frame measurements of a tracked face.
+// MAR: the mouth aspect ratio of the frame.
+// Activity: the standard deviation of the mouth aspect ratio over the sliding window.
+type Status struct {
+	TrackID  int
+	MAR      float64
+	Open     bool
+	Activity float64
+	Speaking bool
+}
+
+// sample is a mouth aspect ratio measurement.
+type sample struct {
+	time time.Time
+	mar  float64
+}
+
+// trackState holds the speaking activity state of a tracked face.
+type trackState struct {
+	samples    []sample
+	lastSeen   time.Time
+	lastActive time.Time
+	speaking   bool
+	start      time.Time
+}
+
+// Detector detects the speaking segments of the tracked faces.
+// OnEvent is called for each start and stop event, if it's defined.
+type Detector struct {
+	Params
+	OnEvent func(Event)
+
+	tracks map[int]*trackState
+}
+
+// NewDetector initializes the speaking activity Detector constructor method.
+func NewDetector(p Params) *Detector {
+	return &Detector{
+		Params: p,
+		tracks: make(map[int]*trackState),
+	}
+}
+
+// Update feeds the detector with the tracks of the faces analyzed on the frame captured at the time t.
+// It returns the status of the tracks with all the mouth landmark points detected, and the events
+// emitted on this frame. The segments of the tracks not updated for longer than Timeout are stopped.
+func (d *Detector) Update(t time.Time, tracks []pigo.Track) ([]Status, []Event) {
+	var (
+		statuses []Status
+		events   []Event
+	)
+	emit := func(ev Event) {
+		events = append(events, ev)
+		if d.OnEvent != nil {
+			d.OnEvent(ev)
+		}
+	}
+
+	for _, tr := range tracks {
+		// The face is still present even if its mouth points are missing on this frame,
+		// so the track is kept alive without sampling it.
+		s, ok := d.tracks[tr.ID]
+		if !ok {
+			s = &trackState{}
+			d.tracks[tr.ID] = s
+		}
+		s.lastSeen = t
+
+		// A frame without the mouth points counts as a silent one, so that the segment is stopped after the hangover.
+		mar, sampled := AspectRatio(tr.Face)
+		var activity float64
+		if sampled {
+			s.samples = append(s.samples, sample{t, mar})
+
+			// Drop the samples which fell out of the sliding window.
+			n := 0
+			for n < len(s.samples) && t.Sub(s.samples[n].time) > d.Window {
+				n++
+			}
+			s.samples = s.samples[n:]
+			activity = stdDev(s.samples)
+		}
+
+		switch {
+		case sampled && !s.speaking && activity >= d.StartThreshold:
+			s.speaking = true
+			s.start = t
+			s.lastActive = t
+			emit(Event{Type: SpeakingStarted, TrackID: tr.ID, Time: t})
+		case sampled && s.speaking && activity >= d.StopThreshold:
+			s.lastActive = t
+		case s.speaking && t.Sub(s.lastActive) > d.Hangover:
+			s.speaking = false
+			emit(d.stopEvent(tr.ID, s))
+		}
+		if !sampled {
+			continue
+		}
+
+		statuses = append(statuses, Status{
+			TrackID:  tr.ID,
+			MAR:      mar,
+			Open:     mar > d.OpenThreshold,
+			Activity: activity,
+			Speaking: s.speaking,
+		})
+	}
+
+	// Stop the segments of the lost tracks, in a deterministic order.
+	var lost []int
+	for id, s := range d.tracks {
+		if t.Sub(s.lastSeen) > d.Timeout {
+			lost = append(lost, id)
+		}
+	}
+	sort.Ints(lost)
+	for _, id := range lost {
+		if s := d.tracks[id]; s.speaking {
+			emit(d.stopEvent(id, s))
+		}
+		delete(d.tracks, id)
+	}
+	return statuses, events
+}
+
+// Speaking reports whether the tracked face is currently speaking.
+func (d *Detector) Speaking(id int) bool {
+	s, ok := d.tracks[id]
+	return ok && s.speaking
+}
+
+// Reset drops the state of all the tracks without emitting stop events.
+func (d *Detector) Reset() {
+	d.tracks = make(map[int]*trackState)
+}
+
+// stopEvent returns the stop event of the speaking segment of a track.
+func (d *Detector) stopEvent(id int, s *trackState) Event {
+	return Event{
+		Type:     SpeakingStopped,
+		TrackID:  id,
+		Time:     s.lastActive,
+		Duration: s.lastActive.Sub(s.start),
+	}
+}
+
+// stdDev returns the standard deviation of the mouth aspect ratio samples.
+func stdDev(samples []sample) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	var sum, sq float64
+	for _, s := range samples {
+		sum += s.mar
+	}
+	mean := sum / float64(len(samples))
+	for _, s := range samples {
+		sq += (s.mar - mean) * (s.mar - mean)
+	}
+	return math.Sqrt(sq / float64(len(samples)))
+}
+
+// dist returns the euclidean distance between two landmark points.
+func dist(p1, p2 pigo.Landmark) float64 {
+	return math.Hypot(float64(p1.Col-p2.Col), float64(p1.Row-p2.Row))
+}
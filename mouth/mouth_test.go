@@ -0,0 +1,135 @@
+package mouth_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/mouth"
+)
+
+// syntheticTrack returns a track with the mouth corners 60px apart and the lips separated by the provided gap.
+func syntheticTrack(id, gap int) pigo.Track {
+	landmark := func(name string, row, col int) pigo.Landmark {
+		return pigo.Landmark{Name: name, Puploc: pigo.Puploc{Row: row, Col: col}}
+	}
+	return pigo.Track{ID: id, Face: pigo.Face{
+		Landmarks: []pigo.Landmark{
+			landmark(pigo.NoseTip, 200, 130),
+			landmark(pigo.MouthLeft, 240, 100),
+			landmark(pigo.UpperLip, 235, 130),
+			landmark(pigo.LowerLip, 235+gap, 130),
+			landmark(pigo.MouthRight, 240, 160),
+		},
+	}}
+}
+
+func TestMouth_AspectRatioShouldRequireAllTheMouthPoints(t *testing.T) {
+	tr := syntheticTrack(1, 30)
+	mar, ok := mouth.AspectRatio(tr.Face)
+	if !ok || math.Abs(mar-0.5) > 1e-9 {
+		t.Errorf("expected a mouth aspect ratio of 0.5, got: %v, %v", mar, ok)
+	}
+
+	tr.Face.Landmarks = tr.Face.Landmarks[:3]
+	if _, ok := mouth.AspectRatio(tr.Face); ok {
+		t.Errorf("expected the mouth aspect ratio to require all the mouth points")
+	}
+}
+
+func TestMouth_DetectorShouldSegmentTheSpeakingActivity(t *testing.T) {
+	var (
+		det      = mouth.NewDetector(mouth.DefaultParams())
+		start    = time.Unix(0, 0)
+		frame    = 33 * time.Millisecond
+		received []mouth.Event
+		events   []mouth.Event
+	)
+	det.OnEvent = func(ev mouth.Event) {
+		received = append(received, ev)
+	}
+
+	// 1s of silence, 2s of speech with a short pause in the middle and 2s of silence.
+	for i := 0; i < 150; i++ {
+		gap := 6
+		if i >= 30 && i < 90 && (i < 55 || i >= 60) && (i/3)%2 == 0 {
+			gap = 30
+		}
+		statuses, evs := det.Update(start.Add(time.Duration(i)*frame), []pigo.Track{syntheticTrack(7, gap)})
+		if len(statuses) != 1 || statuses[0].TrackID != 7 {
+			t.Fatalf("expected the status of the track, got: %v", statuses)
+		}
+		if statuses[0].Open != (gap == 30) {
+			t.Errorf("unexpected mouth open state on frame %d: %+v", i, statuses[0])
+		}
+		events = append(events, evs...)
+	}
+
+	if len(events) != 2 || len(received) != 2 {
+		t.Fatalf("expected a single speaking segment, got: %v", events)
+	}
+	if ev := events[0]; ev.Type != mouth.SpeakingStarted || ev.TrackID != 7 ||
+		ev.Time.Before(start.Add(30*frame)) || ev.Time.After(start.Add(40*frame)) {
+		t.Errorf("unexpected start event: %+v", ev)
+	}
+	if ev := events[1]; ev.Type != mouth.SpeakingStopped ||
+		ev.Time.Before(start.Add(85*frame)) || ev.Duration != ev.Time.Sub(events[0].Time) {
+		t.Errorf("unexpected stop event: %+v", ev)
+	}
+	if det.Speaking(7) {
+		t.Errorf("expected the track to be silent")
+	}
+}
+
+func TestMouth_DetectorShouldStopTheSegmentsOfLostTracks(t *testing.T) {
+	det := mouth.NewDetector(mouth.DefaultParams())
+	start := time.Unix(0, 0)
+	frame := 33 * time.Millisecond
+
+	for i := 0; i < 20; i++ {
+		det.Update(start.Add(time.Duration(i)*frame), []pigo.Track{syntheticTrack(1, 6+24*(i%2))})
+	}
+	if !det.Speaking(1) {
+		t.Fatalf("expected the track to be speaking")
+	}
+
+	_, events := det.Update(start.Add(20*frame+2*time.Second), nil)
+	if len(events) != 1 || events[0].Type != mouth.SpeakingStopped || events[0].TrackID != 1 {
+		t.Errorf("expected the segment of the lost track to be stopped, got: %v", events)
+	}
+	if det.Speaking(1) {
+		t.Errorf("expected the lost track to be dropped")
+	}
+}
+
+func TestMouth_DetectorShouldStopTheSegmentsWithoutMouthPoints(t *testing.T) {
+	det := mouth.NewDetector(mouth.DefaultParams())
+	start := time.Unix(0, 0)
+	frame := 33 * time.Millisecond
+
+	for i := 0; i < 20; i++ {
+		det.Update(start.Add(time.Duration(i)*frame), []pigo.Track{syntheticTrack(1, 6+24*(i%2))})
+	}
+	if !det.Speaking(1) {
+		t.Fatalf("expected the track to be speaking")
+	}
+
+	// The face is still tracked, but its mouth points are missing for longer than the timeout.
+	// The missing points count as silence: the segment stops after the hangover, while the track is kept alive.
+	noMouth := pigo.Track{ID: 1}
+	var stops []mouth.Event
+	for i := 20; i < 20+int(2*det.Timeout/frame); i++ {
+		now := start.Add(time.Duration(i) * frame)
+		_, events := det.Update(now, []pigo.Track{noMouth})
+		for _, ev := range events {
+			if ev.Type != mouth.SpeakingStopped || now.Sub(ev.Time) > det.Hangover+frame {
+				t.Fatalf("frame %d: expected the segment to stop after the hangover, got: %+v", i, ev)
+			}
+		}
+		stops = append(stops, events...)
+	}
+	if len(stops) != 1 || det.Speaking(1) {
+		t.Errorf("expected a single stop event, got: %+v", stops)
+	}
+}
@@ -0,0 +1,234 @@
+// Package overlay composites image assets, like glasses or masks, over the analyzed faces.
+//
+// An asset declares anchor points: the positions within the asset which should be placed over named points
+// of the face, like the pupils or the facial landmark points. The asset is mapped onto the face with the similarity
+// transform (rotation, uniform scale and translation) which best fits its anchors to the face points.
+package overlay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"math/cmplx"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/esimov/pigo/align"
+	pigo "github.com/esimov/pigo/core"
+)
+
+// ErrTooFewAnchors is returned when an asset declares less than two anchor points.
+var ErrTooFewAnchors = errors.New("the asset should declare at least two anchor points")
+
+// Anchor maps a position of the asset to a named point of the face.
+// Point: the name of the face point, either a pupil (pigo.LeftPupil, pigo.RightPupil) or a landmark point name.
+// X, Y: the position of the anchor within the asset, in pixels.
+type Anchor struct {
+	Point string  `json:"point"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+}
+
+// Asset is an image composited over the faces through its anchor points.
+type Asset struct {
+	Image   image.Image
+	Anchors []Anchor
+}
+
+// LoadAsset loads the asset image from path. The anchor points are read from a json file
+// having the same name as the image, but with the .json extension, which contains a list of anchors:
+//
+//	[{"point": "LeftPupil", "x": 130, "y": 140}, {"point": "RightPupil", "x": 372, "y": 140}]
+func LoadAsset(path string) (*Asset, error) {
+	img, err := pigo.GetImage(path)
+	if err != nil {
+		return nil, err
+	}
+	asset := &Asset{Image: img}
+
+	data, err := os.ReadFile(strings.TrimSuffix(path, filepath.Ext(path)) + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("error reading the asset anchors: %w", err)
+	}
+	if err := json.Unmarshal(data, &asset.Anchors); err != nil {
+		return nil, fmt.Errorf("error decoding the asset anchors: %w", err)
+	}
+	return asset, nil
+}
+
+// ParseAnchors parses the anchor points defined in the "Point:x,y;Point:x,y" format.
+func ParseAnchors(s string) ([]Anchor, error) {
+	var anchors []Anchor
+	for _, def := range strings.Split(s, ";") {
+		if def = strings.TrimSpace(def); def == "" {
+			continue
+		}
+		var a Anchor
+		name, pos, ok := strings.Cut(def, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid anchor point: %q", def)
+		}
+		if _, err := fmt.Sscanf(pos, "%g,%g", &a.X, &a.Y); err != nil {
+			return nil, fmt.Errorf("invalid anchor point position: %q", def)
+		}
+		a.Point = strings.TrimSpace(name)
+		anchors = append(anchors, a)
+	}
+	return anchors, nil
+}
+
+// Transform returns the similarity transform mapping the asset onto the face. The anchors pointing
+// to face points which were not detected are ignored. It returns false if less than two anchors can be used.
+func (a Asset) Transform(face pigo.Face) (align.Transform, bool) {
+	var src, dst []complex128
+	for _, anchor := range a.Anchors {
+		if p, ok := facePoint(face, anchor.Point); ok {
+			src = append(src, complex(anchor.X, anchor.Y))
+			dst = append(dst, p)
+		}
+	}
+	if len(src) < 2 {
+		return align.Transform{}, false
+	}
+
+	// The least squares similarity transform, using complex numbers: dst = c*src + t.
+	var srcMean, dstMean complex128
+	for i := range src {
+		srcMean += src[i] / complex(float64(len(src)), 0)
+		dstMean += dst[i] / complex(float64(len(src)), 0)
+	}
+	var num complex128
+	var den float64
+	for i := range src {
+		s, d := src[i]-srcMean, dst[i]-dstMean
+		num += cmplx.Conj(s) * d
+		den += real(s)*real(s) + imag(s)*imag(s)
+	}
+	if den == 0 || num == 0 {
+		return align.Transform{}, false
+	}
+	c := num / complex(den, 0)
+	t := dstMean - c*srcMean
+
+	return align.Transform{
+		A: real(c), B: -imag(c), C: real(t),
+		D: imag(c), E: real(c), F: imag(t),
+	}, true
+}
+
+// Apply returns a copy of the image with the asset composited over each face.
+// The faces without enough detected anchor points are skipped.
+func Apply(img image.Image, faces []pigo.Face, asset Asset) (*image.NRGBA, error) {
+	if len(asset.Anchors) < 2 {
+		return nil, ErrTooFewAnchors
+	}
+	for _, anchor := range asset.Anchors {
+		if _, ok := pigo.LookupLandmark(anchor.Point); !ok && anchor.Point != pigo.LeftPupil && anchor.Point != pigo.RightPupil {
+			return nil, fmt.Errorf("unknown anchor point: %s", anchor.Point)
+		}
+	}
+	src := pigo.ImgToNRGBA(img)
+	dst := image.NewNRGBA(src.Bounds())
+	copy(dst.Pix, src.Pix)
+
+	overlay := pigo.ImgToNRGBA(asset.Image)
+	for _, face := range faces {
+		if t, ok := asset.Transform(face); ok {
+			Composite(dst, overlay, t)
+		}
+	}
+	return dst, nil
+}
+
+// Composite alpha-composites the overlay over the destination image through the transform,
+// which maps the overlay coordinates to the destination coordinates.
+func Composite(dst *image.NRGBA, overlay *image.NRGBA, t align.Transform) {
+	// The bounding box of the transformed overlay.
+	ob := overlay.Bounds()
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range [][2]float64{
+		{float64(ob.Min.X), float64(ob.Min.Y)}, {float64(ob.Max.X), float64(ob.Min.Y)},
+		{float64(ob.Min.X), float64(ob.Max.Y)}, {float64(ob.Max.X), float64(ob.Max.Y)},
+	} {
+		x, y := t.Apply(p[0], p[1])
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	rect := image.Rect(
+		int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Ceil(maxX)), int(math.Ceil(maxY)),
+	).Intersect(dst.Bounds())
+
+	inv := t.Invert()
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			sx, sy := inv.Apply(float64(x), float64(y))
+			r, g, b, a := sample(overlay, sx, sy)
+			if a == 0 {
+				continue
+			}
+			// Blend the premultiplied overlay color over the destination pixel.
+			i := dst.PixOffset(x, y)
+			da := float64(dst.Pix[i+3]) / 255
+			outA := a + da*(1-a)
+			blend := func(src float64, dst uint8) uint8 {
+				return uint8(math.Round((src + float64(dst)/255*da*(1-a)) / outA * 255))
+			}
+			dst.Pix[i+0] = blend(r, dst.Pix[i+0])
+			dst.Pix[i+1] = blend(g, dst.Pix[i+1])
+			dst.Pix[i+2] = blend(b, dst.Pix[i+2])
+			dst.Pix[i+3] = uint8(math.Round(outA * 255))
+		}
+	}
+}
+
+// sample returns the bilinear interpolation of the premultiplied overlay color at (x, y), in the [0, 1] range.
+func sample(img *image.NRGBA, x, y float64) (r, g, b, a float64) {
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	fx, fy := x-float64(x0), y-float64(y0)
+
+	bounds := img.Bounds()
+	for _, p := range []struct {
+		x, y int
+		w    float64
+	}{
+		{x0, y0, (1 - fx) * (1 - fy)},
+		{x0 + 1, y0, fx * (1 - fy)},
+		{x0, y0 + 1, (1 - fx) * fy},
+		{x0 + 1, y0 + 1, fx * fy},
+	} {
+		if p.w == 0 || !image.Pt(p.x, p.y).In(bounds) {
+			continue
+		}
+		c := img.NRGBAAt(p.x, p.y)
+		ca := float64(c.A) / 255 * p.w
+		r += float64(c.R) / 255 * ca
+		g += float64(c.G) / 255 * ca
+		b += float64(c.B) / 255 * ca
+		a += ca
+	}
+	return
+}
+
+// facePoint returns the position of the named point of the face.
+func facePoint(face pigo.Face, name string) (complex128, bool) {
+	var p *pigo.Puploc
+	switch name {
+	case pigo.LeftPupil:
+		p = face.LeftEye
+	case pigo.RightPupil:
+		p = face.RightEye
+	default:
+		if lp, ok := face.Landmark(name); ok {
+			p = &lp.Puploc
+		}
+	}
+	if p == nil {
+		return 0, false
+	}
+	return complex(float64(p.Col), float64(p.Row)), true
+}
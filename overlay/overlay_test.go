@@ -0,0 +1,105 @@
+package overlay_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"testing"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/overlay"
+)
+
+// pupilFace returns a face with the pupils placed at the provided positions.
+func pupilFace(lx, ly, rx, ry int) pigo.Face {
+	return pigo.Face{
+		LeftEye:  &pigo.Puploc{Row: ly, Col: lx},
+		RightEye: &pigo.Puploc{Row: ry, Col: rx},
+	}
+}
+
+func TestOverlay_TransformShouldMapTheAnchorsOnTheFace(t *testing.T) {
+	asset := overlay.Asset{Anchors: []overlay.Anchor{
+		{Point: pigo.LeftPupil, X: 10, Y: 20},
+		{Point: pigo.RightPupil, X: 50, Y: 20},
+	}}
+	// The eye line is rotated by 90 degrees and twice as long as the anchors distance.
+	tr, ok := asset.Transform(pupilFace(100, 100, 100, 180))
+	if !ok {
+		t.Fatal("expected the transform to be computed")
+	}
+	for _, tc := range []struct{ x, y, wantX, wantY float64 }{
+		{10, 20, 100, 100},
+		{50, 20, 100, 180},
+	} {
+		x, y := tr.Apply(tc.x, tc.y)
+		if math.Abs(x-tc.wantX) > 1e-9 || math.Abs(y-tc.wantY) > 1e-9 {
+			t.Errorf("expected (%v, %v) to map to (%v, %v), got: (%v, %v)", tc.x, tc.y, tc.wantX, tc.wantY, x, y)
+		}
+	}
+
+	face := pupilFace(100, 100, 100, 180)
+	face.RightEye = nil
+	if _, ok := asset.Transform(face); ok {
+		t.Errorf("expected no transform with a single detected anchor point")
+	}
+}
+
+func TestOverlay_ApplyShouldCompositeTheAsset(t *testing.T) {
+	// A half transparent red square with the anchors at its left and right edges.
+	img := image.NewNRGBA(image.Rect(0, 0, 20, 10))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.NRGBA{R: 255, A: 128}), image.Point{}, draw.Src)
+	asset := overlay.Asset{Image: img, Anchors: []overlay.Anchor{
+		{Point: pigo.LeftPupil, X: 0, Y: 5},
+		{Point: pigo.RightPupil, X: 20, Y: 5},
+	}}
+
+	src := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	dst, err := overlay.Apply(src, []pigo.Face{pupilFace(30, 50, 70, 50)}, asset)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := dst.NRGBAAt(50, 50); c.R != 255 || c.G < 120 || c.G > 135 || c.A != 255 {
+		t.Errorf("expected a half transparent red blended over white, got: %v", c)
+	}
+	if c := dst.NRGBAAt(50, 80); c != (color.NRGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("expected the pixels outside of the asset to be untouched, got: %v", c)
+	}
+	if src.NRGBAAt(50, 50).G != 255 {
+		t.Errorf("expected the source image to be left untouched")
+	}
+}
+
+func TestOverlay_ShouldValidateTheAnchors(t *testing.T) {
+	anchors, err := overlay.ParseAnchors("LeftPupil:130,140; RightPupil:372.5,140")
+	if err != nil || len(anchors) != 2 || anchors[1].Point != pigo.RightPupil || anchors[1].X != 372.5 {
+		t.Fatalf("unexpected anchors: %v, %v", anchors, err)
+	}
+	if _, err := overlay.ParseAnchors("LeftPupil=130,140"); err == nil {
+		t.Errorf("expected an error on the malformed anchor")
+	}
+
+	src := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	asset := overlay.Asset{Image: src, Anchors: anchors[:1]}
+	if _, err := overlay.Apply(src, nil, asset); err != overlay.ErrTooFewAnchors {
+		t.Errorf("expected the too few anchors error, got: %v", err)
+	}
+	asset.Anchors = append(anchors, overlay.Anchor{Point: "Chin"})
+	if _, err := overlay.Apply(src, nil, asset); err == nil || !strings.Contains(err.Error(), "Chin") {
+		t.Errorf("expected the unknown anchor point error, got: %v", err)
+	}
+}
+
+func TestOverlay_LoadAssetShouldReadTheAnchors(t *testing.T) {
+	asset, err := overlay.LoadAsset("../examples/masquerade/images/sunglasses.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(asset.Anchors) != 2 || asset.Image.Bounds().Dx() == 0 {
+		t.Errorf("unexpected asset: %d anchors, %v", len(asset.Anchors), asset.Image.Bounds())
+	}
+}
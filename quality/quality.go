@@ -0,0 +1,294 @@
+// Package quality assesses whether the analyzed faces are good enough for further processing, like face enrollment.
+//
+// The assessment measures the sharpness, exposure and contrast of the face region, the face size,
+// the interocular distance, the frontalness and the in-plane rotation of the face. Each metric is checked
+// against its threshold and the metrics are combined into a weighted overall score between 0 and 1.
+package quality
+
+import (
+	"image"
+	"math"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// Reason describes why a face failed the quality assessment.
+type Reason string
+
+// The quality assessment failure reasons.
+const (
+	Blurry      Reason = "blurry"
+	Dark        Reason = "dark"
+	Bright      Reason = "bright"
+	LowContrast Reason = "low_contrast"
+	Small       Reason = "small"
+	ShortIOD    Reason = "short_iod"
+	NotFrontal  Reason = "not_frontal"
+	Rotated     Reason = "rotated"
+	LowScore    Reason = "low_score"
+)
+
+// Metrics holds the quality measurements of a face.
+// Sharpness: the variance of the Laplacian over the face region. Blurry faces have low values.
+// Brightness: the mean intensity of the face region, between 0 and 1.
+// Contrast: the standard deviation of the face region intensity (RMS contrast), between 0 and 1.
+// Size: the size of the face detection in pixels.
+// IOD: the interocular distance in pixels. It's 0 if any of the pupils was not localized.
+// Frontalness: 1 for a frontal face, decreasing as the face turns sideways. It's estimated from the symmetry of the
+// facial landmark points around the nose tip and it's NaN if the nose tip or all the symmetric point pairs are missing.
+// Rotation: the in-plane rotation of the eye line in degrees, positive clockwise. It's NaN if any of the pupils is missing.
+type Metrics struct {
+	Sharpness   float64
+	Brightness  float64
+	Contrast    float64
+	Size        int
+	IOD         float64
+	Frontalness float64
+	Rotation    float64
+}
+
+// Weights contains the contribution of each metric to the overall quality score.
+type Weights struct {
+	Sharpness   float64
+	Exposure    float64
+	Contrast    float64
+	Size        float64
+	IOD         float64
+	Frontalness float64
+	Rotation    float64
+}
+
+// Params contains the quality assessment thresholds. A zero threshold disables the related check.
+// MinSharpness: the minimum variance of the Laplacian.
+// MinBrightness, MaxBrightness: the accepted range of the mean intensity.
+// MinContrast: the minimum RMS contrast.
+// MinSize: the minimum face size in pixels.
+// MinIOD: the minimum interocular distance in pixels.
+// MinFrontalness: the minimum frontalness. The faces with unknown frontalness fail the check.
+// MaxRotation: the maximum absolute in-plane rotation in degrees. The faces with unknown rotation fail the check.
+// MinScore: the minimum overall quality score.
+// Weights: the contribution of each metric to the overall quality score.
+type Params struct {
+	MinSharpness   float64
+	MinBrightness  float64
+	MaxBrightness  float64
+	MinContrast    float64
+	MinSize        int
+	MinIOD         float64
+	MinFrontalness float64
+	MaxRotation    float64
+	MinScore       float64
+	Weights        Weights
+}
+
+// DefaultParams returns the default quality assessment thresholds, suited for face enrollment.
+func DefaultParams() Params {
+	return Params{
+		MinSharpness:   50,
+		MinBrightness:  0.25,
+		MaxBrightness:  0.8,
+		MinContrast:    0.08,
+		MinSize:        80,
+		MinIOD:         30,
+		MinFrontalness: 0.75,
+		MaxRotation:    15,
+		MinScore:       0.5,
+		Weights: Weights{
+			Sharpness:   1,
+			Exposure:    1,
+			Contrast:    1,
+			Size:        1,
+			IOD:         1,
+			Frontalness: 1,
+			Rotation:    1,
+		},
+	}
+}
+
+// Assessment holds the quality metrics of a face, the overall quality score
+// and the reasons for which the face failed the assessment, if any.
+type Assessment struct {
+	Metrics
+	Score   float64
+	Pass    bool
+	Reasons []Reason
+}
+
+// Assess measures the quality of the face detected on the grayscale image and evaluates it.
+func Assess(face pigo.Face, img pigo.ImageParams, p Params) Assessment {
+	return p.Evaluate(Measure(face, img))
+}
+
+// Measure returns the quality metrics of the face detected on the grayscale image.
+func Measure(face pigo.Face, img pigo.ImageParams) Metrics {
+	m := Metrics{
+		Size:        face.Detection.Scale,
+		Frontalness: frontalness(face),
+		Rotation:    math.NaN(),
+	}
+	m.Sharpness, m.Brightness, m.Contrast = regionStats(face.Box, img)
+
+	if l, r := face.LeftEye, face.RightEye; l != nil && r != nil {
+		dx, dy := float64(r.Col-l.Col), float64(r.Row-l.Row)
+		m.IOD = math.Hypot(dx, dy)
+		m.Rotation = math.Atan2(dy, dx) * 180 / math.Pi
+	}
+	return m
+}
+
+// Evaluate checks the metrics against the thresholds and computes the overall quality score.
+func (p Params) Evaluate(m Metrics) Assessment {
+	a := Assessment{Metrics: m}
+
+	check := func(failed bool, reason Reason) {
+		if failed {
+			a.Reasons = append(a.Reasons, reason)
+		}
+	}
+	check(p.MinSharpness > 0 && m.Sharpness < p.MinSharpness, Blurry)
+	check(p.MinBrightness > 0 && m.Brightness < p.MinBrightness, Dark)
+	check(p.MaxBrightness > 0 && m.Brightness > p.MaxBrightness, Bright)
+	check(p.MinContrast > 0 && m.Contrast < p.MinContrast, LowContrast)
+	check(p.MinSize > 0 && m.Size < p.MinSize, Small)
+	check(p.MinIOD > 0 && m.IOD < p.MinIOD, ShortIOD)
+	check(p.MinFrontalness > 0 && !(m.Frontalness >= p.MinFrontalness), NotFrontal)
+	check(p.MaxRotation > 0 && !(math.Abs(m.Rotation) <= p.MaxRotation), Rotated)
+
+	a.Score = p.score(m)
+	check(p.MinScore > 0 && a.Score < p.MinScore, LowScore)
+	a.Pass = len(a.Reasons) == 0
+
+	return a
+}
+
+// score returns the weighted mean of the metric scores. Each metric score is between 0 and 1 and it's 0.5
+// when the metric is right at its threshold. The unknown metrics are left out of the mean.
+func (p Params) score(m Metrics) float64 {
+	var sum, total float64
+	add := func(weight, score float64) {
+		if weight > 0 && !math.IsNaN(score) {
+			sum += weight * clamp(score)
+			total += weight
+		}
+	}
+	add(p.Weights.Sharpness, ramp(m.Sharpness, p.MinSharpness))
+	add(p.Weights.Contrast, ramp(m.Contrast, p.MinContrast))
+	add(p.Weights.Size, ramp(float64(m.Size), float64(p.MinSize)))
+	add(p.Weights.IOD, ramp(m.IOD, p.MinIOD))
+	add(p.Weights.Frontalness, m.Frontalness)
+
+	// The exposure score is 1 in the middle of the accepted brightness range and 0.5 at its limits.
+	if lo, hi := p.MinBrightness, p.MaxBrightness; hi > lo {
+		mid, half := (lo+hi)/2, (hi-lo)/2
+		add(p.Weights.Exposure, 1-0.5*math.Abs(m.Brightness-mid)/half)
+	}
+	if p.MaxRotation > 0 {
+		add(p.Weights.Rotation, 1-0.5*math.Abs(m.Rotation)/p.MaxRotation)
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
+
+// ramp returns the score of a metric which should be above the threshold, reaching 1 at twice the threshold.
+func ramp(v, threshold float64) float64 {
+	if threshold <= 0 {
+		return 1
+	}
+	return v / (2 * threshold)
+}
+
+// regionStats returns the variance of the Laplacian, the mean and the standard deviation
+// of the image intensity over the region. The mean and the standard deviation are scaled to the [0, 1] range.
+func regionStats(region image.Rectangle, img pigo.ImageParams) (sharpness, mean, stddev float64) {
+	x0, y0 := max(region.Min.X, 0), max(region.Min.Y, 0)
+	x1, y1 := min(region.Max.X, img.Cols), min(region.Max.Y, img.Rows)
+	if x1-x0 < 3 || y1-y0 < 3 {
+		return 0, 0, 0
+	}
+	at := func(x, y int) float64 {
+		return float64(img.Pixels[y*img.Dim+x])
+	}
+
+	var sum, sumSq, lapSum, lapSumSq float64
+	var n, ln int
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			v := at(x, y)
+			sum += v
+			sumSq += v * v
+			n++
+			// The Laplacian is computed on the inner pixels only.
+			if x > x0 && x < x1-1 && y > y0 && y < y1-1 {
+				lap := at(x-1, y) + at(x+1, y) + at(x, y-1) + at(x, y+1) - 4*v
+				lapSum += lap
+				lapSumSq += lap * lap
+				ln++
+			}
+		}
+	}
+	mean = sum / float64(n)
+	stddev = math.Sqrt(math.Max(0, sumSq/float64(n)-mean*mean))
+	lapMean := lapSum / float64(ln)
+	sharpness = lapSumSq/float64(ln) - lapMean*lapMean
+
+	return sharpness, mean / 255, stddev / 255
+}
+
+// symmetricPairs are the pairs of landmark points which are symmetric on a frontal face.
+var symmetricPairs = [][2]string{
+	{pigo.LeftEyeOuterCorner, pigo.RightEyeOuterCorner},
+	{pigo.LeftEyeInnerCorner, pigo.RightEyeInnerCorner},
+	{pigo.LeftEyebrowOuter, pigo.RightEyebrowOuter},
+	{pigo.LeftEyebrowInner, pigo.RightEyebrowInner},
+	{pigo.MouthLeft, pigo.MouthRight},
+}
+
+// frontalness estimates how frontal the face is from the symmetry of the facial landmark points around the nose tip.
+// Since the nose tip sticks out of the face, it moves towards one side of the face as the head turns.
+// For each symmetric pair of points, the asymmetry is the difference of their distances from the nose tip,
+// measured along the line joining them, divided by their distance. The frontalness is 1 minus the mean asymmetry.
+func frontalness(face pigo.Face) float64 {
+	nose, ok := face.Landmark(pigo.NoseTip)
+	if !ok {
+		return math.NaN()
+	}
+	type point struct{ x, y float64 }
+	var pairs [][2]point
+	if l, r := face.LeftEye, face.RightEye; l != nil && r != nil {
+		pairs = append(pairs, [2]point{{float64(l.Col), float64(l.Row)}, {float64(r.Col), float64(r.Row)}})
+	}
+	for _, names := range symmetricPairs {
+		l, lok := face.Landmark(names[0])
+		r, rok := face.Landmark(names[1])
+		if lok && rok {
+			pairs = append(pairs, [2]point{{float64(l.Col), float64(l.Row)}, {float64(r.Col), float64(r.Row)}})
+		}
+	}
+
+	var asym float64
+	var n int
+	for _, pair := range pairs {
+		l, r := pair[0], pair[1]
+		dx, dy := r.x-l.x, r.y-l.y
+		d := math.Hypot(dx, dy)
+		if d == 0 {
+			continue
+		}
+		// The position of the nose tip projected on the line joining the points, 0 at the left point and 1 at the right one.
+		t := ((float64(nose.Col)-l.x)*dx + (float64(nose.Row)-l.y)*dy) / (d * d)
+		asym += math.Abs(t - (1 - t))
+		n++
+	}
+	if n == 0 {
+		return math.NaN()
+	}
+	return clamp(1 - asym/float64(n))
+}
+
+// clamp limits the value to the [0, 1] range.
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
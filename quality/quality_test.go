@@ -0,0 +1,121 @@
+package quality_test
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/quality"
+)
+
+// grayImage returns a grayscale image filled by the provided function.
+func grayImage(size int, fill func(x, y int) uint8) pigo.ImageParams {
+	pixels := make([]uint8, size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			pixels[y*size+x] = fill(x, y)
+		}
+	}
+	return pigo.ImageParams{Pixels: pixels, Rows: size, Cols: size, Dim: size}
+}
+
+// frontalFace returns a frontal face centered at (100, 100) with the pupils, the eye corners,
+// the mouth corners and the nose tip placed at the provided horizontal offset.
+func frontalFace(noseOffset int) pigo.Face {
+	lp := func(name string, col, row int) pigo.Landmark {
+		return pigo.Landmark{Name: name, Puploc: pigo.Puploc{Row: row, Col: col}}
+	}
+	return pigo.Face{
+		Detection: pigo.Detection{Row: 100, Col: 100, Scale: 120},
+		Box:       image.Rect(40, 40, 160, 160),
+		LeftEye:   &pigo.Puploc{Row: 80, Col: 75},
+		RightEye:  &pigo.Puploc{Row: 80, Col: 125},
+		Landmarks: []pigo.Landmark{
+			lp(pigo.LeftEyeOuterCorner, 65, 80),
+			lp(pigo.RightEyeOuterCorner, 135, 80),
+			lp(pigo.MouthLeft, 80, 130),
+			lp(pigo.MouthRight, 120, 130),
+			lp(pigo.NoseTip, 100+noseOffset, 110),
+		},
+	}
+}
+
+func TestQuality_ShouldMeasureTheFaceRegion(t *testing.T) {
+	face := frontalFace(0)
+	sharp := quality.Measure(face, grayImage(200, func(x, y int) uint8 {
+		return uint8(60 + 120*((x/2+y/2)%2))
+	}))
+	flat := quality.Measure(face, grayImage(200, func(x, y int) uint8 {
+		return 30
+	}))
+
+	if sharp.Sharpness <= flat.Sharpness || flat.Sharpness != 0 {
+		t.Errorf("expected the textured region to be sharper, got: %v and %v", sharp.Sharpness, flat.Sharpness)
+	}
+	if math.Abs(sharp.Brightness-120.0/255) > 0.01 || math.Abs(sharp.Contrast-60.0/255) > 0.01 {
+		t.Errorf("unexpected exposure, brightness: %v, contrast: %v", sharp.Brightness, sharp.Contrast)
+	}
+	if sharp.IOD != 50 || sharp.Rotation != 0 || sharp.Size != 120 {
+		t.Errorf("unexpected geometry, iod: %v, rotation: %v, size: %v", sharp.IOD, sharp.Rotation, sharp.Size)
+	}
+
+	a := quality.DefaultParams().Evaluate(flat)
+	if a.Pass || !hasReason(a, quality.Blurry) || !hasReason(a, quality.Dark) || !hasReason(a, quality.LowContrast) {
+		t.Errorf("expected the flat dark region to fail, got: %v", a.Reasons)
+	}
+	if a := quality.DefaultParams().Evaluate(sharp); !a.Pass || a.Score < 0.8 {
+		t.Errorf("expected the sharp region to pass, got score: %v, reasons: %v", a.Score, a.Reasons)
+	}
+}
+
+func TestQuality_FrontalnessShouldDecreaseWithTheNoseOffset(t *testing.T) {
+	img := grayImage(200, func(x, y int) uint8 { return uint8(x + y) })
+
+	frontal := quality.Measure(frontalFace(0), img).Frontalness
+	turned := quality.Measure(frontalFace(12), img).Frontalness
+	if frontal != 1 || turned >= 0.75 {
+		t.Errorf("unexpected frontalness, frontal: %v, turned: %v", frontal, turned)
+	}
+
+	face := frontalFace(0)
+	face.Landmarks = nil
+	face.RightEye = nil
+	m := quality.Measure(face, img)
+	if !math.IsNaN(m.Frontalness) || !math.IsNaN(m.Rotation) || m.IOD != 0 {
+		t.Fatalf("expected unknown geometry without landmarks, got: %+v", m)
+	}
+	a := quality.DefaultParams().Evaluate(m)
+	if !hasReason(a, quality.NotFrontal) || !hasReason(a, quality.Rotated) || !hasReason(a, quality.ShortIOD) {
+		t.Errorf("expected the unknown geometry to fail the checks, got: %v", a.Reasons)
+	}
+	if math.IsNaN(a.Score) {
+		t.Errorf("expected the unknown metrics to be left out of the score")
+	}
+}
+
+func TestQuality_DisabledThresholdsShouldNotFail(t *testing.T) {
+	p := quality.DefaultParams()
+	p.MinSize, p.MaxRotation, p.MinScore = 0, 0, 0
+
+	face := frontalFace(0)
+	face.Detection.Scale = 10
+	face.RightEye.Row = 120
+	img := grayImage(200, func(x, y int) uint8 { return uint8(60 + 120*((x/2+y/2)%2)) })
+
+	if a := quality.Assess(face, img, p); !a.Pass {
+		t.Errorf("expected the disabled checks to be skipped, got: %v", a.Reasons)
+	}
+	if a := quality.Assess(face, img, quality.DefaultParams()); !hasReason(a, quality.Small) || !hasReason(a, quality.Rotated) {
+		t.Errorf("expected the small rotated face to fail, got: %v", a.Reasons)
+	}
+}
+
+func hasReason(a quality.Assessment, reason quality.Reason) bool {
+	for _, r := range a.Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
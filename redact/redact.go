@@ -0,0 +1,244 @@
+// Package redact anonymizes the detected faces by blurring, pixelating or filling their regions.
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"math"
+
+	"github.com/disintegration/imaging"
+	pigo "github.com/esimov/pigo/core"
+)
+
+// Method is the anonymization method applied over the face regions.
+type Method string
+
+// The supported anonymization methods.
+const (
+	Blur     Method = "blur"
+	Pixelate Method = "pixelate"
+	Fill     Method = "fill"
+)
+
+// Shape is the shape of the redacted face regions.
+type Shape string
+
+// The supported face region shapes.
+const (
+	Box     Shape = "box"
+	Ellipse Shape = "ellipse"
+)
+
+// Params contains the redaction settings.
+// Method: the anonymization method.
+// Shape: the shape of the face regions. The ellipses are taller than wide, following the shape of the face.
+// Padding: the margin added around the detected face, as a fraction of the face size.
+// Feather: the width of the soft region edges, as a fraction of the face size. 0 means hard edges.
+// Strength: the Gaussian blur sigma or the pixelation block size, as a fraction of the face size.
+// Color: the color used by the fill method.
+type Params struct {
+	Method   Method
+	Shape    Shape
+	Padding  float64
+	Feather  float64
+	Strength float64
+	Color    color.Color
+}
+
+// DefaultParams returns the default redaction settings: a strong Gaussian blur over elliptical regions.
+func DefaultParams() Params {
+	return Params{
+		Method:   Blur,
+		Shape:    Ellipse,
+		Padding:  0.1,
+		Feather:  0.05,
+		Strength: 0.1,
+		Color:    color.Black,
+	}
+}
+
+// The errors returned by Validate.
+var (
+	// ErrUnknownMethod is returned when the redaction method is not supported.
+	ErrUnknownMethod = errors.New("unknown redaction method")
+	// ErrUnknownShape is returned when the shape of the face regions is not supported.
+	ErrUnknownShape = errors.New("unknown redaction shape")
+	// ErrNegativeParam is returned when the padding, the feather or the strength is negative.
+	ErrNegativeParam = errors.New("the padding, the feather and the strength should not be negative")
+	// ErrMissingColor is returned when the fill method has no color to fill the face regions with.
+	ErrMissingColor = errors.New("the fill method requires a color")
+)
+
+// Validate checks whether the redaction settings are supported.
+func (p Params) Validate() error {
+	var errs []error
+	switch p.Method {
+	case Blur, Pixelate, Fill:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %s", ErrUnknownMethod, p.Method))
+	}
+	switch p.Shape {
+	case Box, Ellipse:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %s", ErrUnknownShape, p.Shape))
+	}
+	if p.Padding < 0 || p.Feather < 0 || p.Strength < 0 {
+		errs = append(errs, ErrNegativeParam)
+	}
+	if p.Method == Fill && p.Color == nil {
+		errs = append(errs, ErrMissingColor)
+	}
+	return errors.Join(errs...)
+}
+
+// Apply returns a copy of the image with the regions of the detected faces redacted.
+func Apply(img image.Image, dets []pigo.Detection, p Params) (*image.NRGBA, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	src := pigo.ImgToNRGBA(img)
+	// The source might be a sub-image sharing the pixels of a larger image, so it's copied row by row.
+	dst := image.NewNRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+
+	for _, det := range dets {
+		redactRegion(dst, src, det, p)
+	}
+	return dst, nil
+}
+
+// GIF redacts the frames of an animated GIF. The detect function is called on each composited frame
+// and the first error it returns aborts the redaction, so that no partially redacted GIF is produced.
+// The frames of the returned GIF cover the whole canvas and they are quantized to the palettes of the source GIF.
+func GIF(g *gif.GIF, detect func(*image.NRGBA) ([]pigo.Detection, error), p Params) (*gif.GIF, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	out := &gif.GIF{
+		Delay:           g.Delay,
+		LoopCount:       g.LoopCount,
+		Config:          g.Config,
+		BackgroundIndex: g.BackgroundIndex,
+	}
+
+	for i, frame := range pigo.GIFFrames(g) {
+		dets, err := detect(frame)
+		if err != nil {
+			return nil, err
+		}
+		redacted, err := Apply(frame, dets, p)
+		if err != nil {
+			return nil, err
+		}
+		paletted := image.NewPaletted(redacted.Bounds(), pigo.GIFPalette(g, i))
+		draw.FloydSteinberg.Draw(paletted, redacted.Bounds(), redacted, redacted.Bounds().Min)
+
+		out.Image = append(out.Image, paletted)
+		out.Disposal = append(out.Disposal, gif.DisposalNone)
+	}
+	// The frames are full size, so the global color table is not needed.
+	out.Config.ColorModel = nil
+	return out, nil
+}
+
+// redactRegion blends the anonymized face region into the destination image through the region mask.
+func redactRegion(dst, src *image.NRGBA, det pigo.Detection, p Params) {
+	size := float64(det.Scale)
+	halfW := size * (1 + 2*p.Padding) / 2
+	halfH := halfW
+	if p.Shape == Ellipse {
+		halfH *= 1.25
+	}
+	cx, cy := float64(det.Col), float64(det.Row)
+
+	rect := image.Rect(
+		int(math.Floor(cx-halfW)), int(math.Floor(cy-halfH)),
+		int(math.Ceil(cx+halfW)), int(math.Ceil(cy+halfH)),
+	).Intersect(dst.Bounds())
+	if rect.Empty() {
+		return
+	}
+
+	effect := anonymize(src, rect, size, p)
+	feather := p.Feather * size
+
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			// The distance of the pixel from the region edge, positive inside the region.
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			var d float64
+			if p.Shape == Ellipse {
+				r := math.Hypot(dx/halfW, dy/halfH)
+				d = (1 - r) * math.Min(halfW, halfH)
+			} else {
+				d = math.Min(halfW-math.Abs(dx), halfH-math.Abs(dy))
+			}
+			if d <= 0 {
+				continue
+			}
+			alpha := 1.0
+			if feather > 0 {
+				alpha = math.Min(1, d/feather)
+			}
+
+			di := dst.PixOffset(x, y)
+			ei := effect.PixOffset(x-rect.Min.X, y-rect.Min.Y)
+			for c := 0; c < 4; c++ {
+				v := float64(dst.Pix[di+c])*(1-alpha) + float64(effect.Pix[ei+c])*alpha
+				dst.Pix[di+c] = uint8(math.Round(v))
+			}
+		}
+	}
+}
+
+// anonymize returns the anonymized content of the region, with its min-point at (0, 0).
+func anonymize(src *image.NRGBA, rect image.Rectangle, size float64, p Params) *image.NRGBA {
+	switch p.Method {
+	case Fill:
+		return imaging.New(rect.Dx(), rect.Dy(), p.Color)
+	case Pixelate:
+		return pixelate(src, rect, int(math.Max(2, math.Round(p.Strength*size))))
+	}
+	// Blur a larger area than the region, so the pixels outside of it are taken into account at the edges.
+	sigma := math.Max(1, p.Strength*size)
+	margin := int(math.Ceil(3 * sigma))
+	area := rect.Inset(-margin).Intersect(src.Bounds())
+	blurred := imaging.Blur(src.SubImage(area), sigma)
+	offset := rect.Min.Sub(area.Min)
+
+	return imaging.Crop(blurred, image.Rectangle{Min: offset, Max: offset.Add(rect.Size())})
+}
+
+// pixelate replaces the blocks of the region with their mean color.
+func pixelate(src *image.NRGBA, rect image.Rectangle, block int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+
+	for by := 0; by < rect.Dy(); by += block {
+		for bx := 0; bx < rect.Dx(); bx += block {
+			cell := image.Rect(bx, by, bx+block, by+block).Intersect(dst.Bounds())
+
+			var sum [4]int
+			for y := cell.Min.Y; y < cell.Max.Y; y++ {
+				for x := cell.Min.X; x < cell.Max.X; x++ {
+					si := src.PixOffset(rect.Min.X+x, rect.Min.Y+y)
+					for c := 0; c < 4; c++ {
+						sum[c] += int(src.Pix[si+c])
+					}
+				}
+			}
+			n := cell.Dx() * cell.Dy()
+			mean := color.NRGBA{
+				R: uint8(sum[0] / n),
+				G: uint8(sum[1] / n),
+				B: uint8(sum[2] / n),
+				A: uint8(sum[3] / n),
+			}
+			draw.Draw(dst, cell, image.NewUniform(mean), image.Point{}, draw.Src)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,168 @@
+package redact_test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"testing"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/redact"
+)
+
+// checkerboard returns a high contrast image which is visibly altered by all the redaction methods.
+func checkerboard(size int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			c := color.NRGBA{A: 255}
+			if (x/2+y/2)%2 == 0 {
+				c = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestRedact_ShouldAlterOnlyTheFaceRegions(t *testing.T) {
+	src := checkerboard(200)
+	det := pigo.Detection{Row: 60, Col: 60, Scale: 60}
+
+	for _, method := range []redact.Method{redact.Blur, redact.Pixelate, redact.Fill} {
+		for _, shape := range []redact.Shape{redact.Box, redact.Ellipse} {
+			p := redact.DefaultParams()
+			p.Method, p.Shape, p.Feather = method, shape, 0
+
+			dst, err := redact.Apply(src, []pigo.Detection{det}, p)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dst.NRGBAAt(60, 60) == src.NRGBAAt(60, 60) && dst.NRGBAAt(61, 61) == src.NRGBAAt(61, 61) {
+				t.Errorf("%s/%s: expected the face center to be redacted", method, shape)
+			}
+			if dst.NRGBAAt(150, 150) != src.NRGBAAt(150, 150) {
+				t.Errorf("%s/%s: expected the pixels outside of the face to be untouched", method, shape)
+			}
+			// The corners of the padded box are outside of the ellipse.
+			if corner := dst.NRGBAAt(29, 29) == src.NRGBAAt(29, 29); corner != (shape == redact.Ellipse) && method == redact.Fill {
+				t.Errorf("%s/%s: unexpected redaction of the region corner", method, shape)
+			}
+		}
+	}
+}
+
+func TestRedact_FeatherShouldBlendTheEdges(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	p := redact.DefaultParams()
+	p.Method, p.Shape, p.Padding, p.Feather = redact.Fill, redact.Box, 0, 0.2
+
+	dst, err := redact.Apply(src, []pigo.Detection{{Row: 50, Col: 50, Scale: 50}}, p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	edge, center := dst.NRGBAAt(26, 50).R, dst.NRGBAAt(50, 50).R
+	if center != 0 || edge == 0 || edge == 255 {
+		t.Errorf("expected a soft edge around a solid center, got edge: %d, center: %d", edge, center)
+	}
+}
+
+func TestRedact_ShouldRejectUnknownMethods(t *testing.T) {
+	p := redact.DefaultParams()
+	p.Method = "swirl"
+	if _, err := redact.Apply(checkerboard(10), nil, p); !errors.Is(err, redact.ErrUnknownMethod) {
+		t.Errorf("expected an unknown method error, got: %v", err)
+	}
+
+	p = redact.DefaultParams()
+	p.Shape = "star"
+	if err := p.Validate(); !errors.Is(err, redact.ErrUnknownShape) || errors.Is(err, redact.ErrUnknownMethod) {
+		t.Errorf("expected an unknown shape error, got: %v", err)
+	}
+}
+
+func TestRedact_ShouldRejectNegativeParams(t *testing.T) {
+	for name, set := range map[string]func(*redact.Params){
+		"padding":  func(p *redact.Params) { p.Padding = -0.6 },
+		"feather":  func(p *redact.Params) { p.Feather = -0.1 },
+		"strength": func(p *redact.Params) { p.Strength = -1 },
+	} {
+		p := redact.DefaultParams()
+		set(&p)
+		if err := p.Validate(); !errors.Is(err, redact.ErrNegativeParam) {
+			t.Errorf("%s: expected a negative parameter error, got: %v", name, err)
+		}
+	}
+	if err := redact.DefaultParams().Validate(); err != nil {
+		t.Errorf("the default parameters should be valid, got: %v", err)
+	}
+}
+
+func TestRedact_FillShouldRequireAColor(t *testing.T) {
+	p := redact.DefaultParams()
+	p.Method, p.Color = redact.Fill, nil
+	if err := p.Validate(); !errors.Is(err, redact.ErrMissingColor) {
+		t.Errorf("expected a missing color error, got: %v", err)
+	}
+	if _, err := redact.Apply(checkerboard(50), []pigo.Detection{{Row: 25, Col: 25, Scale: 20}}, p); !errors.Is(err, redact.ErrMissingColor) {
+		t.Errorf("expected a missing color error, got: %v", err)
+	}
+	// The color is used only by the fill method.
+	p.Method = redact.Blur
+	if err := p.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRedact_ShouldCopySubImages(t *testing.T) {
+	// The zero origin sub-image shares the rows of the larger image.
+	src := checkerboard(100).SubImage(image.Rect(0, 0, 50, 50)).(*image.NRGBA)
+	dst, err := redact.Apply(src, nil, redact.DefaultParams())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dst.Bounds() != src.Bounds() {
+		t.Fatalf("expected the %v bounds, got %v", src.Bounds(), dst.Bounds())
+	}
+	for y := 0; y < 50; y++ {
+		for x := 0; x < 50; x++ {
+			if dst.NRGBAAt(x, y) != src.NRGBAAt(x, y) {
+				t.Fatalf("the pixel at %d,%d differs from the source image", x, y)
+			}
+		}
+	}
+}
+
+func TestRedact_GIFShouldFailClosed(t *testing.T) {
+	frame := image.NewPaletted(image.Rect(0, 0, 50, 50), palette.Plan9)
+	draw.Draw(frame, frame.Bounds(), checkerboard(50), image.Point{}, draw.Src)
+	g := &gif.GIF{
+		Image:    []*image.Paletted{frame, frame},
+		Delay:    []int{10, 10},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalNone},
+		Config:   image.Config{Width: 50, Height: 50, ColorModel: color.Palette(palette.Plan9)},
+	}
+
+	calls := 0
+	detect := func(img *image.NRGBA) ([]pigo.Detection, error) {
+		calls++
+		return []pigo.Detection{{Row: 25, Col: 25, Scale: 20}}, nil
+	}
+	out, err := redact.GIF(g, detect, redact.DefaultParams())
+	if err != nil || calls != 2 || len(out.Image) != 2 || out.Delay[1] != 10 {
+		t.Fatalf("expected both frames to be redacted, got: %v, %d calls", err, calls)
+	}
+
+	failure := errors.New("detection failed")
+	detect = func(img *image.NRGBA) ([]pigo.Detection, error) {
+		return nil, failure
+	}
+	if out, err := redact.GIF(g, detect, redact.DefaultParams()); out != nil || !errors.Is(err, failure) {
+		t.Errorf("expected no output on detection error, got: %v, %v", out, err)
+	}
+}
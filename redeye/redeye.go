@@ -0,0 +1,223 @@
+// Package redeye detects and corrects the red-eye effect caused by the camera flash, using the localized pupils.
+//
+// The neighbourhood of each pupil is inspected for strongly red pixels. If enough of them are found,
+// a soft circular mask is built around their centroid and the masked pixels are desaturated and darkened,
+// proportionally to their redness, so that the surrounding skin and eyelids are left untouched.
+package redeye
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"math"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// Eye identifies the left or the right eye (from the viewer's perspective).
+type Eye string
+
+// The eyes of a face.
+const (
+	LeftEye  Eye = "left"
+	RightEye Eye = "right"
+)
+
+// Params contains the red-eye detection and correction settings.
+// SearchRadius: the radius of the inspected pupil neighbourhood, as a fraction of the pupil scale.
+// Redness: the minimum redness of the red-eye pixels, measured as (R - max(G, B)) / R, between 0 and 1.
+// MinRed: the minimum red channel value of the red-eye pixels. Darker pixels are never considered red.
+// MinCoverage: the minimum fraction of red pixels within the neighbourhood for the eye to be corrected.
+// Feather: the width of the soft mask edge, as a fraction of the mask radius.
+// Darken: the brightness of the corrected pixels relative to their green and blue channels. Lower values darken more.
+type Params struct {
+	SearchRadius float64
+	Redness      float64
+	MinRed       uint8
+	MinCoverage  float64
+	Feather      float64
+	Darken       float64
+}
+
+// DefaultParams returns the default red-eye detection and correction settings.
+func DefaultParams() Params {
+	return Params{
+		SearchRadius: 0.5,
+		Redness:      0.4,
+		MinRed:       50,
+		MinCoverage:  0.05,
+		Feather:      0.3,
+		Darken:       0.8,
+	}
+}
+
+// The errors returned by Validate.
+var (
+	// ErrInvalidRedness is returned when the redness threshold is not within the (0, 1] range.
+	ErrInvalidRedness = errors.New("the redness should be within the (0, 1] range")
+	// ErrInvalidDarken is returned when the brightness of the corrected pixels is not within the [0, 1] range.
+	ErrInvalidDarken = errors.New("the darkening should be within the [0, 1] range")
+	// ErrNegativeParam is returned when the search radius, the coverage or the feather is negative.
+	ErrNegativeParam = errors.New("the search radius, the coverage and the feather should not be negative")
+)
+
+// Validate checks whether the red-eye detection and correction settings are usable.
+func (p Params) Validate() error {
+	var errs []error
+	if p.Redness <= 0 || p.Redness > 1 {
+		errs = append(errs, ErrInvalidRedness)
+	}
+	if p.Darken < 0 || p.Darken > 1 {
+		errs = append(errs, ErrInvalidDarken)
+	}
+	if p.SearchRadius < 0 || p.MinCoverage < 0 || p.Feather < 0 {
+		errs = append(errs, ErrNegativeParam)
+	}
+	return errors.Join(errs...)
+}
+
+// Report describes the red-eye detection and correction of an eye.
+// Face: the index of the face the eye belongs to.
+// Center: the centroid of the red pixels, or the pupil position if the eye was not corrected.
+// Radius: the radius of the correction mask in pixels. It's 0 if the eye was not corrected.
+// Coverage: the fraction of red pixels found within the pupil neighbourhood.
+// Corrected: the eye was affected by the red-eye effect and it was corrected.
+// Pixels: the number of modified pixels.
+type Report struct {
+	Face      int
+	Eye       Eye
+	Center    image.Point
+	Radius    float64
+	Coverage  float64
+	Corrected bool
+	Pixels    int
+}
+
+// Correct returns a copy of the image with the red-eye effect removed from the localized pupils
+// of the faces, and a report for each inspected eye. The faces without localized pupils are skipped.
+// An error is returned if the settings are not valid.
+func Correct(img image.Image, faces []pigo.Face, p Params) (*image.NRGBA, []Report, error) {
+	if err := p.Validate(); err != nil {
+		return nil, nil, err
+	}
+	src := pigo.ImgToNRGBA(img)
+	// The source might be a sub-image sharing the pixels of a larger image, so it's copied row by row.
+	dst := image.NewNRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+
+	var reports []Report
+	for i, face := range faces {
+		for _, eye := range []struct {
+			eye   Eye
+			pupil *pigo.Puploc
+		}{
+			{LeftEye, face.LeftEye},
+			{RightEye, face.RightEye},
+		} {
+			if eye.pupil == nil {
+				continue
+			}
+			r := correctEye(dst, src, *eye.pupil, p)
+			r.Face, r.Eye = i, eye.eye
+			reports = append(reports, r)
+		}
+	}
+	return dst, reports, nil
+}
+
+// Redness returns the redness of the color: the fraction of the red channel exceeding the other channels.
+func Redness(r, g, b uint8) float64 {
+	if r == 0 {
+		return 0
+	}
+	return math.Max(0, float64(int(r)-int(max(g, b)))/float64(r))
+}
+
+// correctEye inspects the neighbourhood of the pupil on the source image and corrects the red pixels on the destination image.
+func correctEye(dst, src *image.NRGBA, pupil pigo.Puploc, p Params) Report {
+	report := Report{Center: image.Pt(pupil.Col, pupil.Row)}
+
+	search := math.Max(2, p.SearchRadius*float64(pupil.Scale))
+	cx, cy := float64(pupil.Col), float64(pupil.Row)
+	rect := image.Rect(
+		int(math.Floor(cx-search)), int(math.Floor(cy-search)),
+		int(math.Ceil(cx+search))+1, int(math.Ceil(cy+search))+1,
+	).Intersect(src.Bounds())
+
+	isRed := func(x, y int) bool {
+		c := src.NRGBAAt(x, y)
+		return c.R >= p.MinRed && Redness(c.R, c.G, c.B) >= p.Redness
+	}
+
+	// Find the red pixels within the circular neighbourhood and their centroid.
+	var total, red int
+	var sx, sy float64
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			if math.Hypot(float64(x)-cx, float64(y)-cy) > search {
+				continue
+			}
+			total++
+			if isRed(x, y) {
+				red++
+				sx += float64(x)
+				sy += float64(y)
+			}
+		}
+	}
+	if total == 0 {
+		return report
+	}
+	report.Coverage = float64(red) / float64(total)
+	if red == 0 || report.Coverage < p.MinCoverage {
+		return report
+	}
+
+	// The mask covers the area of the red pixels, with a margin for the soft edge.
+	mx, my := sx/float64(red), sy/float64(red)
+	radius := math.Min(search, math.Sqrt(float64(red)/math.Pi)*(1+p.Feather))
+	feather := math.Max(1, p.Feather*radius)
+
+	report.Center = image.Pt(int(math.Round(mx)), int(math.Round(my)))
+	report.Radius = radius
+	report.Corrected = true
+
+	rect = image.Rect(
+		int(math.Floor(mx-radius)), int(math.Floor(my-radius)),
+		int(math.Ceil(mx+radius))+1, int(math.Ceil(my+radius))+1,
+	).Intersect(src.Bounds())
+
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			d := radius - math.Hypot(float64(x)-mx, float64(y)-my)
+			if d <= 0 {
+				continue
+			}
+			c := src.NRGBAAt(x, y)
+			if c.R < p.MinRed {
+				continue
+			}
+			// The weight of the correction ramps up with the pixel redness, from half the threshold to the threshold.
+			alpha := math.Min(1, d/feather) * clamp((Redness(c.R, c.G, c.B)-p.Redness/2)/(p.Redness/2))
+			if alpha == 0 {
+				continue
+			}
+			// The red channel is replaced with the darkened mean of the green and blue channels.
+			v := (float64(c.G) + float64(c.B)) / 2 * p.Darken
+			blend := func(ch uint8) uint8 {
+				return uint8(math.Round(float64(ch)*(1-alpha) + v*alpha))
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = blend(c.R)
+			dst.Pix[i+1] = blend(c.G)
+			dst.Pix[i+2] = blend(c.B)
+			report.Pixels++
+		}
+	}
+	return report
+}
+
+// clamp limits the value to the [0, 1] range.
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,144 @@
+package redeye_test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/redeye"
+)
+
+var skin = color.NRGBA{R: 220, G: 170, B: 140, A: 255}
+
+// eyeImage returns a skin colored image with a red pupil drawn at (30, 30) and a dark pupil drawn at (90, 30).
+func eyeImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 120, 60))
+	draw.Draw(img, img.Bounds(), image.NewUniform(skin), image.Point{}, draw.Src)
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 120; x++ {
+			if math.Hypot(float64(x-30), float64(y-30)) < 6 {
+				img.SetNRGBA(x, y, color.NRGBA{R: 200, G: 40, B: 50, A: 255})
+			}
+			if math.Hypot(float64(x-90), float64(y-30)) < 6 {
+				img.SetNRGBA(x, y, color.NRGBA{R: 30, G: 25, B: 25, A: 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestRedEye_ShouldCorrectOnlyTheRedPupils(t *testing.T) {
+	src := eyeImage()
+	face := pigo.Face{
+		LeftEye:  &pigo.Puploc{Row: 31, Col: 29, Scale: 20},
+		RightEye: &pigo.Puploc{Row: 30, Col: 90, Scale: 20},
+	}
+	dst, reports, err := redeye.Correct(src, []pigo.Face{face}, redeye.DefaultParams())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(reports) != 2 {
+		t.Fatalf("expected a report for each eye, got: %d", len(reports))
+	}
+	left, right := reports[0], reports[1]
+	if left.Eye != redeye.LeftEye || !left.Corrected || left.Pixels == 0 {
+		t.Errorf("expected the red left eye to be corrected, got: %+v", left)
+	}
+	if left.Center != image.Pt(30, 30) || math.Abs(left.Radius-6) > 2 {
+		t.Errorf("expected the mask to be centered on the red pupil, got: %v, radius: %v", left.Center, left.Radius)
+	}
+	if right.Eye != redeye.RightEye || right.Corrected || right.Coverage != 0 {
+		t.Errorf("expected the dark right eye to be left untouched, got: %+v", right)
+	}
+
+	if c := dst.NRGBAAt(30, 30); redeye.Redness(c.R, c.G, c.B) > 0.1 || c.R > 60 {
+		t.Errorf("expected the red pupil to be desaturated and darkened, got: %v", c)
+	}
+	for _, p := range []image.Point{{30, 20}, {40, 30}, {90, 30}} {
+		if c := dst.NRGBAAt(p.X, p.Y); c != src.NRGBAAt(p.X, p.Y) {
+			t.Errorf("expected the pixel at %v to be untouched, got: %v", p, c)
+		}
+	}
+	if src.NRGBAAt(30, 30).R != 200 {
+		t.Errorf("expected the source image to be left untouched")
+	}
+}
+
+func TestRedEye_ShouldSkipTheEyesBelowTheCoverage(t *testing.T) {
+	p := redeye.DefaultParams()
+	p.MinCoverage = 0.9
+
+	face := pigo.Face{LeftEye: &pigo.Puploc{Row: 30, Col: 30, Scale: 20}}
+	dst, reports, err := redeye.Correct(eyeImage(), []pigo.Face{face, {}}, p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reports) != 1 || reports[0].Corrected || reports[0].Coverage == 0 {
+		t.Fatalf("expected a single uncorrected eye, got: %+v", reports)
+	}
+	if c := dst.NRGBAAt(30, 30); c.R != 200 {
+		t.Errorf("expected the red pupil to be left untouched, got: %v", c)
+	}
+}
+
+func TestRedEye_Redness(t *testing.T) {
+	for _, tc := range []struct {
+		c    color.NRGBA
+		want float64
+	}{
+		{color.NRGBA{R: 200, G: 40, B: 50}, 0.75},
+		{skin, 50.0 / 220},
+		{color.NRGBA{R: 20, G: 40, B: 50}, 0},
+		{color.NRGBA{}, 0},
+	} {
+		if got := redeye.Redness(tc.c.R, tc.c.G, tc.c.B); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("expected the redness of %v to be %v, got: %v", tc.c, tc.want, got)
+		}
+	}
+}
+
+func TestRedEye_ShouldRejectInvalidParams(t *testing.T) {
+	for name, c := range map[string]struct {
+		set func(*redeye.Params)
+		err error
+	}{
+		"zero redness":     {func(p *redeye.Params) { p.Redness = 0 }, redeye.ErrInvalidRedness},
+		"redness above 1":  {func(p *redeye.Params) { p.Redness = 1.5 }, redeye.ErrInvalidRedness},
+		"brightening":      {func(p *redeye.Params) { p.Darken = 2 }, redeye.ErrInvalidDarken},
+		"negative feather": {func(p *redeye.Params) { p.Feather = -0.1 }, redeye.ErrNegativeParam},
+	} {
+		p := redeye.DefaultParams()
+		c.set(&p)
+		face := pigo.Face{LeftEye: &pigo.Puploc{Row: 30, Col: 30, Scale: 20}}
+		if _, _, err := redeye.Correct(eyeImage(), []pigo.Face{face}, p); !errors.Is(err, c.err) {
+			t.Errorf("%s: expected the %v error, got: %v", name, c.err, err)
+		}
+	}
+	if err := redeye.DefaultParams().Validate(); err != nil {
+		t.Errorf("the default parameters should be valid, got: %v", err)
+	}
+}
+
+func TestRedEye_ShouldCopySubImages(t *testing.T) {
+	// The zero origin sub-image shares the rows of the larger image.
+	img := image.NewNRGBA(image.Rect(0, 0, 120, 120))
+	draw.Draw(img, image.Rect(0, 0, 60, 60), eyeImage(), image.Point{}, draw.Src)
+	src := img.SubImage(image.Rect(0, 0, 60, 60)).(*image.NRGBA)
+
+	dst, _, err := redeye.Correct(src, nil, redeye.DefaultParams())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 60; x++ {
+			if dst.NRGBAAt(x, y) != src.NRGBAAt(x, y) {
+				t.Fatalf("the pixel at %d,%d differs from the source image", x, y)
+			}
+		}
+	}
+}
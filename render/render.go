@@ -0,0 +1,586 @@
+// Package render draws the results of the face analysis over an image: the face markers with their
+// score and identifier labels, the pupils, the facial landmark points and their connection lines, and the head pose axes.
+//
+// Each element is drawn with its own style and the elements are grouped into layers, which can be selected separately.
+// The annotations are either drawn over the image or written as vector shapes into an SVG document (see WriteSVG).
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/fogleman/gg"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+)
+
+// Layer is a group of annotation elements which can be drawn separately.
+type Layer string
+
+// The annotation layers.
+const (
+	// Faces - the face markers.
+	Faces Layer = "faces"
+	// Labels - the labels of the faces, holding their identifier and detection score.
+	Labels Layer = "labels"
+	// Pupils - the pupils, with their localization boxes if enabled.
+	Pupils Layer = "pupils"
+	// Landmarks - the facial landmark points.
+	Landmarks Layer = "landmarks"
+	// Contours - the lines connecting the landmark points (see FaceContours).
+	Contours Layer = "contours"
+	// Pose - the head pose axes.
+	Pose Layer = "pose"
+)
+
+// layers lists the supported layers in their drawing order.
+var layers = []Layer{Faces, Contours, Landmarks, Pupils, Pose, Labels}
+
+// Marker is the shape of the face marker.
+type Marker string
+
+// The face marker shapes.
+const (
+	Rectangle Marker = "rect"
+	Circle    Marker = "circle"
+	Ellipse   Marker = "ellipse"
+)
+
+// The names of the drawn elements, used by ParseStyles.
+const (
+	faceElement     = "face"
+	eyeBoxElement   = "eyebox"
+	pupilElement    = "pupil"
+	landmarkElement = "landmark"
+	contourElement  = "contour"
+	poseElement     = "pose"
+	labelElement    = "label"
+)
+
+// Style describes how an annotation element is drawn.
+// Color: the stroke color of the lines, the fill color of the points and the text color of the labels.
+// Fill: the color filling the face markers and the label backgrounds. A translucent color shades the face
+// without hiding it, while a fully transparent color (the zero value) disables the filling.
+// LineWidth: the stroke width in pixels.
+type Style struct {
+	Color     color.NRGBA
+	Fill      color.NRGBA
+	LineWidth float64
+}
+
+// Contour is a line connecting named facial landmark points. The closed contours return to their first point.
+// A closed contour of two points, like the eye corners, is drawn as a lens shape between them,
+// passing through the pupil if it's localized.
+type Contour struct {
+	Name   string
+	Points []string
+	Closed bool
+}
+
+// FaceContours are the connection lines drawn between the facial landmark points of the bundled cascades.
+var FaceContours = []Contour{
+	{Name: "LeftEyebrow", Points: []string{pigo.LeftEyebrowOuter, pigo.LeftEyebrowMiddle, pigo.LeftEyebrowInner}},
+	{Name: "RightEyebrow", Points: []string{pigo.RightEyebrowInner, pigo.RightEyebrowMiddle, pigo.RightEyebrowOuter}},
+	{Name: "LeftEye", Points: []string{pigo.LeftEyeOuterCorner, pigo.LeftEyeInnerCorner}, Closed: true},
+	{Name: "RightEye", Points: []string{pigo.RightEyeInnerCorner, pigo.RightEyeOuterCorner}, Closed: true},
+	{Name: "Mouth", Points: []string{pigo.MouthLeft, pigo.UpperLip, pigo.MouthRight, pigo.LowerLip}, Closed: true},
+}
+
+// Params contains the rendering options.
+// Layers: the layers to be drawn.
+// Marker: the shape of the face markers.
+// EyeBoxes: draw the localization box around the pupils.
+// Face, EyeBox, Pupil, Landmark, Contour, Label: the styles of the drawn elements.
+// Pose: the style of the head pose axes. The axes are drawn in red (X), green (Y) and blue (Z)
+// unless the style has a color.
+// Font: the font face of the labels.
+type Params struct {
+	Layers   []Layer
+	Marker   Marker
+	EyeBoxes bool
+	Face     Style
+	EyeBox   Style
+	Pupil    Style
+	Landmark Style
+	Contour  Style
+	Pose     Style
+	Label    Style
+	Font     font.Face
+}
+
+// DefaultParams returns the rendering options used by the command line utility:
+// red face rectangles with red pupils in yellow boxes and blue landmark points.
+func DefaultParams() Params {
+	return Params{
+		Layers:   []Layer{Faces, Pupils, Landmarks},
+		Marker:   Rectangle,
+		EyeBoxes: true,
+		Face:     Style{Color: color.NRGBA{R: 255, A: 255}, LineWidth: 2},
+		EyeBox:   Style{Color: color.NRGBA{R: 255, G: 255, A: 255}, LineWidth: 2},
+		Pupil:    Style{Color: color.NRGBA{R: 255, A: 255}},
+		Landmark: Style{Color: color.NRGBA{B: 255, A: 255}},
+		Contour:  Style{Color: color.NRGBA{G: 200, B: 255, A: 255}, LineWidth: 1},
+		Pose:     Style{LineWidth: 3},
+		Label:    Style{Color: color.NRGBA{R: 255, G: 255, B: 255, A: 255}, Fill: color.NRGBA{R: 255, A: 192}},
+		Font:     basicfont.Face7x13,
+	}
+}
+
+// Validate checks the rendering options.
+func (p Params) Validate() error {
+	var errs []error
+	for _, l := range p.Layers {
+		if !validLayer(l) {
+			errs = append(errs, fmt.Errorf("unknown layer: %s", l))
+		}
+	}
+	switch p.Marker {
+	case Rectangle, Circle, Ellipse:
+	default:
+		errs = append(errs, fmt.Errorf("unknown marker: %s", p.Marker))
+	}
+	for _, s := range []Style{p.Face, p.EyeBox, p.Pupil, p.Landmark, p.Contour, p.Pose, p.Label} {
+		if s.LineWidth < 0 {
+			errs = append(errs, errors.New("the line width should not be negative"))
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Has checks whether the layer is selected for drawing.
+func (p Params) Has(layer Layer) bool {
+	for _, l := range p.Layers {
+		if l == layer {
+			return true
+		}
+	}
+	return false
+}
+
+// Colors returns the opaque colors drawn by the selected layers, which should be kept
+// when the annotated image is quantized to a palette (like the frames of a GIF).
+func (p Params) Colors() []color.Color {
+	var colors []color.Color
+	add := func(c color.NRGBA) {
+		if c.A != 0xff {
+			return
+		}
+		for _, col := range colors {
+			if col == color.Color(c) {
+				return
+			}
+		}
+		colors = append(colors, c)
+	}
+	if p.Has(Faces) {
+		add(p.Face.Color)
+	}
+	if p.Has(Pupils) {
+		add(p.Pupil.Color)
+		if p.EyeBoxes {
+			add(p.EyeBox.Color)
+		}
+	}
+	if p.Has(Landmarks) {
+		add(p.Landmark.Color)
+	}
+	if p.Has(Contours) {
+		add(p.Contour.Color)
+	}
+	if p.Has(Pose) {
+		if p.Pose.Color.A == 0 {
+			for _, c := range poseColors {
+				add(c)
+			}
+		} else {
+			add(p.Pose.Color)
+		}
+	}
+	if p.Has(Labels) {
+		add(p.Label.Color)
+		add(p.Label.Fill)
+	}
+	return colors
+}
+
+// Annotate draws the faces over a copy of the source image.
+func Annotate(src image.Image, faces []pigo.Face, p Params) *image.RGBA {
+	dc := gg.NewContext(src.Bounds().Dx(), src.Bounds().Dy())
+	dc.DrawImage(src, -src.Bounds().Min.X, -src.Bounds().Min.Y)
+	Draw(dc, faces, p)
+	return dc.Image().(*image.RGBA)
+}
+
+// Draw draws the faces on the drawing context, layer by layer.
+func Draw(dc *gg.Context, faces []pigo.Face, p Params) {
+	draw(dc, faces, nil, p)
+}
+
+// DrawTracks draws the tracked faces on the drawing context, showing their track identifier in the labels.
+func DrawTracks(dc *gg.Context, tracks []pigo.Track, p Params) {
+	faces := make([]pigo.Face, len(tracks))
+	ids := make([]int, len(tracks))
+	for i, tr := range tracks {
+		faces[i], ids[i] = tr.Face, tr.ID
+	}
+	draw(dc, faces, ids, p)
+}
+
+// draw draws the faces layer by layer, so the elements of a layer are never hidden by the elements
+// of the previous layers drawn for another face. The identifiers are optional.
+func draw(dc *gg.Context, faces []pigo.Face, ids []int, p Params) {
+	dc.Push()
+	defer dc.Pop()
+
+	for _, layer := range layers {
+		if !p.Has(layer) {
+			continue
+		}
+		for i, face := range faces {
+			switch layer {
+			case Faces:
+				drawFace(dc, face, p)
+			case Contours:
+				drawContours(dc, face, p.Contour)
+			case Landmarks:
+				for _, lp := range face.Landmarks {
+					drawPoint(dc, float64(lp.Col), float64(lp.Row), float64(lp.Scale)*0.075, p.Landmark)
+				}
+			case Pupils:
+				drawPupils(dc, face, p)
+			case Pose:
+				drawPoseAxes(dc, face, p.Pose)
+			case Labels:
+				id := 0
+				if ids != nil {
+					id = ids[i]
+				}
+				drawLabel(dc, face, id, p)
+			}
+		}
+	}
+}
+
+// drawFace draws the face marker.
+func drawFace(dc *gg.Context, face pigo.Face, p Params) {
+	det := face.Detection
+	switch p.Marker {
+	case Circle:
+		dc.DrawArc(float64(det.Col), float64(det.Row), float64(det.Scale/2), 0, 2*math.Pi)
+	case Ellipse:
+		dc.DrawEllipse(float64(det.Col), float64(det.Row), float64(det.Scale)/2, float64(det.Scale)/1.6)
+	default:
+		dc.DrawRectangle(float64(face.Box.Min.X), float64(face.Box.Min.Y), float64(face.Box.Dx()), float64(face.Box.Dy()))
+	}
+	fillStroke(dc, p.Face)
+}
+
+// drawPupils draws the pupils of the face, with their localization boxes if enabled.
+func drawPupils(dc *gg.Context, face pigo.Face, p Params) {
+	for _, eye := range []*pigo.Puploc{face.LeftEye, face.RightEye} {
+		if eye == nil {
+			continue
+		}
+		x, y, r := float64(eye.Col), float64(eye.Row), float64(eye.Scale)
+		drawPoint(dc, x, y, r*0.15, p.Pupil)
+
+		if p.EyeBoxes {
+			dc.DrawRectangle(x-r*1.5, y-r*1.5, r*3, r*3)
+			fillStroke(dc, p.EyeBox)
+		}
+	}
+}
+
+// drawContours draws the lines connecting the landmark points of the face. The contours
+// with missing points are skipped.
+func drawContours(dc *gg.Context, face pigo.Face, s Style) {
+	if s.LineWidth == 0 {
+		return
+	}
+	for _, c := range FaceContours {
+		points := make([]gg.Point, 0, len(c.Points))
+		for _, name := range c.Points {
+			if lp, ok := face.Landmark(name); ok {
+				points = append(points, gg.Point{X: float64(lp.Col), Y: float64(lp.Row)})
+			}
+		}
+		if len(points) < len(c.Points) || len(points) < 2 {
+			continue
+		}
+
+		dc.MoveTo(points[0].X, points[0].Y)
+		if c.Closed && len(points) == 2 {
+			drawLens(dc, points[0], points[1], eyeCenter(face, points[0], points[1]))
+		} else {
+			for _, pt := range points[1:] {
+				dc.LineTo(pt.X, pt.Y)
+			}
+			if c.Closed {
+				dc.ClosePath()
+			}
+		}
+		dc.SetLineWidth(s.LineWidth)
+		dc.SetColor(s.Color)
+		dc.Stroke()
+	}
+}
+
+// drawLens adds a lens shaped path between the two points to the current path, passing
+// above and below the center. The path should be started at the first point.
+func drawLens(dc *gg.Context, p1, p2, center gg.Point) {
+	c1, c2 := lensControls(p1, p2, center)
+	dc.QuadraticTo(c1.X, c1.Y, p2.X, p2.Y)
+	dc.QuadraticTo(c2.X, c2.Y, p1.X, p1.Y)
+	dc.ClosePath()
+}
+
+// lensControls returns the control points of the quadratic curves forming the lens shape between the two points:
+// the curve going from the first point to the second one and the curve coming back.
+func lensControls(p1, p2, center gg.Point) (gg.Point, gg.Point) {
+	dx, dy := p2.X-p1.X, p2.Y-p1.Y
+	// The normal of the segment, sized to the lens height.
+	nx, ny := -dy*0.35, dx*0.35
+	mx, my := (p1.X+p2.X)/2, (p1.Y+p2.Y)/2
+	// The lens is shifted towards the center, so it follows the pupil.
+	cx, cy := center.X-mx, center.Y-my
+
+	return gg.Point{X: mx + cx + nx, Y: my + cy + ny}, gg.Point{X: mx + cx - nx, Y: my + cy - ny}
+}
+
+// eyeCenter returns the pupil found between the eye corners, or the midpoint of the corners if it's missing.
+func eyeCenter(face pigo.Face, p1, p2 gg.Point) gg.Point {
+	mid := gg.Point{X: (p1.X + p2.X) / 2, Y: (p1.Y + p2.Y) / 2}
+	for _, eye := range []*pigo.Puploc{face.LeftEye, face.RightEye} {
+		if eye == nil {
+			continue
+		}
+		pupil := gg.Point{X: float64(eye.Col), Y: float64(eye.Row)}
+		if pupil.Distance(mid) < p1.Distance(p2)/2 {
+			return pupil
+		}
+	}
+	return mid
+}
+
+// poseColors are the default colors of the X, Y and Z head pose axes.
+var poseColors = []color.NRGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}}
+
+// drawPoseAxes draws the head pose axes starting from the nose tip, or from the pupils midpoint if it's missing.
+func drawPoseAxes(dc *gg.Context, face pigo.Face, s Style) {
+	origin, ok := poseOrigin(face)
+	if !ok {
+		return
+	}
+	x, y, z := face.Pose.Axes()
+	dc.SetLineWidth(s.LineWidth)
+	for i, axis := range [][2]float64{x, y, z} {
+		dc.DrawLine(origin.X, origin.Y, origin.X+axis[0], origin.Y+axis[1])
+		dc.SetColor(poseColor(s, i))
+		dc.Stroke()
+	}
+}
+
+// poseOrigin returns the starting point of the head pose axes. It's false if the head pose
+// or the pupils of the face are missing.
+func poseOrigin(face pigo.Face) (gg.Point, bool) {
+	if face.Pose == nil || face.LeftEye == nil || face.RightEye == nil {
+		return gg.Point{}, false
+	}
+	if nose, ok := face.Landmark(pigo.NoseTip); ok {
+		return gg.Point{X: float64(nose.Col), Y: float64(nose.Row)}, true
+	}
+	return gg.Point{
+		X: float64(face.LeftEye.Col+face.RightEye.Col) / 2,
+		Y: float64(face.LeftEye.Row+face.RightEye.Row) / 2,
+	}, true
+}
+
+// poseColor returns the color of the i-th head pose axis.
+func poseColor(s Style, i int) color.NRGBA {
+	if s.Color.A == 0 {
+		return poseColors[i]
+	}
+	return s.Color
+}
+
+// drawLabel draws the label holding the identifier and the detection score above the face box.
+func drawLabel(dc *gg.Context, face pigo.Face, id int, p Params) {
+	l := newLabel(face, id, p.Font)
+	if p.Label.Fill.A > 0 {
+		dc.DrawRectangle(l.x, l.y, l.width, l.height)
+		dc.SetColor(p.Label.Fill)
+		dc.Fill()
+	}
+	dc.SetFontFace(l.font)
+	dc.SetColor(p.Label.Color)
+	dc.DrawStringAnchored(l.text, l.x+labelPadding, l.y+labelPadding, 0, 1)
+}
+
+// labelPadding is the space around the label text in pixels.
+const labelPadding = 2.0
+
+// label is the text holding the identifier and the detection score of the face, with its background box.
+type label struct {
+	text                string
+	font                font.Face
+	x, y, width, height float64
+}
+
+// newLabel lays out the label of the face above its box. The identifier is shown only if it's positive.
+func newLabel(face pigo.Face, id int, fontFace font.Face) label {
+	var parts []string
+	if id > 0 {
+		parts = append(parts, "#"+strconv.Itoa(id))
+	}
+	parts = append(parts, strconv.FormatFloat(float64(face.Score), 'f', 1, 32))
+
+	l := label{text: strings.Join(parts, " "), font: fontFace}
+	if l.font == nil {
+		l.font = basicfont.Face7x13
+	}
+	w := font.MeasureString(l.font, l.text)
+	h := float64(l.font.Metrics().Height) / 64
+	l.width, l.height = float64(w>>6)+2*labelPadding, h+2*labelPadding
+
+	l.x, l.y = float64(face.Box.Min.X), float64(face.Box.Min.Y)-l.height
+	if l.y < 0 {
+		// There is no room above the box, so the label is drawn inside it.
+		l.y = float64(face.Box.Min.Y)
+	}
+	return l
+}
+
+// drawPoint draws a filled circle.
+func drawPoint(dc *gg.Context, x, y, r float64, s Style) {
+	dc.DrawArc(x, y, r, 0, 2*math.Pi)
+	dc.SetColor(s.Color)
+	dc.Fill()
+}
+
+// fillStroke fills the current path with the fill color of the style, if any, then strokes it.
+func fillStroke(dc *gg.Context, s Style) {
+	if s.Fill.A > 0 {
+		dc.SetColor(s.Fill)
+		dc.FillPreserve()
+	}
+	dc.SetLineWidth(s.LineWidth)
+	dc.SetColor(s.Color)
+	if s.LineWidth > 0 {
+		dc.Stroke()
+	} else {
+		dc.ClearPath()
+	}
+}
+
+// validLayer checks whether the layer is supported.
+func validLayer(l Layer) bool {
+	for _, layer := range layers {
+		if l == layer {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseLayers parses the comma separated list of layer names.
+func ParseLayers(s string) ([]Layer, error) {
+	var res []Layer
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !validLayer(Layer(name)) {
+			return nil, fmt.Errorf("unknown layer: %q", name)
+		}
+		res = append(res, Layer(name))
+	}
+	return res, nil
+}
+
+// ParseStyles overrides the element styles of the base parameters with the styles defined in the
+// "element:color,width,fill;element:color" format, where the width and the fill color are optional
+// and the colors are given in the #rrggbb or #rrggbbaa format. An empty color keeps the base color.
+// The elements are face, eyebox, pupil, landmark, contour, pose and label.
+func ParseStyles(s string, base Params) (Params, error) {
+	p := base
+	for _, def := range strings.Split(s, ";") {
+		if def = strings.TrimSpace(def); def == "" {
+			continue
+		}
+		name, spec, ok := strings.Cut(def, ":")
+		if !ok {
+			return base, fmt.Errorf("invalid style: %q", def)
+		}
+		var style *Style
+		switch strings.TrimSpace(name) {
+		case faceElement:
+			style = &p.Face
+		case eyeBoxElement:
+			style = &p.EyeBox
+		case pupilElement:
+			style = &p.Pupil
+		case landmarkElement:
+			style = &p.Landmark
+		case contourElement:
+			style = &p.Contour
+		case poseElement:
+			style = &p.Pose
+		case labelElement:
+			style = &p.Label
+		default:
+			return base, fmt.Errorf("unknown element: %q", name)
+		}
+
+		fields := strings.Split(spec, ",")
+		if len(fields) > 3 {
+			return base, fmt.Errorf("invalid style: %q", def)
+		}
+		if c := strings.TrimSpace(fields[0]); c != "" {
+			col, err := ParseColor(c)
+			if err != nil {
+				return base, err
+			}
+			style.Color = col
+		}
+		if len(fields) > 1 && strings.TrimSpace(fields[1]) != "" {
+			width, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+			if err != nil || width < 0 {
+				return base, fmt.Errorf("invalid line width: %q", fields[1])
+			}
+			style.LineWidth = width
+		}
+		if len(fields) > 2 && strings.TrimSpace(fields[2]) != "" {
+			col, err := ParseColor(strings.TrimSpace(fields[2]))
+			if err != nil {
+				return base, err
+			}
+			style.Fill = col
+		}
+	}
+	return p, nil
+}
+
+// ParseColor parses a color defined in the #rrggbb or #rrggbbaa format.
+func ParseColor(s string) (color.NRGBA, error) {
+	c := color.NRGBA{A: 255}
+	if (len(s) != 7 && len(s) != 9) || s[0] != '#' {
+		return c, fmt.Errorf("invalid color %q: expected the #rrggbb or #rrggbbaa format", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return c, fmt.Errorf("invalid color %q: %w", s, err)
+	}
+	if len(s) == 9 {
+		c.A = uint8(v)
+		v >>= 8
+	}
+	c.R, c.G, c.B = uint8(v>>16), uint8(v>>8), uint8(v)
+	return c, nil
+}
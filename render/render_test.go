@@ -0,0 +1,113 @@
+package render_test
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/render"
+)
+
+func TestRender_ParseColor(t *testing.T) {
+	cases := map[string]color.NRGBA{
+		"#ff8000":   {R: 255, G: 128, A: 255},
+		"#00ff0080": {G: 255, A: 128},
+		"#FFFFFF":   {R: 255, G: 255, B: 255, A: 255},
+	}
+	for s, expected := range cases {
+		c, err := render.ParseColor(s)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", s, err)
+		}
+		if c != expected {
+			t.Errorf("%s: expected %v, got %v", s, expected, c)
+		}
+	}
+	for _, s := range []string{"", "ff0000", "#ff00", "#gg0000", "#ff0000ff00"} {
+		if _, err := render.ParseColor(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestRender_ParseLayers(t *testing.T) {
+	layers, err := render.ParseLayers(" faces, pose,,labels ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []render.Layer{render.Faces, render.Pose, render.Labels}; !reflect.DeepEqual(layers, expected) {
+		t.Errorf("expected %v, got %v", expected, layers)
+	}
+	if _, err := render.ParseLayers("faces,mask"); err == nil {
+		t.Errorf("expected an error for an unknown layer")
+	}
+}
+
+func TestRender_ParseStyles(t *testing.T) {
+	base := render.DefaultParams()
+	p, err := render.ParseStyles("face:#00ff00,3,#00ff0033; landmark:#ffffff;label:,,#000000", base)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (render.Style{Color: color.NRGBA{G: 255, A: 255}, Fill: color.NRGBA{G: 255, A: 0x33}, LineWidth: 3}); p.Face != expected {
+		t.Errorf("expected the face style %v, got %v", expected, p.Face)
+	}
+	if p.Landmark.Color != (color.NRGBA{R: 255, G: 255, B: 255, A: 255}) || p.Landmark.LineWidth != base.Landmark.LineWidth {
+		t.Errorf("unexpected landmark style %v", p.Landmark)
+	}
+	if p.Label.Color != base.Label.Color || p.Label.Fill != (color.NRGBA{A: 255}) {
+		t.Errorf("the empty label color should keep the base color, got %v", p.Label)
+	}
+	if p.Pupil != base.Pupil {
+		t.Errorf("the pupil style should not be changed, got %v", p.Pupil)
+	}
+
+	for _, s := range []string{"nose:#ffffff", "face", "face:red", "face:#ffffff,-1", "face:#ffffff,1,#000000,2"} {
+		if _, err := render.ParseStyles(s, base); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestRender_Validate(t *testing.T) {
+	if err := render.DefaultParams().Validate(); err != nil {
+		t.Fatalf("the default parameters should be valid: %v", err)
+	}
+	p := render.DefaultParams()
+	p.Marker = "star"
+	p.Layers = append(p.Layers, "mask")
+	p.Face.LineWidth = -1
+	if err := p.Validate(); err == nil {
+		t.Errorf("expected a validation error")
+	}
+}
+
+func TestRender_AnnotateShouldDrawTheFaceMarker(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 100, 100))
+	for i := range src.Pix {
+		src.Pix[i] = 0xff
+	}
+	face := pigo.Face{Box: image.Rect(20, 20, 80, 80), Score: 10}
+
+	p := render.DefaultParams()
+	p.Layers = []render.Layer{render.Faces}
+	p.Face = render.Style{Color: color.NRGBA{G: 255, A: 255}, LineWidth: 2}
+
+	img := render.Annotate(src, []pigo.Face{face}, p)
+	if c := img.RGBAAt(20, 50); c.G != 255 || c.R > 64 {
+		t.Errorf("expected the face marker at the box edge, got %v", c)
+	}
+	if c := img.RGBAAt(50, 50); c != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("the face box should not be filled, got %v", c)
+	}
+	if c := src.NRGBAAt(20, 50); c.R != 0xff {
+		t.Errorf("the source image should not be modified")
+	}
+
+	p.Layers = nil
+	if c := render.Annotate(src, []pigo.Face{face}, p).RGBAAt(20, 50); c.R != 255 {
+		t.Errorf("nothing should be drawn without layers, got %v", c)
+	}
+}
@@ -0,0 +1,253 @@
+package render
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/fogleman/gg"
+)
+
+// Background is the source image shown behind the annotations of an SVG document.
+// Href: the link to the image: a file path, an URL or a data URI (see DataURI).
+// Orientation: the EXIF orientation of the linked image. The stored image is transformed explicitly
+// to be upright, like the annotated face coordinates, whether the SVG viewer applies the EXIF orientation or not.
+type Background struct {
+	Href        string
+	Orientation pigo.Orientation
+}
+
+// DataURI encodes the image file into a data URI, which embeds the original image bytes into the SVG document.
+func DataURI(data []byte) string {
+	mime := http.DetectContentType(data)
+	if strings.HasPrefix(string(data), "II*\x00") || strings.HasPrefix(string(data), "MM\x00*") {
+		mime = "image/tiff"
+	}
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// WriteSVG writes the annotations of the faces as vector shapes into an SVG document sized width x height,
+// the size of the upright source image. The annotations are drawn over the background image if provided,
+// otherwise the document is a transparent overlay, which can be placed over the untouched source image.
+//
+// The elements are grouped by layer and they carry the index of their face in the data-face attribute,
+// while the face markers, the pupils, the landmark points and the head pose axes carry their scores
+// in data attributes as well.
+func WriteSVG(w io.Writer, faces []pigo.Face, width, height int, bg *Background, p Params) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		width, height, width, height)
+	if bg != nil && bg.Href != "" {
+		href := escape(bg.Href)
+		if bg.Orientation > pigo.OrientationNormal && bg.Orientation <= pigo.OrientationRotate270 {
+			// The size of the stored image.
+			sw, sh := width, height
+			if bg.Orientation.Transposed() {
+				sw, sh = height, width
+			}
+			fmt.Fprintf(bw, `<image href="%s" width="%d" height="%d" transform="%s" style="image-orientation:none"/>`+"\n",
+				href, sw, sh, orientationTransform(bg.Orientation, sw, sh))
+		} else {
+			fmt.Fprintf(bw, `<image href="%s" width="%d" height="%d"/>`+"\n", href, width, height)
+		}
+	}
+
+	for _, layer := range layers {
+		if !p.Has(layer) || len(faces) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, `<g class="%s">`+"\n", layer)
+		for i, face := range faces {
+			switch layer {
+			case Faces:
+				writeSVGFace(bw, face, i, p)
+			case Contours:
+				writeSVGContours(bw, face, i, p.Contour)
+			case Landmarks:
+				for _, lp := range face.Landmarks {
+					fmt.Fprintf(bw, `<circle class="landmark" data-face="%d" data-name="%s" data-confidence="%s" cx="%d" cy="%d" r="%s"%s/>`+"\n",
+						i, escape(lp.Name), num(lp.Confidence()), lp.Col, lp.Row, num(float64(lp.Scale)*0.075), paint("fill", p.Landmark.Color))
+				}
+			case Pupils:
+				writeSVGPupils(bw, face, i, p)
+			case Pose:
+				writeSVGPose(bw, face, i, p.Pose)
+			case Labels:
+				l := newLabel(face, 0, p.Font)
+				if p.Label.Fill.A > 0 {
+					fmt.Fprintf(bw, `<rect class="label" data-face="%d" x="%s" y="%s" width="%s" height="%s"%s/>`+"\n",
+						i, num(l.x), num(l.y), num(l.width), num(l.height), paint("fill", p.Label.Fill))
+				}
+				fmt.Fprintf(bw, `<text class="label" data-face="%d" x="%s" y="%s" font-family="monospace" font-size="%s" dominant-baseline="hanging"%s>%s</text>`+"\n",
+					i, num(l.x+labelPadding), num(l.y+labelPadding), num(l.height-2*labelPadding), paint("fill", p.Label.Color), escape(l.text))
+			}
+		}
+		fmt.Fprintln(bw, "</g>")
+	}
+	fmt.Fprintln(bw, "</svg>")
+
+	return bw.Flush()
+}
+
+// writeSVGFace writes the face marker.
+func writeSVGFace(w io.Writer, face pigo.Face, i int, p Params) {
+	det := face.Detection
+	attrs := fmt.Sprintf(`class="face" data-face="%d" data-score="%s" data-angle="%s"`, i, num(float64(face.Score)), num(face.Angle))
+	switch p.Marker {
+	case Circle:
+		fmt.Fprintf(w, `<circle %s cx="%d" cy="%d" r="%d"%s/>`+"\n", attrs, det.Col, det.Row, det.Scale/2, shapeStyle(p.Face))
+	case Ellipse:
+		fmt.Fprintf(w, `<ellipse %s cx="%d" cy="%d" rx="%s" ry="%s"%s/>`+"\n",
+			attrs, det.Col, det.Row, num(float64(det.Scale)/2), num(float64(det.Scale)/1.6), shapeStyle(p.Face))
+	default:
+		fmt.Fprintf(w, `<rect %s x="%d" y="%d" width="%d" height="%d"%s/>`+"\n",
+			attrs, face.Box.Min.X, face.Box.Min.Y, face.Box.Dx(), face.Box.Dy(), shapeStyle(p.Face))
+	}
+}
+
+// writeSVGPupils writes the pupils of the face, with their localization boxes if enabled.
+func writeSVGPupils(w io.Writer, face pigo.Face, i int, p Params) {
+	for _, eye := range []struct {
+		name string
+		*pigo.Puploc
+	}{{"left", face.LeftEye}, {"right", face.RightEye}} {
+		if eye.Puploc == nil {
+			continue
+		}
+		x, y, r := float64(eye.Col), float64(eye.Row), float64(eye.Scale)
+		if p.EyeBoxes {
+			fmt.Fprintf(w, `<rect class="eyebox" data-face="%d" data-eye="%s" x="%s" y="%s" width="%s" height="%s"%s/>`+"\n",
+				i, eye.name, num(x-r*1.5), num(y-r*1.5), num(r*3), num(r*3), shapeStyle(p.EyeBox))
+		}
+		fmt.Fprintf(w, `<circle class="pupil" data-face="%d" data-eye="%s" data-confidence="%s" cx="%d" cy="%d" r="%s"%s/>`+"\n",
+			i, eye.name, num(eye.Confidence()), eye.Col, eye.Row, num(r*0.15), paint("fill", p.Pupil.Color))
+	}
+}
+
+// writeSVGContours writes the lines connecting the landmark points of the face. The contours with missing points are skipped.
+func writeSVGContours(w io.Writer, face pigo.Face, i int, s Style) {
+	if s.LineWidth == 0 {
+		return
+	}
+	for _, c := range FaceContours {
+		points := make([]gg.Point, 0, len(c.Points))
+		for _, name := range c.Points {
+			if lp, ok := face.Landmark(name); ok {
+				points = append(points, gg.Point{X: float64(lp.Col), Y: float64(lp.Row)})
+			}
+		}
+		if len(points) < len(c.Points) || len(points) < 2 {
+			continue
+		}
+
+		d := []string{"M", point(points[0])}
+		if c.Closed && len(points) == 2 {
+			c1, c2 := lensControls(points[0], points[1], eyeCenter(face, points[0], points[1]))
+			d = append(d, "Q", point(c1), point(points[1]), "Q", point(c2), point(points[0]), "Z")
+		} else {
+			for _, pt := range points[1:] {
+				d = append(d, "L", point(pt))
+			}
+			if c.Closed {
+				d = append(d, "Z")
+			}
+		}
+		fmt.Fprintf(w, `<path class="contour" data-face="%d" data-name="%s" d="%s" fill="none"%s/>`+"\n",
+			i, escape(c.Name), strings.Join(d, " "), stroke(s))
+	}
+}
+
+// writeSVGPose writes the head pose axes of the face.
+func writeSVGPose(w io.Writer, face pigo.Face, i int, s Style) {
+	origin, ok := poseOrigin(face)
+	if !ok {
+		return
+	}
+	pose := face.Pose
+	fmt.Fprintf(w, `<g class="pose" data-face="%d" data-yaw="%s" data-pitch="%s" data-roll="%s" data-fit="%s">`+"\n",
+		i, num(pose.Yaw), num(pose.Pitch), num(pose.Roll), num(pose.Fit))
+	x, y, z := pose.Axes()
+	for j, axis := range [][2]float64{x, y, z} {
+		fmt.Fprintf(w, `<line data-axis="%c" x1="%s" y1="%s" x2="%s" y2="%s"%s/>`+"\n", 'x'+j,
+			num(origin.X), num(origin.Y), num(origin.X+axis[0]), num(origin.Y+axis[1]),
+			stroke(Style{Color: poseColor(s, j), LineWidth: s.LineWidth}))
+	}
+	fmt.Fprintln(w, "</g>")
+}
+
+// orientationTransform returns the SVG transformation which maps the stored image, sized width x height,
+// to its upright version.
+func orientationTransform(o pigo.Orientation, width, height int) string {
+	w, h := width, height
+	var m [6]int
+	switch o {
+	case pigo.OrientationFlipH:
+		m = [6]int{-1, 0, 0, 1, w, 0}
+	case pigo.OrientationRotate180:
+		m = [6]int{-1, 0, 0, -1, w, h}
+	case pigo.OrientationFlipV:
+		m = [6]int{1, 0, 0, -1, 0, h}
+	case pigo.OrientationTranspose:
+		m = [6]int{0, 1, 1, 0, 0, 0}
+	case pigo.OrientationRotate90:
+		m = [6]int{0, 1, -1, 0, h, 0}
+	case pigo.OrientationTransverse:
+		m = [6]int{0, -1, -1, 0, h, w}
+	case pigo.OrientationRotate270:
+		m = [6]int{0, -1, 1, 0, 0, w}
+	default:
+		m = [6]int{1, 0, 0, 1, 0, 0}
+	}
+	return fmt.Sprintf("matrix(%d %d %d %d %d %d)", m[0], m[1], m[2], m[3], m[4], m[5])
+}
+
+// shapeStyle returns the fill and stroke attributes of a shape drawn with the style.
+func shapeStyle(s Style) string {
+	return paint("fill", s.Fill) + stroke(s)
+}
+
+// stroke returns the stroke attributes of a line drawn with the style.
+func stroke(s Style) string {
+	if s.LineWidth == 0 {
+		return ` stroke="none"`
+	}
+	return paint("stroke", s.Color) + fmt.Sprintf(` stroke-width="%s"`, num(s.LineWidth))
+}
+
+// paint returns the fill or stroke attribute painting with the color, with its opacity attribute if it's translucent.
+func paint(attr string, c color.NRGBA) string {
+	if c.A == 0 {
+		return fmt.Sprintf(` %s="none"`, attr)
+	}
+	res := fmt.Sprintf(` %s="#%02x%02x%02x"`, attr, c.R, c.G, c.B)
+	if c.A < 0xff {
+		res += fmt.Sprintf(` %s-opacity="%s"`, attr, num(float64(c.A)/0xff))
+	}
+	return res
+}
+
+// point formats the point as the coordinates of a path command.
+func point(pt gg.Point) string {
+	return num(pt.X) + "," + num(pt.Y)
+}
+
+// num formats the number with at most 3 decimals.
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+// escape escapes the text for being written into the SVG document.
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
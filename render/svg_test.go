@@ -0,0 +1,136 @@
+package render_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"strings"
+	"testing"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/render"
+)
+
+// svgElement is a generic element of the SVG document.
+type svgElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr   `xml:",any,attr"`
+	Children []svgElement `xml:",any"`
+}
+
+// attr returns the value of the named attribute.
+func (e svgElement) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// find returns the elements having the provided class, in document order.
+func (e svgElement) find(class string) []svgElement {
+	var res []svgElement
+	for _, c := range e.Children {
+		if c.attr("class") == class {
+			res = append(res, c)
+		}
+		res = append(res, c.find(class)...)
+	}
+	return res
+}
+
+func writeSVG(t *testing.T, faces []pigo.Face, width, height int, bg *render.Background, p render.Params) svgElement {
+	var buf bytes.Buffer
+	if err := render.WriteSVG(&buf, faces, width, height, bg, p); err != nil {
+		t.Fatalf("failed writing the svg document: %v", err)
+	}
+	var doc svgElement
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("the svg document is malformed: %v\n%s", err, buf.String())
+	}
+	return doc
+}
+
+func TestSVG_WriteShouldDescribeTheFaces(t *testing.T) {
+	face := pigo.Face{
+		Detection: pigo.Detection{Row: 50, Col: 50, Scale: 60, Q: 12.5},
+		Box:       image.Rect(20, 20, 80, 80),
+		Score:     12.5,
+		LeftEye:   &pigo.Puploc{Row: 40, Col: 35, Scale: 10},
+		Landmarks: []pigo.Landmark{{Name: pigo.NoseTip, Puploc: pigo.Puploc{Row: 55, Col: 50, Scale: 30}}},
+	}
+	p := render.DefaultParams()
+	p.Layers = []render.Layer{render.Faces, render.Pupils, render.Landmarks, render.Labels}
+
+	doc := writeSVG(t, []pigo.Face{face}, 100, 120, nil, p)
+	if doc.XMLName.Local != "svg" || doc.attr("width") != "100" || doc.attr("height") != "120" {
+		t.Fatalf("unexpected svg root element: %v %v", doc.XMLName, doc.Attrs)
+	}
+	for _, c := range doc.Children {
+		if c.XMLName.Local == "image" {
+			t.Errorf("the overlay should not include the source image")
+		}
+	}
+
+	faces := doc.find("face")
+	if len(faces) != 1 {
+		t.Fatalf("expected a face marker, got %d", len(faces))
+	}
+	f := faces[0]
+	if f.XMLName.Local != "rect" || f.attr("x") != "20" || f.attr("width") != "60" || f.attr("data-score") != "12.5" {
+		t.Errorf("unexpected face marker: %v", f.Attrs)
+	}
+	if f.attr("stroke") != "#ff0000" || f.attr("fill") != "none" {
+		t.Errorf("the face marker should use the face style, got %v", f.Attrs)
+	}
+	if pupils := doc.find("pupil"); len(pupils) != 1 || pupils[0].attr("data-eye") != "left" || pupils[0].attr("cx") != "35" {
+		t.Errorf("unexpected pupils: %v", pupils)
+	}
+	if boxes := doc.find("eyebox"); len(boxes) != 1 {
+		t.Errorf("expected an eye box, got %d", len(boxes))
+	}
+	if lps := doc.find("landmark"); len(lps) != 1 || lps[0].attr("data-name") != pigo.NoseTip {
+		t.Errorf("unexpected landmark points: %v", lps)
+	}
+	if labels := doc.find("label"); len(labels) != 2 || labels[1].XMLName.Local != "text" {
+		t.Errorf("expected the label background and text, got %v", labels)
+	}
+
+	p.Marker = render.Circle
+	if f := writeSVG(t, []pigo.Face{face}, 100, 120, nil, p).find("face")[0]; f.XMLName.Local != "circle" || f.attr("r") != "30" {
+		t.Errorf("unexpected circle marker: %v %v", f.XMLName, f.Attrs)
+	}
+}
+
+func TestSVG_WriteShouldShowTheBackground(t *testing.T) {
+	bg := &render.Background{Href: "faces & friends.jpg"}
+	doc := writeSVG(t, nil, 40, 30, bg, render.DefaultParams())
+	if len(doc.Children) != 1 || doc.Children[0].XMLName.Local != "image" {
+		t.Fatalf("expected only the background image, got %v", doc.Children)
+	}
+	img := doc.Children[0]
+	if img.attr("href") != bg.Href || img.attr("width") != "40" || img.attr("transform") != "" {
+		t.Errorf("unexpected background image: %v", img.Attrs)
+	}
+
+	// The stored image is 30x40 and it's rotated clockwise into the 40x30 upright image.
+	bg.Orientation = pigo.OrientationRotate90
+	img = writeSVG(t, nil, 40, 30, bg, render.DefaultParams()).Children[0]
+	if img.attr("width") != "30" || img.attr("height") != "40" || img.attr("transform") != "matrix(0 1 -1 0 40 0)" {
+		t.Errorf("unexpected oriented background image: %v", img.Attrs)
+	}
+}
+
+func TestSVG_DataURI(t *testing.T) {
+	cases := map[string][]byte{
+		"data:image/png;base64,":  []byte("\x89PNG\r\n\x1a\n"),
+		"data:image/jpeg;base64,": {0xff, 0xd8, 0xff, 0xe0},
+		"data:image/tiff;base64,": []byte("II*\x00\x08\x00\x00\x00"),
+	}
+	for prefix, data := range cases {
+		if uri := render.DataURI(data); !strings.HasPrefix(uri, prefix) {
+			t.Errorf("expected the %q prefix, got %q", prefix, uri)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+// Package smooth reduces the frame to frame jitter of the pupils and facial landmark points detected over a video stream.
+//
+// Since the detector runs independently on every frame, using random perturbations, the localized points jitter
+// by a few pixels even on a still face. The filters of this package smooth each point coordinate over time:
+// the One Euro filter adapts its cutoff frequency to the speed of the point, trading jitter for lag only when
+// the point is nearly still, the exponential moving average applies a fixed amount of smoothing and the Kalman
+// filter follows the point with a constant velocity motion model.
+package smooth
+
+import (
+	"math"
+	"time"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// Method is the smoothing filter applied over the point coordinates.
+type Method string
+
+// The supported smoothing filters.
+const (
+	None    Method = "none"
+	OneEuro Method = "oneeuro"
+	EMA     Method = "ema"
+	Kalman  Method = "kalman"
+)
+
+// Methods lists the supported smoothing filters.
+var Methods = []Method{None, OneEuro, EMA, Kalman}
+
+// Params contains the smoothing filter settings.
+// Method: the smoothing filter.
+// MinCutoff: the One Euro filter cutoff frequency in Hz used when the point is still. Lower values reduce the jitter.
+// Beta: the One Euro filter cutoff frequency increase per pixel/second of speed. Higher values reduce the lag.
+// DerivateCutoff: the One Euro filter cutoff frequency in Hz used for smoothing the speed of the point.
+// Alpha: the exponential moving average weight of the new samples, between 0 and 1. Lower values smooth more.
+// ProcessNoise: the Kalman filter acceleration variance, in pixels²/second⁴. Higher values follow the motion faster.
+// MeasurementNoise: the Kalman filter variance of the detected positions, in pixels².
+// Timeout: the tracks not updated for longer than this period are dropped.
+type Params struct {
+	Method           Method
+	MinCutoff        float64
+	Beta             float64
+	DerivateCutoff   float64
+	Alpha            float64
+	ProcessNoise     float64
+	MeasurementNoise float64
+	Timeout          time.Duration
+}
+
+// DefaultParams returns the default smoothing settings, using the One Euro filter.
+func DefaultParams() Params {
+	return Params{
+		Method:           OneEuro,
+		MinCutoff:        1,
+		Beta:             0.05,
+		DerivateCutoff:   1,
+		Alpha:            0.5,
+		ProcessNoise:     5000,
+		MeasurementNoise: 4,
+		Timeout:          time.Second,
+	}
+}
+
+// Filter smooths a scalar signal sampled at successive times.
+type Filter interface {
+	// Filter returns the smoothed value of the sample v taken at the time t.
+	Filter(t time.Time, v float64) float64
+	// Reset clears the filter state, so the next sample is returned unchanged.
+	Reset()
+}
+
+// NewFilter returns a new scalar filter using the method and the settings defined in the parameters.
+// It returns nil if the method is None or it's not supported.
+func NewFilter(p Params) Filter {
+	switch p.Method {
+	case OneEuro:
+		return &OneEuroFilter{MinCutoff: p.MinCutoff, Beta: p.Beta, DerivateCutoff: p.DerivateCutoff}
+	case EMA:
+		return &EMAFilter{Alpha: p.Alpha}
+	case Kalman:
+		return &KalmanFilter{ProcessNoise: p.ProcessNoise, MeasurementNoise: p.MeasurementNoise}
+	}
+	return nil
+}
+
+// OneEuroFilter is a low pass filter with an adaptive cutoff frequency, as described in
+// "1€ Filter: A Simple Speed-based Low-pass Filter for Noisy Input in Interactive Systems" by Casiez et al.
+type OneEuroFilter struct {
+	MinCutoff      float64
+	Beta           float64
+	DerivateCutoff float64
+
+	init  bool
+	last  time.Time
+	value float64
+	deriv float64
+}
+
+// Filter returns the smoothed value of the sample v taken at the time t.
+func (f *OneEuroFilter) Filter(t time.Time, v float64) float64 {
+	if !f.init {
+		f.init, f.last, f.value, f.deriv = true, t, v, 0
+		return v
+	}
+	dt := t.Sub(f.last).Seconds()
+	if dt <= 0 {
+		return f.value
+	}
+	f.last = t
+
+	f.deriv += smoothingFactor(dt, f.DerivateCutoff) * ((v-f.value)/dt - f.deriv)
+	cutoff := f.MinCutoff + f.Beta*math.Abs(f.deriv)
+	f.value += smoothingFactor(dt, cutoff) * (v - f.value)
+
+	return f.value
+}
+
+// Reset clears the filter state.
+func (f *OneEuroFilter) Reset() {
+	f.init = false
+}
+
+// smoothingFactor returns the weight of a new sample for a low pass filter with the cutoff frequency.
+func smoothingFactor(dt, cutoff float64) float64 {
+	r := 2 * math.Pi * cutoff * dt
+	return r / (r + 1)
+}
+
+// EMAFilter is an exponential moving average. Alpha is the weight of the new samples.
+type EMAFilter struct {
+	Alpha float64
+
+	init  bool
+	value float64
+}
+
+// Filter returns the smoothed value of the sample v. The sampling time is not taken into account.
+func (f *EMAFilter) Filter(t time.Time, v float64) float64 {
+	if !f.init {
+		f.init, f.value = true, v
+		return v
+	}
+	f.value += f.Alpha * (v - f.value)
+	return f.value
+}
+
+// Reset clears the filter state.
+func (f *EMAFilter) Reset() {
+	f.init = false
+}
+
+// KalmanFilter tracks the position and the velocity of a point moving with constant velocity,
+// disturbed by random accelerations (ProcessNoise) and measured with random errors (MeasurementNoise).
+type KalmanFilter struct {
+	ProcessNoise     float64
+	MeasurementNoise float64
+
+	init bool
+	last time.Time
+	x    [2]float64    // position and velocity
+	p    [2][2]float64 // state covariance
+}
+
+// Filter returns the estimated position of the point given the measurement v taken at the time t.
+func (f *KalmanFilter) Filter(t time.Time, v float64) float64 {
+	if !f.init {
+		f.init, f.last = true, t
+		f.x = [2]float64{v, 0}
+		// The initial velocity is unknown.
+		f.p = [2][2]float64{{f.MeasurementNoise, 0}, {0, 1e4}}
+		return v
+	}
+	dt := t.Sub(f.last).Seconds()
+	if dt <= 0 {
+		return f.x[0]
+	}
+	f.last = t
+
+	// Predict: x = F*x, P = F*P*F' + Q, with F = [1 dt; 0 1].
+	f.x[0] += f.x[1] * dt
+	p00 := f.p[0][0] + dt*(f.p[0][1]+f.p[1][0]) + dt*dt*f.p[1][1]
+	p01 := f.p[0][1] + dt*f.p[1][1]
+	p10 := f.p[1][0] + dt*f.p[1][1]
+	p11 := f.p[1][1]
+
+	q := f.ProcessNoise
+	p00 += q * dt * dt * dt * dt / 4
+	p01 += q * dt * dt * dt / 2
+	p10 += q * dt * dt * dt / 2
+	p11 += q * dt * dt
+
+	// Update with the measured position, H = [1 0].
+	s := p00 + f.MeasurementNoise
+	k0, k1 := p00/s, p10/s
+	y := v - f.x[0]
+	f.x[0] += k0 * y
+	f.x[1] += k1 * y
+	f.p = [2][2]float64{
+		{(1 - k0) * p00, (1 - k0) * p01},
+		{p10 - k1*p00, p11 - k1*p01},
+	}
+	return f.x[0]
+}
+
+// Reset clears the filter state.
+func (f *KalmanFilter) Reset() {
+	f.init = false
+}
+
+// PointFilter smooths the position and the scale of a localized point.
+type PointFilter struct {
+	row, col, scale Filter
+}
+
+// NewPointFilter returns a new point filter using the settings defined in the parameters.
+// It returns nil if the method is None or it's not supported.
+func NewPointFilter(p Params) *PointFilter {
+	if NewFilter(p) == nil {
+		return nil
+	}
+	return &PointFilter{
+		row:   NewFilter(p),
+		col:   NewFilter(p),
+		scale: NewFilter(p),
+	}
+}
+
+// Filter returns the smoothed point of the point localized at the time t.
+func (f *PointFilter) Filter(t time.Time, p pigo.Puploc) pigo.Puploc {
+	p.Row = int(math.Round(f.row.Filter(t, float64(p.Row))))
+	p.Col = int(math.Round(f.col.Filter(t, float64(p.Col))))
+	p.Scale = float32(f.scale.Filter(t, float64(p.Scale)))
+	return p
+}
+
+// Reset clears the filter state.
+func (f *PointFilter) Reset() {
+	f.row.Reset()
+	f.col.Reset()
+	f.scale.Reset()
+}
+
+// trackState holds the point filters of a tracked face, keyed by the point names.
+type trackState struct {
+	points   map[string]*PointFilter
+	lastSeen time.Time
+}
+
+// Smoother smooths the pupils and the facial landmark points of the tracked faces, using separate filters for each track.
+type Smoother struct {
+	Params
+
+	tracks map[int]*trackState
+}
+
+// NewSmoother initializes the Smoother constructor method.
+func NewSmoother(p Params) *Smoother {
+	return &Smoother{
+		Params: p,
+		tracks: make(map[int]*trackState),
+	}
+}
+
+// Update smooths the points of the faces tracked on the frame captured at the time t and returns
+// the tracks with the smoothed faces, in the same order. The input tracks are not modified.
+// The filters of the tracks not updated for longer than Timeout are dropped.
+func (s *Smoother) Update(t time.Time, tracks []pigo.Track) []pigo.Track {
+	result := make([]pigo.Track, len(tracks))
+	copy(result, tracks)
+	if NewFilter(s.Params) == nil {
+		return result
+	}
+
+	for i := range result {
+		tr := &result[i]
+		st, ok := s.tracks[tr.ID]
+		if !ok {
+			st = &trackState{points: make(map[string]*PointFilter)}
+			s.tracks[tr.ID] = st
+		}
+		st.lastSeen = t
+
+		filter := func(name string, p pigo.Puploc) pigo.Puploc {
+			f, ok := st.points[name]
+			if !ok {
+				f = NewPointFilter(s.Params)
+				st.points[name] = f
+			}
+			return f.Filter(t, p)
+		}
+		if tr.Face.LeftEye != nil {
+			p := filter(pigo.LeftPupil, *tr.Face.LeftEye)
+			tr.Face.LeftEye = &p
+		}
+		if tr.Face.RightEye != nil {
+			p := filter(pigo.RightPupil, *tr.Face.RightEye)
+			tr.Face.RightEye = &p
+		}
+		if len(tr.Face.Landmarks) > 0 {
+			landmarks := make([]pigo.Landmark, len(tr.Face.Landmarks))
+			for j, lp := range tr.Face.Landmarks {
+				lp.Puploc = filter(lp.Name, lp.Puploc)
+				landmarks[j] = lp
+			}
+			tr.Face.Landmarks = landmarks
+		}
+		// The head pose is estimated again from the smoothed points.
+		if tr.Face.Pose != nil {
+			tr.Face.Pose = pigo.EstimateHeadPose(tr.Face)
+		}
+	}
+
+	for id, st := range s.tracks {
+		if t.Sub(st.lastSeen) > s.Timeout {
+			delete(s.tracks, id)
+		}
+	}
+	return result
+}
+
+// Reset drops the filters of all the tracks.
+func (s *Smoother) Reset() {
+	s.tracks = make(map[int]*trackState)
+}
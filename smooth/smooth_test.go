@@ -0,0 +1,95 @@
+package smooth_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/smooth"
+)
+
+// frameTime returns the capture time of the i-th frame of a 30 fps stream.
+func frameTime(i int) time.Time {
+	return time.Unix(0, 0).Add(time.Duration(i) * time.Second / 30)
+}
+
+func TestSmooth_FiltersShouldReduceTheJitter(t *testing.T) {
+	for _, method := range []smooth.Method{smooth.OneEuro, smooth.EMA, smooth.Kalman} {
+		p := smooth.DefaultParams()
+		p.Method = method
+		f := smooth.NewFilter(p)
+		rng := rand.New(rand.NewSource(1))
+
+		var rawErr, smoothErr float64
+		for i := 0; i < 300; i++ {
+			noise := rng.NormFloat64() * 2
+			v := f.Filter(frameTime(i), 100+noise)
+			if i >= 30 {
+				rawErr += noise * noise
+				smoothErr += (v - 100) * (v - 100)
+			}
+		}
+		if smoothErr > rawErr/2 {
+			t.Errorf("%s: expected the jitter to be reduced, got rms: %.2f, raw rms: %.2f",
+				method, math.Sqrt(smoothErr/270), math.Sqrt(rawErr/270))
+		}
+	}
+}
+
+func TestSmooth_FiltersShouldFollowTheMotion(t *testing.T) {
+	for _, method := range []smooth.Method{smooth.OneEuro, smooth.Kalman} {
+		p := smooth.DefaultParams()
+		p.Method = method
+		f := smooth.NewFilter(p)
+
+		// A point moving with 200 pixels/second.
+		var v, truth float64
+		for i := 0; i < 60; i++ {
+			truth = 100 + 200*float64(i)/30
+			v = f.Filter(frameTime(i), truth)
+		}
+		if lag := truth - v; lag < 0 || lag > 5 {
+			t.Errorf("%s: expected a small lag behind the moving point, got: %.2f", method, lag)
+		}
+
+		f.Reset()
+		if v := f.Filter(frameTime(61), 10); v != 10 {
+			t.Errorf("%s: expected the first sample to be returned unchanged after reset, got: %v", method, v)
+		}
+	}
+	if smooth.NewFilter(smooth.Params{Method: smooth.None}) != nil {
+		t.Errorf("expected no filter for the none method")
+	}
+}
+
+func TestSmooth_SmootherShouldKeepSeparateTracks(t *testing.T) {
+	s := smooth.NewSmoother(smooth.DefaultParams())
+	track := func(id, col int) pigo.Track {
+		return pigo.Track{ID: id, Face: pigo.Face{
+			LeftEye:   &pigo.Puploc{Row: 100, Col: col},
+			Landmarks: []pigo.Landmark{{Name: pigo.NoseTip, Puploc: pigo.Puploc{Row: 150, Col: col}}},
+		}}
+	}
+
+	s.Update(frameTime(0), []pigo.Track{track(1, 100), track(2, 300)})
+	in := []pigo.Track{track(1, 110), track(2, 290)}
+	out := s.Update(frameTime(1), in)
+
+	if c := out[0].Face.LeftEye.Col; c <= 100 || c >= 110 {
+		t.Errorf("expected the first track pupil to be smoothed, got: %d", c)
+	}
+	if c := out[1].Face.Landmarks[0].Col; c <= 290 || c >= 300 {
+		t.Errorf("expected the second track landmark point to be smoothed, got: %d", c)
+	}
+	if in[0].Face.LeftEye.Col != 110 || in[1].Face.Landmarks[0].Col != 290 {
+		t.Errorf("expected the input tracks to be left untouched")
+	}
+
+	// The filters of the lost tracks are dropped after the timeout.
+	s.Update(frameTime(60), nil)
+	if out := s.Update(frameTime(61), []pigo.Track{track(1, 200)}); out[0].Face.LeftEye.Col != 200 {
+		t.Errorf("expected a new filter for the timed out track, got: %d", out[0].Face.LeftEye.Col)
+	}
+}
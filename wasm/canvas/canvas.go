@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"math"
 	"syscall/js"
+	"time"
 
+	"github.com/esimov/pigo/blink"
+	pigo "github.com/esimov/pigo/core"
+	"github.com/esimov/pigo/smooth"
 	"github.com/esimov/pigo/wasm/detector"
 )
 
@@ -35,6 +39,17 @@ type Canvas struct {
 	flploc     bool
 	markerType string
 	markerIdx  int
+
+	// Blink detection properties
+	showBlink bool
+	blinkDet  *blink.Detector
+	blinks    int
+	lastBlink time.Time
+
+	// Temporal smoothing properties
+	tracker   *pigo.Tracker
+	smoother  *smooth.Smoother
+	smoothIdx int
 }
 
 var det *detector.Detector
@@ -60,6 +75,9 @@ func NewCanvas() *Canvas {
 	c.showCoord = false
 	c.flploc = false
 	c.markerType = "rect"
+	c.blinkDet = blink.NewDetector(blink.DefaultParams())
+	c.tracker = pigo.NewTracker()
+	c.smoother = smooth.NewSmoother(smooth.Params{Method: smooth.None})
 
 	det = detector.NewDetector()
 	return &c
@@ -95,8 +113,17 @@ func (c *Canvas) Render() error {
 			// and the memory will keep up increasing by each iteration.
 			data = make([]byte, len(data))
 
-			res := det.DetectFaces(pixels, height, width)
-			c.drawDetection(res)
+			faces := det.DetectFaces(pixels, height, width, c.showPupil, c.flploc)
+			faces = c.smoothFaces(faces)
+			c.drawDetection(faces)
+			if c.showBlink {
+				c.detectBlink(faces, pigo.ImageParams{
+					Pixels: pixels,
+					Rows:   height,
+					Cols:   width,
+					Dim:    width,
+				})
+			}
 
 			c.window.Get("stats").Call("end")
 		}()
@@ -191,67 +218,96 @@ func (c *Canvas) rgbaToGrayscale(data []uint8) []uint8 {
 }
 
 // drawDetection draws the detected faces and eyes.
-func (c *Canvas) drawDetection(dets [][]int) {
-	for i := 0; i < len(dets); i++ {
-		if dets[i][3] > 50 {
-			c.ctx.Call("beginPath")
-			c.ctx.Set("lineWidth", 3)
-			c.ctx.Set("strokeStyle", "red")
-
-			row, col, scale := dets[i][1], dets[i][0], dets[i][2]
-			if c.showCoord {
-				c.ctx.Set("fillStyle", "red")
-				c.ctx.Set("font", "18px Arial")
-				message := fmt.Sprintf("(%v, %v)", row-scale/2, col-scale/2)
-				txtWidth := c.ctx.Call("measureText", js.ValueOf(message)).Get("width").Int()
-				c.ctx.Call("fillText", message, (row-scale/2)-txtWidth/2, col-scale/2-10)
-			}
-			switch c.markerType {
-			case "rect":
-				c.ctx.Call("rect", row-scale/2, col-scale/2, scale, scale)
-			case "circle":
-				c.ctx.Call("moveTo", row+int(scale/2), col)
-				c.ctx.Call("arc", row, col, scale/2, 0, 2*math.Pi, true)
-			case "ellipse":
-				c.ctx.Call("moveTo", row+int(scale/2), col)
-				c.ctx.Call("ellipse", row, col, scale/2, float64(scale)/1.6, 0, 0, 2*math.Pi)
-			}
-			c.ctx.Call("stroke")
+func (c *Canvas) drawDetection(faces []pigo.Face) {
+	for _, face := range faces {
+		c.ctx.Call("beginPath")
+		c.ctx.Set("lineWidth", 3)
+		c.ctx.Set("strokeStyle", "red")
+
+		row, col, scale := face.Detection.Col, face.Detection.Row, face.Detection.Scale
+		if c.showCoord {
+			c.ctx.Set("fillStyle", "red")
+			c.ctx.Set("font", "18px Arial")
+			message := fmt.Sprintf("(%v, %v)", row-scale/2, col-scale/2)
+			txtWidth := c.ctx.Call("measureText", js.ValueOf(message)).Get("width").Int()
+			c.ctx.Call("fillText", message, (row-scale/2)-txtWidth/2, col-scale/2-10)
+		}
+		switch c.markerType {
+		case "rect":
+			c.ctx.Call("rect", row-scale/2, col-scale/2, scale, scale)
+		case "circle":
+			c.ctx.Call("moveTo", row+int(scale/2), col)
+			c.ctx.Call("arc", row, col, scale/2, 0, 2*math.Pi, true)
+		case "ellipse":
+			c.ctx.Call("moveTo", row+int(scale/2), col)
+			c.ctx.Call("ellipse", row, col, scale/2, float64(scale)/1.6, 0, 0, 2*math.Pi)
+		}
+		c.ctx.Call("stroke")
 
-			if c.showPupil {
-				leftPupil := det.DetectLeftPupil(dets[i])
-				if leftPupil != nil {
-					col, row, scale := leftPupil.Col, leftPupil.Row, leftPupil.Scale/8
+		if c.showPupil {
+			for _, pupil := range []*pigo.Puploc{face.LeftEye, face.RightEye} {
+				if pupil != nil {
+					col, row, scale := pupil.Col, pupil.Row, pupil.Scale/8
 					c.ctx.Call("moveTo", col+int(scale), row)
 					c.ctx.Call("arc", col, row, scale, 0, 2*math.Pi, true)
 				}
+			}
+			c.ctx.Call("stroke")
 
-				rightPupil := det.DetectRightPupil(dets[i])
-				if rightPupil != nil {
-					col, row, scale := rightPupil.Col, rightPupil.Row, rightPupil.Scale/8
-					c.ctx.Call("moveTo", col+int(scale), row)
-					c.ctx.Call("arc", col, row, scale, 0, 2*math.Pi, true)
-				}
-				c.ctx.Call("stroke")
-
-				if c.flploc {
-					flps := det.DetectLandmarkPoints(leftPupil, rightPupil)
-					c.ctx.Call("beginPath")
-					c.ctx.Set("fillStyle", "rgb(0, 255, 0)")
-					for _, flp := range flps {
-						if len(flp) > 0 {
-							col, row, scale = flp[1], flp[0], flp[2]/7
-							c.ctx.Call("moveTo", row, col)
-							c.ctx.Call("arc", row, col, scale, 0, 2*math.Pi, false)
-						}
-					}
-					c.ctx.Call("fill")
+			if c.flploc {
+				c.ctx.Call("beginPath")
+				c.ctx.Set("fillStyle", "rgb(0, 255, 0)")
+				for _, flp := range face.Landmarks {
+					col, row, scale := flp.Col, flp.Row, int(flp.Scale)/7
+					c.ctx.Call("moveTo", col, row)
+					c.ctx.Call("arc", col, row, scale, 0, 2*math.Pi, false)
 				}
+				c.ctx.Call("fill")
 			}
 		}
 	}
 }
 
+// detectBlink feeds the blink detector with the first detected face and draws the blink counter.
+// The detector is reset when the face is lost, since the next face might belong to someone else.
+func (c *Canvas) detectBlink(faces []pigo.Face, imgParams pigo.ImageParams) {
+	if len(faces) == 0 || !c.showPupil {
+		c.blinkDet.Reset()
+		return
+	}
+	now := time.Now()
+	if len(c.blinkDet.Update(now, faces[0], imgParams)) > 0 {
+		c.blinks++
+		c.lastBlink = now
+	}
+
+	c.ctx.Set("fillStyle", "red")
+	c.ctx.Set("font", "18px Arial")
+	c.ctx.Call("fillText", fmt.Sprintf("Blinks: %d", c.blinks), 10, 24)
+
+	if now.Sub(c.lastBlink) < 300*time.Millisecond {
+		face := faces[0].Detection
+		c.ctx.Call("fillText", "Blink!", face.Col-face.Scale/2, face.Row-face.Scale/2-10)
+	}
+}
+
+// smoothFaces reduces the jitter of the pupils and the landmark points using the selected smoothing filter.
+func (c *Canvas) smoothFaces(faces []pigo.Face) []pigo.Face {
+	if c.smoother.Method == smooth.None {
+		return faces
+	}
+	tracks := c.smoother.Update(time.Now(), c.tracker.Update(faces))
+	for i, tr := range tracks {
+		faces[i] = tr.Face
+	}
+
+	c.ctx.Set("fillStyle", "red")
+	c.ctx.Set("font", "18px Arial")
+	c.ctx.Call("fillText", fmt.Sprintf("Smoothing: %s", c.smoother.Method), 10, c.windowSize.height-10)
+
+	return faces
+}
+
 // detectKeyPress listen for the keypress event and retrieves the key code.
 func (c *Canvas) detectKeyPress() {
 	keyEventHandler := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
@@ -275,6 +331,16 @@ func (c *Canvas) detectKeyPress() {
 			c.flploc = !c.flploc
 		case keyCode.String() == "x":
 			c.showCoord = !c.showCoord
+		case keyCode.String() == "b":
+			c.showBlink = !c.showBlink
+			c.blinkDet.Reset()
+			c.blinks = 0
+		case keyCode.String() == "m":
+			c.smoothIdx = (c.smoothIdx + 1) % len(smooth.Methods)
+			p := smooth.DefaultParams()
+			p.Method = smooth.Methods[c.smoothIdx]
+			c.smoother = smooth.NewSmoother(p)
+			c.tracker.Reset()
 		}
 		return nil
 	})